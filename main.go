@@ -1,7 +1,18 @@
 package main
 
 import (
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/golfz/fun-exercise-api/memstore"
 	"github.com/golfz/fun-exercise-api/postgres"
+	"github.com/golfz/fun-exercise-api/sqlite"
 	"github.com/golfz/fun-exercise-api/wallet"
 	"github.com/labstack/echo/v4"
 
@@ -13,24 +24,278 @@ import (
 // @version		1.0
 // @description	Sophisticated Wallet API
 // @host		localhost:1323
+// @security	BearerAuth
+// @security	APIKeyAuth
+//
+// @securityDefinitions.apikey	BearerAuth
+// @in							header
+// @name						Authorization
+// @description				"Bearer " followed by a JWT signed with HS256 or RS256
+//
+// @securityDefinitions.apikey	APIKeyAuth
+// @in							header
+// @name						X-API-Key
+// @description				API key issued under /api/v1/admin/api-keys, for callers without a bearer token
 func main() {
-	p, err := postgres.New()
+	if len(os.Args) > 1 {
+		if err := runCommand(os.Args[1], os.Args[2:]); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	store, err := newStore()
+	if err != nil {
+		panic(err)
+	}
+
+	keys, err := loadJWTKeys()
 	if err != nil {
 		panic(err)
 	}
 
+	rates := wallet.RateTable{}
+	if fxRatesFile := os.Getenv("FX_RATES_FILE"); fxRatesFile != "" {
+		if rates, err = wallet.LoadRateTableFile(fxRatesFile); err != nil {
+			panic(err)
+		}
+	}
+
 	e := echo.New()
+	e.HTTPErrorHandler = wallet.ProblemHandler
 	e.GET("/swagger/*", echoSwagger.WrapHandler)
-	handler := wallet.New(p)
+	handler := wallet.New(store, rates)
+	// retried requests carrying the same Idempotency-Key run once
+	idempotent := wallet.Idempotency(store, wallet.DefaultIdempotencyTTL)
 
-	g := e.Group("/api/v1")
+	// every API route needs an API key or a bearer token, and the permission of its route
+	g := e.Group("/api/v1", wallet.APIKeyAuth(store), wallet.JWTAuth(keys))
+	register(g, apiRoutes(handler), idempotent)
+	register(g.Group("/admin"), adminRoutes(handler), idempotent)
 
-	g.GET("/wallets", handler.GetWalletsHandler)              // challenge 3
-	g.GET("/users/:id/wallets", handler.GetUserWalletHandler) // challenge 4
+	e.Logger.Fatal(e.Start(":1323"))
+}
 
-	g.POST("/wallets", handler.CreateWalletHandler)
-	g.PUT("/wallets", handler.UpdateWalletHandler)
-	g.DELETE("/users/:id/wallets", handler.DeleteUserWalletHandler)
+// route is an API endpoint and the permission its caller needs.
+type route struct {
+	method     string
+	path       string
+	handler    echo.HandlerFunc
+	permission wallet.Permission
+	// idempotent routes replay the response of a retried Idempotency-Key
+	idempotent bool
+}
 
-	e.Logger.Fatal(e.Start(":1323"))
+// apiRoutes are the customer routes under /api/v1, wallet.PermissionOwn
+// limits them to the caller's own user and wallets.
+func apiRoutes(h *wallet.Handler) []route {
+	return []route{
+		{http.MethodGet, "/users/:id", h.GetUserHandler, wallet.PermissionOwn, false},
+		{http.MethodPut, "/users/:id", h.UpdateUserHandler, wallet.PermissionOwn, true},
+		{http.MethodGet, "/wallets", h.GetWalletsHandler, wallet.PermissionOwn, false},              // challenge 3
+		{http.MethodGet, "/users/:id/wallets", h.GetUserWalletHandler, wallet.PermissionOwn, false}, // challenge 4
+
+		{http.MethodGet, "/wallets/:id", h.GetWalletHandler, wallet.PermissionOwn, false},
+		{http.MethodPost, "/wallets", h.CreateWalletHandler, wallet.PermissionOwn, true},
+		{http.MethodPatch, "/wallets/:id", h.PatchWalletHandler, wallet.PermissionOwn, true},
+		{http.MethodDelete, "/wallets/:id", h.DeleteWalletHandler, wallet.PermissionOwn, false},
+		{http.MethodPost, "/wallets/:id/restore", h.RestoreWalletHandler, wallet.PermissionOwn, true},
+		{http.MethodPost, "/wallets/:id/close", h.CloseWalletHandler, wallet.PermissionOwn, true},
+		{http.MethodGet, "/wallets/:id/transactions", h.GetWalletTransactionsHandler, wallet.PermissionOwn, false},
+		{http.MethodPost, "/wallets/:id/deposits", h.DepositHandler, wallet.PermissionOwn, true},
+		{http.MethodPost, "/wallets/:id/withdrawals", h.WithdrawHandler, wallet.PermissionOwn, true},
+
+		{http.MethodPost, "/transfers", h.TransferHandler, wallet.PermissionOwn, true},
+		{http.MethodPost, "/conversions", h.ConvertHandler, wallet.PermissionOwn, true},
+		{http.MethodGet, "/fx/quote", h.GetFXQuoteHandler, wallet.PermissionFXQuote, false},
+	}
+}
+
+// adminRoutes are the operator routes under /api/v1/admin, they act on every user's wallets.
+func adminRoutes(h *wallet.Handler) []route {
+	return []route{
+		{http.MethodGet, "/users", h.GetUsersHandler, wallet.PermissionReadUsers, false},
+		{http.MethodPost, "/users", h.CreateUserHandler, wallet.PermissionManageUsers, true},
+		{http.MethodGet, "/users/:id", h.GetUserHandler, wallet.PermissionReadUsers, false},
+		{http.MethodPut, "/users/:id", h.UpdateUserHandler, wallet.PermissionManageUsers, true},
+		{http.MethodDelete, "/users/:id", h.DeleteUserHandler, wallet.PermissionManageUsers, false},
+		{http.MethodGet, "/users/:id/wallets", h.GetUserWalletHandler, wallet.PermissionReadWallets, false},
+		{http.MethodDelete, "/users/:id/wallets", h.DeleteUserWalletHandler, wallet.PermissionManageWallets, false},
+
+		{http.MethodGet, "/wallets", h.GetWalletsHandler, wallet.PermissionReadWallets, false},
+		{http.MethodGet, "/wallets/:id", h.GetWalletHandler, wallet.PermissionReadWallets, false},
+		{http.MethodPut, "/wallets", h.UpdateWalletHandler, wallet.PermissionManageWallets, true},
+		{http.MethodPost, "/wallets/:id/restore", h.RestoreWalletHandler, wallet.PermissionManageWallets, true},
+		{http.MethodGet, "/wallets/:id/transactions", h.GetWalletTransactionsHandler, wallet.PermissionReadWallets, false},
+		{http.MethodPost, "/wallets/:id/freeze", h.FreezeWalletHandler, wallet.PermissionWalletStatus, true},
+		{http.MethodPost, "/wallets/:id/unfreeze", h.UnfreezeWalletHandler, wallet.PermissionWalletStatus, true},
+		// closing cannot be undone, so it is not left to the support role with freezing
+		{http.MethodPost, "/wallets/:id/close", h.CloseWalletHandler, wallet.PermissionManageWallets, true},
+
+		// the issued key is answered only once, so it is never kept for an Idempotency-Key replay
+		{http.MethodGet, "/api-keys", h.GetAPIKeysHandler, wallet.PermissionManageAPIKeys, false},
+		{http.MethodPost, "/api-keys", h.CreateAPIKeyHandler, wallet.PermissionManageAPIKeys, false},
+		{http.MethodPost, "/api-keys/:id/rotate", h.RotateAPIKeyHandler, wallet.PermissionManageAPIKeys, false},
+		{http.MethodPost, "/api-keys/:id/revoke", h.RevokeAPIKeyHandler, wallet.PermissionManageAPIKeys, false},
+	}
+}
+
+// register adds the routes to g behind the permission each needs.
+func register(g *echo.Group, routes []route, idempotent echo.MiddlewareFunc) {
+	for _, r := range routes {
+		middleware := []echo.MiddlewareFunc{wallet.RequirePermission(r.permission)}
+		if r.idempotent {
+			middleware = append(middleware, idempotent)
+		}
+		g.Add(r.method, r.path, r.handler, middleware...)
+	}
+}
+
+// store keeps the wallets, the API keys and the responses stored for Idempotency-Key replays.
+type store interface {
+	wallet.Storer
+	wallet.IdempotencyStorer
+}
+
+// newStore picks the storage from STORE: "postgres" (the default),
+// "sqlite" for an embedded database file at SQLITE_PATH, or "memory".
+func newStore() (store, error) {
+	switch os.Getenv("STORE") {
+	case "memory":
+		store := memstore.New()
+		return store, memstore.Seed(store, memstore.DemoUsers, memstore.DemoWallets)
+	case "sqlite":
+		path := os.Getenv("SQLITE_PATH")
+		if path == "" {
+			path = "wallet.db"
+		}
+		return sqlite.New(path)
+	default:
+		return postgres.New()
+	}
+}
+
+// loadJWTKeys reads the keys bearer tokens are verified with from JWT_KEY_FILE,
+// an HS256 secret or a PEM RSA public key, or from the JSON Web Key Set at JWT_JWKS_FILE.
+func loadJWTKeys() (wallet.JWTKeys, error) {
+	keyFile, jwksFile := os.Getenv("JWT_KEY_FILE"), os.Getenv("JWT_JWKS_FILE")
+	switch {
+	case keyFile != "" && jwksFile != "":
+		return nil, errors.New("set only one of JWT_KEY_FILE and JWT_JWKS_FILE")
+	case keyFile != "":
+		return wallet.LoadJWTKeyFile(keyFile)
+	case jwksFile != "":
+		return wallet.LoadJWKSFile(jwksFile)
+	default:
+		return nil, errors.New("set JWT_KEY_FILE or JWT_JWKS_FILE to verify bearer tokens")
+	}
+}
+
+// runCommand handles the maintenance subcommands:
+//
+//	migrate up          apply every pending Postgres migration
+//	migrate down [n]    revert the last n migrations (default 1)
+//	migrate status      list migrations and when they were applied
+//	seed                create the demo wallets in the store chosen by STORE
+//	token sub [ttl] [roles]
+//	                    print an HS256 bearer token for sub signed with JWT_KEY_FILE,
+//	                    valid for ttl (default 1h), roles is a comma separated list (default customer)
+func runCommand(name string, args []string) error {
+	switch name {
+	case "migrate":
+		return migrate(args)
+	case "seed":
+		store, err := newStore()
+		if err != nil {
+			return err
+		}
+		return memstore.Seed(store, memstore.DemoUsers, memstore.DemoWallets)
+	case "token":
+		return token(args)
+	default:
+		return fmt.Errorf("unknown command %q, expected migrate, seed or token", name)
+	}
+}
+
+func token(args []string) error {
+	if len(args) == 0 || args[0] == "" {
+		return fmt.Errorf("usage: token sub [ttl] [roles]")
+	}
+
+	ttl := time.Hour
+	if len(args) > 1 {
+		var err error
+		if ttl, err = time.ParseDuration(args[1]); err != nil || ttl <= 0 {
+			return fmt.Errorf("token: invalid ttl %q", args[1])
+		}
+	}
+
+	var roles []string
+	if len(args) > 2 {
+		roles = strings.Split(args[2], ",")
+	}
+
+	keys, err := wallet.LoadJWTKeyFile(os.Getenv("JWT_KEY_FILE"))
+	if err != nil {
+		return err
+	}
+	secret, ok := keys[""].([]byte)
+	if !ok {
+		return errors.New("token: JWT_KEY_FILE must hold an HS256 secret, RS256 tokens are signed by the issuer")
+	}
+
+	now := time.Now()
+	signed, err := jwt.NewWithClaims(jwt.SigningMethodHS256, wallet.JWTClaims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   args[0],
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(ttl)),
+		},
+		Roles: roles,
+	}).SignedString(secret)
+	if err != nil {
+		return err
+	}
+	fmt.Println(signed)
+	return nil
+}
+
+func migrate(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("usage: migrate up|down [n]|status")
+	}
+
+	p, err := postgres.Open()
+	if err != nil {
+		return err
+	}
+
+	switch args[0] {
+	case "up":
+		return p.Migrate()
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			if steps, err = strconv.Atoi(args[1]); err != nil || steps < 1 {
+				return fmt.Errorf("migrate down: invalid step count %q", args[1])
+			}
+		}
+		return p.MigrateDown(steps)
+	case "status":
+		status, err := p.MigrationStatus()
+		if err != nil {
+			return err
+		}
+		for _, s := range status {
+			applied := "pending"
+			if s.AppliedAt != nil {
+				applied = s.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+			fmt.Printf("%04d_%s\t%s\n", s.Version, s.Name, applied)
+		}
+		return nil
+	default:
+		return fmt.Errorf("unknown migrate command %q, expected up, down or status", args[0])
+	}
 }
@@ -0,0 +1,107 @@
+package memstore
+
+import (
+	"sort"
+	"time"
+
+	"github.com/golfz/fun-exercise-api/wallet"
+)
+
+// copyAPIKey keeps callers from changing the scopes of a stored key.
+func copyAPIKey(k wallet.APIKey) wallet.APIKey {
+	k.Scopes = append([]wallet.Permission(nil), k.Scopes...)
+	return k
+}
+
+func (s *Store) GetAPIKeys() ([]wallet.APIKey, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	keys := make([]wallet.APIKey, 0, len(s.apiKeys))
+	for _, k := range s.apiKeys {
+		keys = append(keys, copyAPIKey(k))
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i].ID < keys[j].ID
+	})
+	return keys, nil
+}
+
+func (s *Store) GetAPIKeyByPrefix(prefix string) (wallet.APIKey, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, k := range s.apiKeys {
+		if k.Prefix == prefix {
+			return copyAPIKey(k), nil
+		}
+	}
+	return wallet.APIKey{}, wallet.ErrAPIKeyNotFound
+}
+
+func (s *Store) CreateAPIKey(k *wallet.APIKey) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.users[k.OwnerID]; !ok {
+		return wallet.ErrUserNotFound
+	}
+
+	s.lastAPIKeyID++
+	created := copyAPIKey(*k)
+	created.ID = s.lastAPIKeyID
+	created.CreatedAt = now()
+	created.LastUsedAt = nil
+	created.RevokedAt = nil
+	s.apiKeys[created.ID] = created
+
+	*k = copyAPIKey(created)
+	return nil
+}
+
+func (s *Store) RotateAPIKey(id int, prefix, hash string) (wallet.APIKey, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	k, ok := s.apiKeys[id]
+	if !ok {
+		return wallet.APIKey{}, wallet.ErrAPIKeyNotFound
+	}
+	if k.RevokedAt != nil {
+		return wallet.APIKey{}, wallet.ErrAPIKeyRevoked
+	}
+	k.Prefix = prefix
+	k.Hash = hash
+	s.apiKeys[id] = k
+	return copyAPIKey(k), nil
+}
+
+func (s *Store) RevokeAPIKey(id int) (wallet.APIKey, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	k, ok := s.apiKeys[id]
+	if !ok {
+		return wallet.APIKey{}, wallet.ErrAPIKeyNotFound
+	}
+	if k.RevokedAt == nil {
+		revokedAt := now()
+		k.RevokedAt = &revokedAt
+		s.apiKeys[id] = k
+	}
+	return copyAPIKey(k), nil
+}
+
+func (s *Store) TouchAPIKey(id int, at time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	k, ok := s.apiKeys[id]
+	if !ok {
+		return wallet.ErrAPIKeyNotFound
+	}
+	at = at.UTC()
+	k.LastUsedAt = &at
+	s.apiKeys[id] = k
+	return nil
+}
@@ -0,0 +1,187 @@
+package memstore
+
+import (
+	"github.com/golfz/fun-exercise-api/wallet"
+)
+
+// record appends a ledger entry for a signed balance change, callers must hold s.mu.
+func (s *Store) record(w wallet.Wallet, amount wallet.Money, reason, correlationID string) {
+	if amount.IsZero() {
+		return
+	}
+
+	s.lastTransactionID++
+	s.transactions = append(s.transactions, wallet.Transaction{
+		ID:            s.lastTransactionID,
+		WalletID:      w.ID,
+		Type:          wallet.TransactionTypeOf(amount),
+		Amount:        amount.Abs(),
+		Balance:       w.Balance,
+		Reason:        reason,
+		CorrelationID: correlationID,
+		CreatedAt:     now(),
+	})
+}
+
+// setBalance replaces the wallet balance, callers must hold s.mu and work the balance
+// out with Money.Add first, so a change that does not fit leaves every wallet alone.
+func (s *Store) setBalance(id int, balance wallet.Money) wallet.Wallet {
+	w := s.wallets[id]
+	w.Balance = balance
+	w.Version++
+	s.wallets[id] = w
+	return w
+}
+
+// walletPair returns both wallets of a transfer, callers must hold s.mu.
+func (s *Store) walletPair(fromID, toID int) (wallet.Wallet, wallet.Wallet, error) {
+	from, ok := s.liveWallet(fromID)
+	if !ok {
+		return wallet.Wallet{}, wallet.Wallet{}, wallet.ErrWalletNotFound
+	}
+	to, ok := s.liveWallet(toID)
+	if !ok {
+		return wallet.Wallet{}, wallet.Wallet{}, wallet.ErrWalletNotFound
+	}
+	return from, to, nil
+}
+
+func (s *Store) changeBalance(walletID int, amount wallet.Money, reason string) (wallet.Wallet, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	current, ok := s.liveWallet(walletID)
+	if !ok {
+		return wallet.Wallet{}, wallet.ErrWalletNotFound
+	}
+	if err := wallet.CheckActive(current); err != nil {
+		return wallet.Wallet{}, err
+	}
+	if err := wallet.CheckAmountPrecision(current.Currency, amount); err != nil {
+		return wallet.Wallet{}, err
+	}
+	if amount.IsNegative() && !wallet.CanDebit(current, amount.Neg()) {
+		return wallet.Wallet{}, wallet.ErrInsufficientFunds
+	}
+	balance, err := current.Balance.Add(amount)
+	if err != nil {
+		return wallet.Wallet{}, err
+	}
+
+	updated := s.setBalance(walletID, balance)
+	s.record(updated, amount, reason, wallet.NewCorrelationID())
+
+	return updated, nil
+}
+
+func (s *Store) Deposit(walletID int, amount wallet.Money) (wallet.Wallet, error) {
+	return s.changeBalance(walletID, amount, wallet.ReasonDeposit)
+}
+
+func (s *Store) Withdraw(walletID int, amount wallet.Money) (wallet.Wallet, error) {
+	return s.changeBalance(walletID, amount.Neg(), wallet.ReasonWithdrawal)
+}
+
+func (s *Store) Transfer(fromID, toID int, amount wallet.Money) (wallet.TransferResult, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	from, to, err := s.walletPair(fromID, toID)
+	if err != nil {
+		return wallet.TransferResult{}, err
+	}
+	if err = wallet.CheckActive(from, to); err != nil {
+		return wallet.TransferResult{}, err
+	}
+	if from.Currency != to.Currency {
+		return wallet.TransferResult{}, wallet.ErrCurrencyMismatch
+	}
+	if err = wallet.CheckAmountPrecision(from.Currency, amount); err != nil {
+		return wallet.TransferResult{}, err
+	}
+	if !wallet.CanDebit(from, amount) {
+		return wallet.TransferResult{}, wallet.ErrInsufficientFunds
+	}
+	fromBalance, err := from.Balance.Sub(amount)
+	if err != nil {
+		return wallet.TransferResult{}, err
+	}
+	toBalance, err := to.Balance.Add(amount)
+	if err != nil {
+		return wallet.TransferResult{}, err
+	}
+
+	result := wallet.TransferResult{
+		From: s.setBalance(fromID, fromBalance),
+		To:   s.setBalance(toID, toBalance),
+	}
+
+	correlationID := wallet.NewCorrelationID()
+	s.record(result.From, amount.Neg(), wallet.ReasonTransferOut, correlationID)
+	s.record(result.To, amount, wallet.ReasonTransferIn, correlationID)
+
+	return result, nil
+}
+
+func (s *Store) Convert(fromID, toID int, amount wallet.Money, rates wallet.FXRateProvider) (wallet.Conversion, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	from, to, err := s.walletPair(fromID, toID)
+	if err != nil {
+		return wallet.Conversion{}, err
+	}
+	if err = wallet.CheckActive(from, to); err != nil {
+		return wallet.Conversion{}, err
+	}
+	quote, err := wallet.Quote(rates, from.Currency, to.Currency, amount)
+	if err != nil {
+		return wallet.Conversion{}, err
+	}
+	if !wallet.CanDebit(from, amount) {
+		return wallet.Conversion{}, wallet.ErrInsufficientFunds
+	}
+	fromBalance, err := from.Balance.Sub(quote.Amount)
+	if err != nil {
+		return wallet.Conversion{}, err
+	}
+	toBalance, err := to.Balance.Add(quote.ConvertedAmount)
+	if err != nil {
+		return wallet.Conversion{}, err
+	}
+
+	s.lastConversionID++
+	conversion := wallet.Conversion{
+		ID:            s.lastConversionID,
+		FromWalletID:  fromID,
+		ToWalletID:    toID,
+		FromCurrency:  quote.From,
+		ToCurrency:    quote.To,
+		FromAmount:    quote.Amount,
+		ToAmount:      quote.ConvertedAmount,
+		Rate:          quote.Rate,
+		CorrelationID: wallet.NewCorrelationID(),
+		CreatedAt:     now(),
+		From:          s.setBalance(fromID, fromBalance),
+		To:            s.setBalance(toID, toBalance),
+	}
+	s.conversions = append(s.conversions, conversion)
+
+	s.record(conversion.From, conversion.FromAmount.Neg(), wallet.ReasonConversionOut, conversion.CorrelationID)
+	s.record(conversion.To, conversion.ToAmount, wallet.ReasonConversionIn, conversion.CorrelationID)
+
+	return conversion, nil
+}
+
+func (s *Store) GetTransactions(walletID int) ([]wallet.Transaction, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	transactions := make([]wallet.Transaction, 0)
+	for _, t := range s.transactions {
+		if t.WalletID == walletID {
+			transactions = append(transactions, t)
+		}
+	}
+	return transactions, nil
+}
@@ -0,0 +1,60 @@
+package memstore
+
+import (
+	"github.com/golfz/fun-exercise-api/wallet"
+)
+
+func (s *Store) ReserveIdempotencyKey(record wallet.IdempotencyRecord) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if stored, ok := s.idempotencyKeys[record.Key]; ok && stored.ExpiresAt.After(record.CreatedAt) {
+		return wallet.ErrIdempotencyKeyInUse
+	}
+	for key, stored := range s.idempotencyKeys {
+		if !stored.ExpiresAt.After(record.CreatedAt) {
+			delete(s.idempotencyKeys, key)
+		}
+	}
+	record.StatusCode = 0
+	record.ContentType = ""
+	record.ETag = ""
+	record.Body = nil
+	s.idempotencyKeys[record.Key] = record
+	return nil
+}
+
+func (s *Store) GetIdempotencyKey(key string) (wallet.IdempotencyRecord, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	record, ok := s.idempotencyKeys[key]
+	if !ok {
+		return wallet.IdempotencyRecord{}, wallet.ErrIdempotencyKeyNotFound
+	}
+	return record, nil
+}
+
+func (s *Store) CompleteIdempotencyKey(record wallet.IdempotencyRecord) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stored, ok := s.idempotencyKeys[record.Key]
+	if !ok {
+		return wallet.ErrIdempotencyKeyNotFound
+	}
+	stored.StatusCode = record.StatusCode
+	stored.ContentType = record.ContentType
+	stored.ETag = record.ETag
+	stored.Body = append([]byte(nil), record.Body...)
+	s.idempotencyKeys[record.Key] = stored
+	return nil
+}
+
+func (s *Store) DeleteIdempotencyKey(key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.idempotencyKeys, key)
+	return nil
+}
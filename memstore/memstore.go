@@ -0,0 +1,373 @@
+// Package memstore is an in-memory wallet.Storer for local development and
+// tests, it keeps nothing once the process exits.
+package memstore
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/golfz/fun-exercise-api/wallet"
+)
+
+type Store struct {
+	mu                sync.Mutex
+	users             map[int]wallet.User
+	wallets           map[int]wallet.Wallet
+	transactions      []wallet.Transaction
+	conversions       []wallet.Conversion
+	idempotencyKeys   map[string]wallet.IdempotencyRecord
+	apiKeys           map[int]wallet.APIKey
+	lastUserID        int
+	lastAPIKeyID      int
+	lastWalletID      int
+	lastTransactionID int
+	lastConversionID  int
+}
+
+// DemoUsers own the DemoWallets, both are created by the seed command.
+var DemoUsers = []wallet.User{
+	{Name: "John Doe"},
+	{Name: "Jane Doe"},
+}
+
+// DemoWallets are the wallets created by the seed command, their UserID
+// is the position of the user in DemoUsers counting from 1.
+var DemoWallets = []wallet.Wallet{
+	{UserID: 1, WalletName: "John Savings", WalletType: wallet.WalletTypeSavings, Currency: wallet.DefaultCurrency, Balance: wallet.MustParseMoney("1000")},
+	{UserID: 1, WalletName: "John Credit Card", WalletType: wallet.WalletTypeCreditCard, Currency: wallet.DefaultCurrency, Balance: wallet.MustParseMoney("500")},
+	{UserID: 1, WalletName: "John Crypto Wallet", WalletType: wallet.WalletTypeCryptoWallet, Currency: wallet.DefaultCurrency, Balance: wallet.MustParseMoney("100")},
+	{UserID: 2, WalletName: "Jane Savings", WalletType: wallet.WalletTypeSavings, Currency: wallet.DefaultCurrency, Balance: wallet.MustParseMoney("2000")},
+	{UserID: 2, WalletName: "Jane Credit Card", WalletType: wallet.WalletTypeCreditCard, Currency: wallet.DefaultCurrency, Balance: wallet.MustParseMoney("1000")},
+	{UserID: 2, WalletName: "Jane Crypto Wallet", WalletType: wallet.WalletTypeCryptoWallet, Currency: wallet.DefaultCurrency, Balance: wallet.MustParseMoney("200")},
+}
+
+func New() *Store {
+	return &Store{
+		users:           make(map[int]wallet.User),
+		wallets:         make(map[int]wallet.Wallet),
+		idempotencyKeys: make(map[string]wallet.IdempotencyRecord),
+		apiKeys:         make(map[int]wallet.APIKey),
+	}
+}
+
+// Seed creates the users, then the wallets in any store, the UserID of
+// each wallet is the position of its user in users counting from 1.
+func Seed(store wallet.Storer, users []wallet.User, wallets []wallet.Wallet) error {
+	userIDs := make(map[int]int)
+	for i, u := range users {
+		if err := store.CreateUser(&u); err != nil {
+			return err
+		}
+		userIDs[i+1] = u.ID
+	}
+	for _, w := range wallets {
+		w.UserID = userIDs[w.UserID]
+		if err := store.CreateWallet(&w); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func now() time.Time {
+	return time.Now().UTC()
+}
+
+// compareWallets orders a and b by the sort field, then by id, like the SQL stores.
+func compareWallets(a, b wallet.Wallet, sortBy string) int {
+	switch sortBy {
+	case wallet.SortByBalance:
+		if a.Balance != b.Balance {
+			return compare(a.Balance < b.Balance)
+		}
+	case wallet.SortByCreatedAt:
+		if !a.CreatedAt.Equal(b.CreatedAt) {
+			return compare(a.CreatedAt.Before(b.CreatedAt))
+		}
+	case wallet.SortByWalletName:
+		if a.WalletName != b.WalletName {
+			return compare(a.WalletName < b.WalletName)
+		}
+	}
+	if a.ID != b.ID {
+		return compare(a.ID < b.ID)
+	}
+	return 0
+}
+
+func compare(less bool) int {
+	if less {
+		return -1
+	}
+	return 1
+}
+
+// nameTaken reports whether the user has a wallet other than exceptID with the name,
+// like the unique (user_id, wallet_name) constraint of the SQL stores.
+func (s *Store) nameTaken(userID int, name string, exceptID int) bool {
+	for id, w := range s.wallets {
+		if id != exceptID && w.DeletedAt == nil && w.UserID == userID && w.WalletName == name {
+			return true
+		}
+	}
+	return false
+}
+
+// liveWallet returns the wallet unless it is missing or soft-deleted, callers must hold s.mu.
+func (s *Store) liveWallet(id int) (wallet.Wallet, bool) {
+	w, ok := s.wallets[id]
+	if !ok || w.DeletedAt != nil {
+		return wallet.Wallet{}, false
+	}
+	return w, true
+}
+
+func (s *Store) GetWallets(filter wallet.Filter, page wallet.Page) (wallet.WalletPage, error) {
+	if err := page.Validate(); err != nil {
+		return wallet.WalletPage{}, err
+	}
+
+	// the cursor wallet may be gone, so rebuild it from the cursor values
+	var after wallet.Wallet
+	if page.Cursor != nil {
+		value, err := page.Cursor.SortValue()
+		if err != nil {
+			return wallet.WalletPage{}, err
+		}
+		after.ID = page.Cursor.ID
+		switch v := value.(type) {
+		case wallet.Money:
+			after.Balance = v
+		case time.Time:
+			after.CreatedAt = v
+		case string:
+			after.WalletName = v
+		}
+	}
+
+	direction := 1
+	if page.Desc {
+		direction = -1
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	wallets := make([]wallet.Wallet, 0)
+	for _, w := range s.wallets {
+		if !filter.Match(w) {
+			continue
+		}
+		if page.Cursor != nil && direction*compareWallets(w, after, page.Sort) <= 0 {
+			continue
+		}
+		wallets = append(wallets, w)
+	}
+	sort.Slice(wallets, func(i, j int) bool {
+		return direction*compareWallets(wallets[i], wallets[j], page.Sort) < 0
+	})
+	if len(wallets) > page.Limit+1 {
+		wallets = wallets[:page.Limit+1]
+	}
+
+	return page.NewWalletPage(wallets), nil
+}
+
+func (s *Store) GetWalletByID(id int) (wallet.Wallet, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	w, ok := s.liveWallet(id)
+	if !ok {
+		return wallet.Wallet{}, wallet.ErrWalletNotFound
+	}
+	return w, nil
+}
+
+func (s *Store) GetWalletOwner(id int) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	w, ok := s.wallets[id]
+	if !ok {
+		return 0, wallet.ErrWalletNotFound
+	}
+	return w.UserID, nil
+}
+
+func (s *Store) CreateWallet(w *wallet.Wallet) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	user, ok := s.users[w.UserID]
+	if !ok {
+		return wallet.ErrUserNotFound
+	}
+	if s.nameTaken(w.UserID, w.WalletName, 0) {
+		return wallet.ErrDuplicateWallet
+	}
+
+	s.lastWalletID++
+	created := *w
+	created.ID = s.lastWalletID
+	created.UserName = user.Name
+	created.Status = wallet.WalletStatusActive
+	created.StatusReason = ""
+	created.Version = 1
+	created.CreatedAt = now()
+	created.DeletedAt = nil
+	s.wallets[created.ID] = created
+
+	s.record(created, created.Balance, wallet.ReasonOpeningBalance, wallet.NewCorrelationID())
+
+	*w = created
+	return nil
+}
+
+func (s *Store) UpdateWallet(w *wallet.Wallet) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	current, ok := s.liveWallet(w.ID)
+	if !ok {
+		return wallet.ErrWalletNotFound
+	}
+	if current.Version != w.Version {
+		return wallet.ErrVersionMismatch
+	}
+	if err := wallet.CheckActive(current); err != nil {
+		return err
+	}
+	if err := wallet.CheckAmountPrecision(current.Currency, w.Balance); err != nil {
+		return err
+	}
+	if w.Balance.IsNegative() && !wallet.AllowsNegativeBalance(current.WalletType) {
+		return wallet.ErrNegativeBalance
+	}
+	change, err := w.Balance.Sub(current.Balance)
+	if err != nil {
+		return err
+	}
+
+	updated := current
+	updated.Balance = w.Balance
+	updated.Version++
+	s.wallets[updated.ID] = updated
+
+	s.record(updated, change, wallet.ReasonBalanceUpdate, wallet.NewCorrelationID())
+
+	*w = updated
+	return nil
+}
+
+func (s *Store) PatchWallet(id, version int, patch wallet.WalletPatch) (wallet.Wallet, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	w, ok := s.liveWallet(id)
+	if !ok {
+		return wallet.Wallet{}, wallet.ErrWalletNotFound
+	}
+	if w.Version != version {
+		return wallet.Wallet{}, wallet.ErrVersionMismatch
+	}
+	if err := patch.Apply(&w); err != nil {
+		return wallet.Wallet{}, err
+	}
+	if s.nameTaken(w.UserID, w.WalletName, id) {
+		return wallet.Wallet{}, wallet.ErrDuplicateWallet
+	}
+	w.Version++
+	s.wallets[id] = w
+
+	return w, nil
+}
+
+func (s *Store) ChangeWalletStatus(id, version int, status, reason string) (wallet.Wallet, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	w, ok := s.liveWallet(id)
+	if !ok {
+		return wallet.Wallet{}, wallet.ErrWalletNotFound
+	}
+	if w.Version != version {
+		return wallet.Wallet{}, wallet.ErrVersionMismatch
+	}
+	if err := wallet.CheckStatusChange(w, status); err != nil {
+		return wallet.Wallet{}, err
+	}
+	w.Status = status
+	w.StatusReason = reason
+	w.Version++
+	s.wallets[id] = w
+
+	return w, nil
+}
+
+func (s *Store) DeleteWallet(id, version int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	w, ok := s.liveWallet(id)
+	if !ok {
+		return wallet.ErrWalletNotFound
+	}
+	if w.Version != version {
+		return wallet.ErrVersionMismatch
+	}
+	if err := wallet.CheckDeletable(w); err != nil {
+		return err
+	}
+	s.softDelete(w)
+	return nil
+}
+
+func (s *Store) DeleteUserWallets(userID int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	live := make([]wallet.Wallet, 0)
+	for _, w := range s.wallets {
+		if w.UserID == userID && w.DeletedAt == nil {
+			live = append(live, w)
+		}
+	}
+	if err := wallet.CheckDeletable(live...); err != nil {
+		return err
+	}
+	for _, w := range live {
+		s.softDelete(w)
+	}
+	return nil
+}
+
+// softDelete marks w as deleted, callers must hold s.mu.
+func (s *Store) softDelete(w wallet.Wallet) {
+	deletedAt := now()
+	w.DeletedAt = &deletedAt
+	w.Version++
+	s.wallets[w.ID] = w
+}
+
+func (s *Store) RestoreWallet(id int) (wallet.Wallet, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	w, ok := s.wallets[id]
+	if !ok {
+		return wallet.Wallet{}, wallet.ErrWalletNotFound
+	}
+	if w.DeletedAt == nil {
+		return w, nil
+	}
+	if s.nameTaken(w.UserID, w.WalletName, id) {
+		return wallet.Wallet{}, wallet.ErrDuplicateWallet
+	}
+	w.DeletedAt = nil
+	w.Version++
+	s.wallets[id] = w
+
+	return w, nil
+}
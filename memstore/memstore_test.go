@@ -0,0 +1,70 @@
+package memstore
+
+import (
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/golfz/fun-exercise-api/wallet"
+	"github.com/golfz/fun-exercise-api/wallet/storertest"
+	"github.com/stretchr/testify/assert"
+)
+
+func seededStore(t *testing.T) *Store {
+	store := New()
+	if err := Seed(store, DemoUsers, DemoWallets); err != nil {
+		t.Fatal(err)
+	}
+	return store
+}
+
+func TestStorerConformance(t *testing.T) {
+	storertest.Run(t, func(t *testing.T) wallet.Storer {
+		return New()
+	})
+}
+
+func TestIdempotencyStorerConformance(t *testing.T) {
+	storertest.RunIdempotency(t, func(t *testing.T) wallet.IdempotencyStorer {
+		return New()
+	})
+}
+
+func TestSeed(t *testing.T) {
+	store := seededStore(t)
+
+	page, err := store.GetWallets(wallet.Filter{UserID: 2}, wallet.DefaultPage())
+
+	assert.NoError(t, err)
+	assert.Len(t, page.Wallets, 3)
+	assert.Equal(t, "Jane Savings", page.Wallets[0].WalletName)
+}
+
+func TestConcurrentWithdrawals(t *testing.T) {
+	store := seededStore(t)
+
+	// 150 withdrawals of 10 from a Savings wallet holding 1000, only 100 can succeed
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	succeeded := 0
+	for i := 0; i < 150; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := store.Withdraw(1, wallet.MustParseMoney("10"))
+			if err != nil && !errors.Is(err, wallet.ErrInsufficientFunds) {
+				t.Error(err)
+			}
+			if err == nil {
+				mu.Lock()
+				succeeded++
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, 100, succeeded)
+	savings, _ := store.GetWalletByID(1)
+	assert.True(t, savings.Balance.IsZero())
+}
@@ -0,0 +1,92 @@
+package memstore
+
+import (
+	"sort"
+
+	"github.com/golfz/fun-exercise-api/wallet"
+)
+
+func (s *Store) GetUsers() ([]wallet.User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	users := make([]wallet.User, 0, len(s.users))
+	for _, u := range s.users {
+		users = append(users, u)
+	}
+	sort.Slice(users, func(i, j int) bool {
+		return users[i].ID < users[j].ID
+	})
+	return users, nil
+}
+
+func (s *Store) GetUserByID(id int) (wallet.User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	u, ok := s.users[id]
+	if !ok {
+		return wallet.User{}, wallet.ErrUserNotFound
+	}
+	return u, nil
+}
+
+func (s *Store) CreateUser(u *wallet.User) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.lastUserID++
+	created := *u
+	created.ID = s.lastUserID
+	created.CreatedAt = now()
+	s.users[created.ID] = created
+
+	*u = created
+	return nil
+}
+
+func (s *Store) UpdateUser(u *wallet.User) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	updated, ok := s.users[u.ID]
+	if !ok {
+		return wallet.ErrUserNotFound
+	}
+	updated.Name = u.Name
+	s.users[u.ID] = updated
+
+	// wallets keep a copy of the name, the SQL stores read it from users instead
+	for id, w := range s.wallets {
+		if w.UserID == u.ID {
+			w.UserName = updated.Name
+			s.wallets[id] = w
+		}
+	}
+
+	*u = updated
+	return nil
+}
+
+func (s *Store) DeleteUser(id int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.users[id]; !ok {
+		return wallet.ErrUserNotFound
+	}
+	// soft-deleted wallets count too, they can still be restored
+	for _, w := range s.wallets {
+		if w.UserID == id {
+			return wallet.ErrUserHasWallets
+		}
+	}
+	delete(s.users, id)
+	// the keys of the user go with it, like ON DELETE CASCADE in the SQL stores
+	for keyID, k := range s.apiKeys {
+		if k.OwnerID == id {
+			delete(s.apiKeys, keyID)
+		}
+	}
+	return nil
+}
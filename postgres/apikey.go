@@ -0,0 +1,150 @@
+package postgres
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/golfz/fun-exercise-api/sqlquery"
+	"github.com/golfz/fun-exercise-api/wallet"
+	"github.com/lib/pq"
+)
+
+// rowScanner is satisfied by both *sql.Row and *sql.Rows.
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanAPIKey(row rowScanner) (wallet.APIKey, error) {
+	var k wallet.APIKey
+	var scopes []string
+	err := row.Scan(&k.ID, &k.Prefix, &k.Hash, &k.Name, &k.OwnerID, pq.Array(&scopes), &k.CreatedAt, &k.LastUsedAt, &k.RevokedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return wallet.APIKey{}, wallet.ErrAPIKeyNotFound
+	}
+	k.Scopes = make([]wallet.Permission, 0, len(scopes))
+	for _, scope := range scopes {
+		k.Scopes = append(k.Scopes, wallet.Permission(scope))
+	}
+	return k, err
+}
+
+func (p *Postgres) GetAPIKeys() (_ []wallet.APIKey, err error) {
+	defer translateError(&err)
+
+	selectSql := `
+		SELECT ` + sqlquery.APIKeyColumns + `
+		FROM api_key
+		ORDER BY id ASC`
+
+	rows, err := p.Db.Query(selectSql)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	keys := make([]wallet.APIKey, 0)
+	for rows.Next() {
+		k, err := scanAPIKey(rows)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, k)
+	}
+	return keys, rows.Err()
+}
+
+func (p *Postgres) GetAPIKeyByPrefix(prefix string) (_ wallet.APIKey, err error) {
+	defer translateError(&err)
+
+	selectSql := `
+		SELECT ` + sqlquery.APIKeyColumns + `
+		FROM api_key
+		WHERE prefix = $1`
+	return scanAPIKey(p.Db.QueryRow(selectSql, prefix))
+}
+
+func (p *Postgres) CreateAPIKey(k *wallet.APIKey) (err error) {
+	defer translateError(&err)
+
+	scopes := make([]string, 0, len(k.Scopes))
+	for _, scope := range k.Scopes {
+		scopes = append(scopes, string(scope))
+	}
+
+	// selecting the owner inserts nothing when the user does not exist
+	insertSql := `
+		INSERT INTO api_key (prefix, hash, name, owner_id, scopes)
+		SELECT $1, $2, $3, id, $5
+		FROM users
+		WHERE id = $4
+		RETURNING ` + sqlquery.APIKeyColumns
+
+	created, err := scanAPIKey(p.Db.QueryRow(insertSql, k.Prefix, k.Hash, k.Name, k.OwnerID, pq.Array(scopes)))
+	if errors.Is(err, wallet.ErrAPIKeyNotFound) {
+		return wallet.ErrUserNotFound
+	}
+	if err != nil {
+		return err
+	}
+
+	*k = created
+	return nil
+}
+
+func (p *Postgres) RotateAPIKey(id int, prefix, hash string) (_ wallet.APIKey, err error) {
+	defer translateError(&err)
+
+	updateSql := `
+		UPDATE api_key SET prefix = $1, hash = $2
+		WHERE id = $3 AND revoked_at IS NULL
+		RETURNING ` + sqlquery.APIKeyColumns
+
+	rotated, err := scanAPIKey(p.Db.QueryRow(updateSql, prefix, hash, id))
+	if !errors.Is(err, wallet.ErrAPIKeyNotFound) {
+		return rotated, err
+	}
+
+	// nothing was updated, tell a revoked key from a missing one
+	var revoked bool
+	err = p.Db.QueryRow(`SELECT revoked_at IS NOT NULL FROM api_key WHERE id = $1`, id).Scan(&revoked)
+	if errors.Is(err, sql.ErrNoRows) {
+		return wallet.APIKey{}, wallet.ErrAPIKeyNotFound
+	}
+	if err != nil {
+		return wallet.APIKey{}, err
+	}
+	if revoked {
+		return wallet.APIKey{}, wallet.ErrAPIKeyRevoked
+	}
+	// a revoked key is never brought back, so the row only changed under a concurrent request
+	return wallet.APIKey{}, wallet.ErrConflict
+}
+
+func (p *Postgres) RevokeAPIKey(id int) (_ wallet.APIKey, err error) {
+	defer translateError(&err)
+
+	// a revoked key keeps the time it was first revoked
+	updateSql := `
+		UPDATE api_key SET revoked_at = COALESCE(revoked_at, CURRENT_TIMESTAMP)
+		WHERE id = $1
+		RETURNING ` + sqlquery.APIKeyColumns
+	return scanAPIKey(p.Db.QueryRow(updateSql, id))
+}
+
+func (p *Postgres) TouchAPIKey(id int, at time.Time) (err error) {
+	defer translateError(&err)
+
+	result, err := p.Db.Exec(`UPDATE api_key SET last_used_at = $1 WHERE id = $2`, at.UTC(), id)
+	if err != nil {
+		return err
+	}
+	touched, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if touched == 0 {
+		return wallet.ErrAPIKeyNotFound
+	}
+	return nil
+}
@@ -0,0 +1,53 @@
+package postgres
+
+import (
+	"github.com/golfz/fun-exercise-api/wallet"
+)
+
+// changeBalance adds the signed amount to the wallet balance and records it in the ledger.
+func (p *Postgres) changeBalance(walletID int, amount wallet.Money, reason string) (_ wallet.Wallet, err error) {
+	defer translateError(&err)
+
+	tx, err := p.Db.Begin()
+	if err != nil {
+		return wallet.Wallet{}, err
+	}
+	defer tx.Rollback()
+
+	current, err := lockWalletForUpdate(tx, walletID)
+	if err != nil {
+		return wallet.Wallet{}, err
+	}
+	if err = wallet.CheckActive(current); err != nil {
+		return wallet.Wallet{}, err
+	}
+	if err = wallet.CheckAmountPrecision(current.Currency, amount); err != nil {
+		return wallet.Wallet{}, err
+	}
+	if amount.IsNegative() && !wallet.CanDebit(current, amount.Neg()) {
+		return wallet.Wallet{}, wallet.ErrInsufficientFunds
+	}
+
+	updated, err := addBalance(tx, current, amount)
+	if err != nil {
+		return wallet.Wallet{}, err
+	}
+
+	if err = insertTransaction(tx, updated, amount, reason, wallet.NewCorrelationID()); err != nil {
+		return wallet.Wallet{}, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return wallet.Wallet{}, err
+	}
+
+	return updated, nil
+}
+
+func (p *Postgres) Deposit(walletID int, amount wallet.Money) (wallet.Wallet, error) {
+	return p.changeBalance(walletID, amount, wallet.ReasonDeposit)
+}
+
+func (p *Postgres) Withdraw(walletID int, amount wallet.Money) (wallet.Wallet, error) {
+	return p.changeBalance(walletID, amount.Neg(), wallet.ReasonWithdrawal)
+}
@@ -0,0 +1,84 @@
+package postgres
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/golfz/fun-exercise-api/wallet"
+	"github.com/lib/pq"
+)
+
+// https://www.postgresql.org/docs/current/errcodes-appendix.html
+const (
+	codeNumericValueOutOfRange    = "22003"
+	codeInvalidTextRepresentation = "22P02"
+	codeForeignKeyViolation       = "23503"
+	codeUniqueViolation           = "23505"
+	codeCheckViolation            = "23514"
+	codeSerializationFailure      = "40001"
+	codeDeadlockDetected          = "40P01"
+)
+
+// The constraints whose violation means a wallet error rather than a bug.
+const (
+	balanceCheck       = "user_wallet_balance_check"
+	walletNameUnique   = "user_wallet_user_id_wallet_name_key"
+	walletUserFK       = "user_wallet_user_id_fkey"
+	apiKeyOwnerFK      = "api_key_owner_id_fkey"
+	apiKeyPrefixUnique = "api_key_prefix_key"
+)
+
+// translateError replaces the database error in *err with the wallet error it means,
+// every Storer method defers it so handlers never see sql or pq errors.
+func translateError(err *error) {
+	*err = storeError(*err)
+}
+
+// storeError maps the violation of a known constraint to its wallet error, anything
+// unexpected stays a server error.
+func storeError(err error) error {
+	if errors.Is(err, sql.ErrNoRows) {
+		return wallet.ErrWalletNotFound
+	}
+
+	var pqErr *pq.Error
+	if !errors.As(err, &pqErr) {
+		return err
+	}
+	switch pqErr.Code {
+	case codeUniqueViolation:
+		switch pqErr.Constraint {
+		case walletNameUnique:
+			return wallet.ErrDuplicateWallet
+		case apiKeyPrefixUnique:
+			// two random prefixes met, a retry draws another
+			return wallet.ErrConflict
+		}
+	case codeCheckViolation:
+		if pqErr.Constraint == balanceCheck {
+			return wallet.ErrInsufficientFunds
+		}
+	case codeNumericValueOutOfRange:
+		// a balance beyond the DECIMAL(18, 8) money columns, wallet.MaxMoney keeps most out
+		return fmt.Errorf("%w: %s", wallet.ErrInvalidMoney, pqErr.Message)
+	case codeInvalidTextRepresentation:
+		// an enum value such as a wallet_type the database does not know
+		return fmt.Errorf("%w: %s", wallet.ErrInvalidRequest, pqErr.Message)
+	case codeForeignKeyViolation:
+		// the stores check the user first, so only a user deleted by a concurrent request gets here
+		switch pqErr.Constraint {
+		case walletUserFK, apiKeyOwnerFK:
+			return wallet.ErrUserNotFound
+		}
+	case codeSerializationFailure, codeDeadlockDetected:
+		return wallet.ErrConflict
+	}
+	return fmt.Errorf("postgres: %w", err)
+}
+
+// hasCode reports whether err is a database error with code.
+func hasCode(err error, code pq.ErrorCode) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == code
+}
@@ -0,0 +1,66 @@
+package postgres
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/golfz/fun-exercise-api/wallet"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStoreError(t *testing.T) {
+	other := errors.New("connection refused")
+
+	tests := []struct {
+		name string
+		err  error
+		want error
+	}{
+		{name: "No error", err: nil, want: nil},
+		{name: "No rows", err: sql.ErrNoRows, want: wallet.ErrWalletNotFound},
+		{name: "Wrapped no rows", err: fmt.Errorf("scan: %w", sql.ErrNoRows), want: wallet.ErrWalletNotFound},
+		{name: "Wallet name violation", err: &pq.Error{Code: codeUniqueViolation, Constraint: "user_wallet_user_id_wallet_name_key"}, want: wallet.ErrDuplicateWallet},
+		{name: "API key prefix violation", err: &pq.Error{Code: codeUniqueViolation, Constraint: "api_key_prefix_key"}, want: wallet.ErrConflict},
+		{name: "Balance check violation", err: &pq.Error{Code: codeCheckViolation, Constraint: balanceCheck}, want: wallet.ErrInsufficientFunds},
+		{name: "Wallet user violation", err: &pq.Error{Code: codeForeignKeyViolation, Constraint: "user_wallet_user_id_fkey"}, want: wallet.ErrUserNotFound},
+		{name: "API key owner violation", err: &pq.Error{Code: codeForeignKeyViolation, Constraint: "api_key_owner_id_fkey"}, want: wallet.ErrUserNotFound},
+		{name: "Numeric overflow", err: &pq.Error{Code: codeNumericValueOutOfRange, Message: "numeric field overflow"}, want: wallet.ErrInvalidMoney},
+		{name: "Invalid enum", err: &pq.Error{Code: codeInvalidTextRepresentation, Message: `invalid input value for enum wallet_type: "Piggy Bank"`}, want: wallet.ErrInvalidRequest},
+		{name: "Serialization failure", err: &pq.Error{Code: codeSerializationFailure}, want: wallet.ErrConflict},
+		{name: "Deadlock", err: &pq.Error{Code: codeDeadlockDetected}, want: wallet.ErrConflict},
+		{name: "Other error", err: other, want: other},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := storeError(test.err)
+
+			if test.want == nil {
+				assert.NoError(t, got)
+				return
+			}
+			assert.ErrorIs(t, got, test.want)
+		})
+	}
+
+	// violations of other constraints are bugs, not something the client can fix or retry
+	unexpected := []*pq.Error{
+		{Code: codeUniqueViolation, Constraint: "users_pkey"},
+		{Code: codeUniqueViolation, Constraint: "schema_migrations_pkey"},
+		{Code: codeCheckViolation, Constraint: "wallet_transaction_amount_check"},
+		{Code: codeForeignKeyViolation, Constraint: "wallet_transaction_wallet_id_fkey"},
+		{Code: "53300"},
+	}
+	for _, err := range unexpected {
+		t.Run("Unexpected "+string(err.Code)+" "+err.Constraint, func(t *testing.T) {
+			got := storeError(err)
+
+			assert.ErrorIs(t, got, err)
+			assert.Equal(t, http.StatusInternalServerError, wallet.NewProblem(got).Status)
+		})
+	}
+}
@@ -0,0 +1,78 @@
+package postgres
+
+import (
+	"github.com/golfz/fun-exercise-api/wallet"
+)
+
+func (p *Postgres) Convert(fromID, toID int, amount wallet.Money, rates wallet.FXRateProvider) (_ wallet.Conversion, err error) {
+	defer translateError(&err)
+
+	tx, err := p.Db.Begin()
+	if err != nil {
+		return wallet.Conversion{}, err
+	}
+	defer tx.Rollback()
+
+	locked, err := lockWalletPair(tx, fromID, toID)
+	if err != nil {
+		return wallet.Conversion{}, err
+	}
+	from, to := locked[fromID], locked[toID]
+	if err = wallet.CheckActive(from, to); err != nil {
+		return wallet.Conversion{}, err
+	}
+
+	// price the conversion at the rate in force while both wallets are locked
+	quote, err := wallet.Quote(rates, from.Currency, to.Currency, amount)
+	if err != nil {
+		return wallet.Conversion{}, err
+	}
+	if !wallet.CanDebit(from, amount) {
+		return wallet.Conversion{}, wallet.ErrInsufficientFunds
+	}
+
+	conversion := wallet.Conversion{
+		FromWalletID:  fromID,
+		ToWalletID:    toID,
+		FromCurrency:  quote.From,
+		ToCurrency:    quote.To,
+		FromAmount:    quote.Amount,
+		ToAmount:      quote.ConvertedAmount,
+		Rate:          quote.Rate,
+		CorrelationID: wallet.NewCorrelationID(),
+	}
+
+	if conversion.From, err = addBalance(tx, from, conversion.FromAmount.Neg()); err != nil {
+		return wallet.Conversion{}, err
+	}
+	if conversion.To, err = addBalance(tx, to, conversion.ToAmount); err != nil {
+		return wallet.Conversion{}, err
+	}
+
+	err = insertTransaction(tx, conversion.From, conversion.FromAmount.Neg(), wallet.ReasonConversionOut, conversion.CorrelationID)
+	if err != nil {
+		return wallet.Conversion{}, err
+	}
+	err = insertTransaction(tx, conversion.To, conversion.ToAmount, wallet.ReasonConversionIn, conversion.CorrelationID)
+	if err != nil {
+		return wallet.Conversion{}, err
+	}
+
+	insertSql := `
+		INSERT INTO fx_conversion (from_wallet_id, to_wallet_id, from_currency, to_currency, from_amount, to_amount, rate, correlation_id)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		RETURNING id, created_at`
+	args := []interface{}{
+		conversion.FromWalletID, conversion.ToWalletID, conversion.FromCurrency, conversion.ToCurrency,
+		conversion.FromAmount, conversion.ToAmount, conversion.Rate, conversion.CorrelationID,
+	}
+	if err = tx.QueryRow(insertSql, args...).Scan(&conversion.ID, &conversion.CreatedAt); err != nil {
+		return wallet.Conversion{}, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return wallet.Conversion{}, err
+	}
+
+	return conversion, nil
+}
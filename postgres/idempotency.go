@@ -0,0 +1,89 @@
+package postgres
+
+import (
+	"database/sql"
+	"errors"
+	"github.com/golfz/fun-exercise-api/sqlquery"
+	"github.com/golfz/fun-exercise-api/wallet"
+)
+
+func (p *Postgres) ReserveIdempotencyKey(record wallet.IdempotencyRecord) (err error) {
+	defer translateError(&err)
+
+	// expired records are only replaced when their key comes back, purge the rest here
+	purgeSql, args, err := sqlquery.Postgres.PurgeIdempotencyKeys(record.CreatedAt)
+	if err != nil {
+		return err
+	}
+	if _, err = p.Db.Exec(purgeSql, args...); err != nil {
+		return err
+	}
+
+	insertSql, args, err := sqlquery.Postgres.ReserveIdempotencyKey(record)
+	if err != nil {
+		return err
+	}
+
+	result, err := p.Db.Exec(insertSql, args...)
+	if err != nil {
+		return err
+	}
+	reserved, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if reserved == 0 {
+		return wallet.ErrIdempotencyKeyInUse
+	}
+	return nil
+}
+
+func (p *Postgres) GetIdempotencyKey(key string) (_ wallet.IdempotencyRecord, err error) {
+	defer translateError(&err)
+
+	selectSql := `
+		SELECT ` + sqlquery.IdempotencyKeyColumns + `
+		FROM idempotency_key
+		WHERE key = $1`
+
+	var r wallet.IdempotencyRecord
+	err = p.Db.QueryRow(selectSql, key).Scan(&r.Key, &r.RequestHash, &r.StatusCode, &r.ContentType, &r.ETag, &r.Body, &r.CreatedAt, &r.ExpiresAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return wallet.IdempotencyRecord{}, wallet.ErrIdempotencyKeyNotFound
+	}
+	return r, err
+}
+
+func (p *Postgres) CompleteIdempotencyKey(record wallet.IdempotencyRecord) (err error) {
+	defer translateError(&err)
+
+	if record.Body == nil {
+		record.Body = []byte{} // a nil slice would be written as NULL
+	}
+
+	updateSql := `
+		UPDATE idempotency_key SET status_code = $1, content_type = $2, etag = $3, response_body = $4
+		WHERE key = $5`
+
+	result, err := p.Db.Exec(updateSql, record.StatusCode, record.ContentType, record.ETag, record.Body, record.Key)
+	if err != nil {
+		return err
+	}
+	completed, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if completed == 0 {
+		return wallet.ErrIdempotencyKeyNotFound
+	}
+	return nil
+}
+
+func (p *Postgres) DeleteIdempotencyKey(key string) (err error) {
+	defer translateError(&err)
+
+	deleteSql := `DELETE FROM idempotency_key WHERE key = $1`
+
+	_, err = p.Db.Exec(deleteSql, key)
+	return err
+}
@@ -0,0 +1,59 @@
+package postgres
+
+import (
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadMigrations(t *testing.T) {
+	t.Run("orders pairs by version", func(t *testing.T) {
+		fsys := fstest.MapFS{
+			"0002_add_currency.up.sql":   {Data: []byte("ALTER TABLE a ADD b INT;")},
+			"0002_add_currency.down.sql": {Data: []byte("ALTER TABLE a DROP b;")},
+			"0001_create_a.up.sql":       {Data: []byte("CREATE TABLE a ();")},
+		}
+
+		migrations, err := loadMigrations(fsys)
+
+		require.NoError(t, err)
+		assert.Equal(t, []Migration{
+			{Version: 1, Name: "create_a", Up: "CREATE TABLE a ();"},
+			{Version: 2, Name: "add_currency", Up: "ALTER TABLE a ADD b INT;", Down: "ALTER TABLE a DROP b;"},
+		}, migrations)
+	})
+
+	tests := []struct {
+		name string
+		fsys fstest.MapFS
+	}{
+		{"badly named file", fstest.MapFS{"create_a.sql": {Data: []byte("CREATE TABLE a ();")}}},
+		{"missing up file", fstest.MapFS{"0001_create_a.down.sql": {Data: []byte("DROP TABLE a;")}}},
+		{"one version with two names", fstest.MapFS{
+			"0001_create_a.up.sql": {Data: []byte("CREATE TABLE a ();")},
+			"0001_create_b.up.sql": {Data: []byte("CREATE TABLE b ();")},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := loadMigrations(tt.fsys)
+
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestEmbeddedMigrations(t *testing.T) {
+	migrations, err := embeddedMigrations()
+
+	require.NoError(t, err)
+	require.NotEmpty(t, migrations)
+	assert.Equal(t, 1, migrations[0].Version)
+	assert.Equal(t, "create_user_wallet", migrations[0].Name)
+	for i, m := range migrations {
+		assert.Equal(t, i+1, m.Version, "versions must have no gaps")
+		assert.NotEmpty(t, m.Down, "migration %04d_%s needs a down file", m.Version, m.Name)
+	}
+}
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS user_wallet;
+
+DROP TYPE IF EXISTS wallet_type;
@@ -0,0 +1,16 @@
+-- Adopts databases created by the old init.sql, so every statement tolerates existing objects
+DO $$ BEGIN
+	CREATE TYPE wallet_type AS ENUM ('Savings', 'Credit Card', 'Crypto Wallet');
+EXCEPTION
+	WHEN duplicate_object THEN NULL;
+END $$;
+
+CREATE TABLE IF NOT EXISTS user_wallet (
+	id SERIAL PRIMARY KEY,
+	user_id INT NOT NULL,
+	user_name VARCHAR(255) NOT NULL,
+	wallet_name VARCHAR(255) NOT NULL,
+	wallet_type wallet_type NOT NULL,
+	balance DECIMAL(10, 2) NOT NULL,
+	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS wallet_transaction;
+
+DROP TYPE IF EXISTS transaction_type;
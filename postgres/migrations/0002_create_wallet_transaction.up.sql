@@ -0,0 +1,27 @@
+-- Append-only ledger of every balance change
+DO $$ BEGIN
+	CREATE TYPE transaction_type AS ENUM ('credit', 'debit');
+EXCEPTION
+	WHEN duplicate_object THEN NULL;
+END $$;
+
+CREATE TABLE IF NOT EXISTS wallet_transaction (
+	id SERIAL PRIMARY KEY,
+	wallet_id INT NOT NULL,
+	type transaction_type NOT NULL,
+	amount DECIMAL(10, 2) NOT NULL CHECK (amount > 0),
+	balance DECIMAL(10, 2) NOT NULL,
+	reason VARCHAR(255) NOT NULL,
+	correlation_id UUID NOT NULL,
+	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS wallet_transaction_wallet_id_idx ON wallet_transaction (wallet_id);
+
+-- Wallets that existed before the ledger start with their balance as opening balance
+INSERT INTO wallet_transaction (wallet_id, type, amount, balance, reason, correlation_id)
+SELECT w.id, CASE WHEN w.balance < 0 THEN 'debit' ELSE 'credit' END::transaction_type,
+	ABS(w.balance), w.balance, 'opening balance', gen_random_uuid()
+FROM user_wallet w
+WHERE w.balance <> 0
+	AND NOT EXISTS (SELECT 1 FROM wallet_transaction t WHERE t.wallet_id = w.id);
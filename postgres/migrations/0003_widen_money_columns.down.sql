@@ -0,0 +1,4 @@
+ALTER TABLE wallet_transaction ALTER COLUMN balance TYPE DECIMAL(10, 2);
+ALTER TABLE wallet_transaction ALTER COLUMN amount TYPE DECIMAL(10, 2);
+
+ALTER TABLE user_wallet ALTER COLUMN balance TYPE DECIMAL(10, 2);
@@ -0,0 +1,5 @@
+-- Room for the 8 decimal places of wallet.Money
+ALTER TABLE user_wallet ALTER COLUMN balance TYPE DECIMAL(18, 8);
+
+ALTER TABLE wallet_transaction ALTER COLUMN amount TYPE DECIMAL(18, 8);
+ALTER TABLE wallet_transaction ALTER COLUMN balance TYPE DECIMAL(18, 8);
@@ -0,0 +1,13 @@
+-- Cross-currency conversions with the rate that was applied
+CREATE TABLE IF NOT EXISTS fx_conversion (
+	id SERIAL PRIMARY KEY,
+	from_wallet_id INT NOT NULL,
+	to_wallet_id INT NOT NULL,
+	from_currency CHAR(3) NOT NULL,
+	to_currency CHAR(3) NOT NULL,
+	from_amount DECIMAL(18, 8) NOT NULL,
+	to_amount DECIMAL(18, 8) NOT NULL,
+	rate DECIMAL(24, 12) NOT NULL,
+	correlation_id UUID NOT NULL,
+	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
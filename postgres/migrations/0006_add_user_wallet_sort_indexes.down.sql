@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS user_wallet_wallet_name_id_idx;
+DROP INDEX IF EXISTS user_wallet_created_at_id_idx;
+DROP INDEX IF EXISTS user_wallet_balance_id_idx;
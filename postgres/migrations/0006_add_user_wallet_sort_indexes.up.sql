@@ -0,0 +1,4 @@
+-- Keyset pagination walks (sort column, id), one index per sort
+CREATE INDEX IF NOT EXISTS user_wallet_balance_id_idx ON user_wallet (balance, id);
+CREATE INDEX IF NOT EXISTS user_wallet_created_at_id_idx ON user_wallet (created_at, id);
+CREATE INDEX IF NOT EXISTS user_wallet_wallet_name_id_idx ON user_wallet (wallet_name, id);
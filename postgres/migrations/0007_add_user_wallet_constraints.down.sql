@@ -0,0 +1,2 @@
+ALTER TABLE user_wallet DROP CONSTRAINT IF EXISTS user_wallet_balance_check;
+ALTER TABLE user_wallet DROP CONSTRAINT IF EXISTS user_wallet_user_id_wallet_name_key;
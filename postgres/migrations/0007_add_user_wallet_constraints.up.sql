@@ -0,0 +1,14 @@
+-- Wallets sharing a name with an older wallet of the same user keep their data,
+-- their name gets the wallet id appended so the unique constraint can hold
+UPDATE user_wallet w SET wallet_name = left(w.wallet_name, 240) || ' (' || w.id || ')'
+WHERE EXISTS (
+	SELECT 1 FROM user_wallet older
+	WHERE older.user_id = w.user_id AND older.wallet_name = w.wallet_name AND older.id < w.id
+);
+
+-- A user cannot have two wallets with the same name
+ALTER TABLE user_wallet ADD CONSTRAINT user_wallet_user_id_wallet_name_key UNIQUE (user_id, wallet_name);
+
+-- Only credit cards may be overdrawn, NOT VALID leaves rows written before the rule alone
+ALTER TABLE user_wallet ADD CONSTRAINT user_wallet_balance_check
+	CHECK (wallet_type = 'Credit Card' OR balance >= 0) NOT VALID;
@@ -0,0 +1,10 @@
+-- Responses kept for Idempotency-Key replays, status_code is 0 while the request runs
+CREATE TABLE IF NOT EXISTS idempotency_key (
+	key VARCHAR(255) PRIMARY KEY,
+	request_hash CHAR(64) NOT NULL,
+	status_code INT NOT NULL DEFAULT 0,
+	content_type VARCHAR(255) NOT NULL DEFAULT '',
+	response_body BYTEA NOT NULL DEFAULT '',
+	created_at TIMESTAMPTZ NOT NULL,
+	expires_at TIMESTAMPTZ NOT NULL
+);
@@ -0,0 +1,2 @@
+-- Goes up with every change of the wallet, sent as its ETag for optimistic concurrency
+ALTER TABLE user_wallet ADD COLUMN IF NOT EXISTS version INT NOT NULL DEFAULT 1;
@@ -0,0 +1,12 @@
+-- Soft-deleted wallets keep their balance and ledger, so they are not thrown away:
+-- restore or remove them by hand before migrating down
+DO $$ BEGIN
+	IF EXISTS (SELECT 1 FROM user_wallet WHERE deleted_at IS NOT NULL) THEN
+		RAISE EXCEPTION 'user_wallet has soft-deleted wallets, restore or remove them before migrating down';
+	END IF;
+END $$;
+
+DROP INDEX IF EXISTS user_wallet_user_id_wallet_name_key;
+ALTER TABLE user_wallet ADD CONSTRAINT user_wallet_user_id_wallet_name_key UNIQUE (user_id, wallet_name);
+
+ALTER TABLE user_wallet DROP COLUMN IF EXISTS deleted_at;
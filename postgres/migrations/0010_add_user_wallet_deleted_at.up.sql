@@ -0,0 +1,7 @@
+-- Set while the wallet is soft-deleted, deleted wallets are left out of reads
+ALTER TABLE user_wallet ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMP;
+
+-- A deleted wallet no longer holds on to its name
+ALTER TABLE user_wallet DROP CONSTRAINT IF EXISTS user_wallet_user_id_wallet_name_key;
+CREATE UNIQUE INDEX IF NOT EXISTS user_wallet_user_id_wallet_name_key
+	ON user_wallet (user_id, wallet_name) WHERE deleted_at IS NULL;
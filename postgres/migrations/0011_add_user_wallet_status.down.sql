@@ -0,0 +1,2 @@
+ALTER TABLE user_wallet DROP COLUMN IF EXISTS status_reason;
+ALTER TABLE user_wallet DROP COLUMN IF EXISTS status;
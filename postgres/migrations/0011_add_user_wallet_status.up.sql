@@ -0,0 +1,4 @@
+-- Only an active wallet balance may change, see wallet.CanChangeStatus for the transitions
+ALTER TABLE user_wallet ADD COLUMN IF NOT EXISTS status VARCHAR(16) NOT NULL DEFAULT 'active'
+	CONSTRAINT user_wallet_status_check CHECK (status IN ('active', 'frozen', 'closed'));
+ALTER TABLE user_wallet ADD COLUMN IF NOT EXISTS status_reason VARCHAR(255) NOT NULL DEFAULT '';
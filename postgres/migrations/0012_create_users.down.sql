@@ -0,0 +1,6 @@
+ALTER TABLE user_wallet ADD COLUMN IF NOT EXISTS user_name VARCHAR(255) NOT NULL DEFAULT '';
+UPDATE user_wallet SET user_name = users.name FROM users WHERE users.id = user_wallet.user_id;
+ALTER TABLE user_wallet ALTER COLUMN user_name DROP DEFAULT;
+
+ALTER TABLE user_wallet DROP CONSTRAINT IF EXISTS user_wallet_user_id_fkey;
+DROP TABLE IF EXISTS users;
@@ -0,0 +1,17 @@
+CREATE TABLE IF NOT EXISTS users (
+	id SERIAL PRIMARY KEY,
+	name VARCHAR(255) NOT NULL,
+	created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Adopt the users known from their wallets, the name of the latest wallet wins
+INSERT INTO users (id, name)
+SELECT DISTINCT ON (user_id) user_id, user_name
+FROM user_wallet
+ORDER BY user_id, id DESC
+ON CONFLICT (id) DO NOTHING;
+SELECT setval(pg_get_serial_sequence('users', 'id'), COALESCE(MAX(id), 0) + 1, false) FROM users;
+
+-- The name now lives on the user only, wallets read it through user_id
+ALTER TABLE user_wallet ADD CONSTRAINT user_wallet_user_id_fkey FOREIGN KEY (user_id) REFERENCES users (id);
+ALTER TABLE user_wallet DROP COLUMN IF EXISTS user_name;
@@ -0,0 +1,15 @@
+-- Keys of service callers, only the SHA-256 hash of a key is kept, the prefix finds it
+CREATE TABLE IF NOT EXISTS api_key (
+	id SERIAL PRIMARY KEY,
+	prefix VARCHAR(16) NOT NULL,
+	hash CHAR(64) NOT NULL,
+	name VARCHAR(255) NOT NULL,
+	owner_id INT NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+	scopes TEXT[] NOT NULL DEFAULT '{}',
+	created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	last_used_at TIMESTAMP,
+	revoked_at TIMESTAMP,
+	CONSTRAINT api_key_prefix_key UNIQUE (prefix)
+);
+
+CREATE INDEX IF NOT EXISTS api_key_owner_id_idx ON api_key (owner_id);
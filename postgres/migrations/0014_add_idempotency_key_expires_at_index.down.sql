@@ -0,0 +1 @@
+DROP INDEX IF EXISTS idempotency_key_expires_at_idx;
@@ -0,0 +1,2 @@
+-- Reserving a key purges the expired ones
+CREATE INDEX IF NOT EXISTS idempotency_key_expires_at_idx ON idempotency_key (expires_at);
@@ -0,0 +1,2 @@
+-- Replays answer with the ETag of the first response
+ALTER TABLE idempotency_key ADD COLUMN IF NOT EXISTS etag VARCHAR(255) NOT NULL DEFAULT '';
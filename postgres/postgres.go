@@ -18,7 +18,25 @@ type Postgres struct {
 	Db *sql.DB
 }
 
+// New connects to the database configured by DB_* and migrates it to the
+// latest schema, unless DB_AUTO_MIGRATE is "false".
 func New() (*Postgres, error) {
+	p, err := Open()
+	if err != nil {
+		return nil, err
+	}
+
+	if os.Getenv("DB_AUTO_MIGRATE") != "false" {
+		if err = p.Migrate(); err != nil {
+			log.Fatal(err)
+			return nil, err
+		}
+	}
+	return p, nil
+}
+
+// Open connects to the database configured by DB_* without touching its schema.
+func Open() (*Postgres, error) {
 	dbHost := os.Getenv("DB_HOST")
 	dbPort := os.Getenv("DB_PORT")
 	dbUser := os.Getenv("DB_USER")
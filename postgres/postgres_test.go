@@ -0,0 +1,36 @@
+package postgres
+
+import (
+	"os"
+	"testing"
+
+	"github.com/golfz/fun-exercise-api/wallet"
+	"github.com/golfz/fun-exercise-api/wallet/storertest"
+)
+
+// TestStorerConformance runs against the database configured by DB_*, it
+// empties every table, so it only runs when POSTGRES_CONFORMANCE=1.
+func TestStorerConformance(t *testing.T) {
+	if os.Getenv("POSTGRES_CONFORMANCE") != "1" {
+		t.Skip("set POSTGRES_CONFORMANCE=1 to run against the database in DB_*")
+	}
+
+	p, err := New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	empty := func(t *testing.T) *Postgres {
+		_, err := p.Db.Exec(`TRUNCATE users, user_wallet, wallet_transaction, fx_conversion, idempotency_key, api_key RESTART IDENTITY`)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return p
+	}
+
+	storertest.Run(t, func(t *testing.T) wallet.Storer {
+		return empty(t)
+	})
+	storertest.RunIdempotency(t, func(t *testing.T) wallet.IdempotencyStorer {
+		return empty(t)
+	})
+}
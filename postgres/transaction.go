@@ -0,0 +1,53 @@
+package postgres
+
+import (
+	"database/sql"
+	"github.com/golfz/fun-exercise-api/wallet"
+)
+
+// insertTransaction appends a ledger entry for a signed balance change,
+// it must run in the same transaction as the change itself.
+func insertTransaction(tx *sql.Tx, w wallet.Wallet, amount wallet.Money, reason, correlationID string) error {
+	if amount.IsZero() {
+		return nil
+	}
+
+	insertSql := `
+		INSERT INTO wallet_transaction (wallet_id, type, amount, balance, reason, correlation_id)
+		VALUES ($1, $2, $3, $4, $5, $6)`
+	args := []interface{}{w.ID, wallet.TransactionTypeOf(amount), amount.Abs(), w.Balance, reason, correlationID}
+
+	_, err := tx.Exec(insertSql, args...)
+	return err
+}
+
+func scanTransactionsFromRows(rows *sql.Rows) ([]wallet.Transaction, error) {
+	transactions := make([]wallet.Transaction, 0)
+	for rows.Next() {
+		var t wallet.Transaction
+		err := rows.Scan(&t.ID, &t.WalletID, &t.Type, &t.Amount, &t.Balance, &t.Reason, &t.CorrelationID, &t.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		transactions = append(transactions, t)
+	}
+	return transactions, rows.Err()
+}
+
+func (p *Postgres) GetTransactions(walletID int) (_ []wallet.Transaction, err error) {
+	defer translateError(&err)
+
+	selectSql := `
+		SELECT id, wallet_id, type, amount, balance, reason, correlation_id, created_at 
+		FROM wallet_transaction 
+		WHERE wallet_id = $1
+		ORDER BY id ASC`
+
+	rows, err := p.Db.Query(selectSql, walletID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanTransactionsFromRows(rows)
+}
@@ -0,0 +1,100 @@
+package postgres
+
+import (
+	"database/sql"
+	"github.com/golfz/fun-exercise-api/sqlquery"
+	"github.com/golfz/fun-exercise-api/wallet"
+)
+
+func lockWalletForUpdate(tx *sql.Tx, id int) (wallet.Wallet, error) {
+	selectSql := `
+		SELECT ` + sqlquery.WalletColumns + ` 
+		FROM user_wallet 
+		WHERE id = $1 AND deleted_at IS NULL
+		FOR UPDATE`
+	return scanWalletFromRow(tx.QueryRow(selectSql, id))
+}
+
+// lockWalletPair locks both wallets in id order, so two opposite transfers cannot deadlock.
+func lockWalletPair(tx *sql.Tx, fromID, toID int) (map[int]wallet.Wallet, error) {
+	lockOrder := []int{fromID, toID}
+	if toID < fromID {
+		lockOrder = []int{toID, fromID}
+	}
+
+	locked := make(map[int]wallet.Wallet)
+	for _, id := range lockOrder {
+		w, err := lockWalletForUpdate(tx, id)
+		if err != nil {
+			return nil, err
+		}
+		locked[id] = w
+	}
+	return locked, nil
+}
+
+// addBalance adds the signed amount to the balance of the locked wallet w,
+// and returns ErrInvalidMoney when the new balance would not fit.
+func addBalance(tx *sql.Tx, w wallet.Wallet, amount wallet.Money) (wallet.Wallet, error) {
+	if _, err := w.Balance.Add(amount); err != nil {
+		return wallet.Wallet{}, err
+	}
+
+	updateSql := `
+		UPDATE user_wallet SET balance = balance + $1, version = version + 1 
+		WHERE id = $2
+		RETURNING ` + sqlquery.WalletColumns
+
+	return scanWalletFromRow(tx.QueryRow(updateSql, amount, w.ID))
+}
+
+func (p *Postgres) Transfer(fromID, toID int, amount wallet.Money) (_ wallet.TransferResult, err error) {
+	defer translateError(&err)
+
+	tx, err := p.Db.Begin()
+	if err != nil {
+		return wallet.TransferResult{}, err
+	}
+	defer tx.Rollback()
+
+	locked, err := lockWalletPair(tx, fromID, toID)
+	if err != nil {
+		return wallet.TransferResult{}, err
+	}
+	if err = wallet.CheckActive(locked[fromID], locked[toID]); err != nil {
+		return wallet.TransferResult{}, err
+	}
+
+	if locked[fromID].Currency != locked[toID].Currency {
+		return wallet.TransferResult{}, wallet.ErrCurrencyMismatch
+	}
+	if err = wallet.CheckAmountPrecision(locked[fromID].Currency, amount); err != nil {
+		return wallet.TransferResult{}, err
+	}
+	if !wallet.CanDebit(locked[fromID], amount) {
+		return wallet.TransferResult{}, wallet.ErrInsufficientFunds
+	}
+
+	var result wallet.TransferResult
+	if result.From, err = addBalance(tx, locked[fromID], amount.Neg()); err != nil {
+		return wallet.TransferResult{}, err
+	}
+	if result.To, err = addBalance(tx, locked[toID], amount); err != nil {
+		return wallet.TransferResult{}, err
+	}
+
+	// both ledger entries share one correlation id
+	correlationID := wallet.NewCorrelationID()
+	if err = insertTransaction(tx, result.From, amount.Neg(), wallet.ReasonTransferOut, correlationID); err != nil {
+		return wallet.TransferResult{}, err
+	}
+	if err = insertTransaction(tx, result.To, amount, wallet.ReasonTransferIn, correlationID); err != nil {
+		return wallet.TransferResult{}, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return wallet.TransferResult{}, err
+	}
+
+	return result, nil
+}
@@ -0,0 +1,129 @@
+package postgres
+
+import (
+	"database/sql"
+	"errors"
+	"github.com/golfz/fun-exercise-api/sqlquery"
+	"github.com/golfz/fun-exercise-api/wallet"
+)
+
+func scanUser(row *sql.Row) (wallet.User, error) {
+	var u wallet.User
+	err := row.Scan(&u.ID, &u.Name, &u.CreatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return wallet.User{}, wallet.ErrUserNotFound
+	}
+	return u, err
+}
+
+// lockUserForShare keeps the user from being deleted until the transaction ends,
+// so a wallet can be created for it.
+func lockUserForShare(tx *sql.Tx, id int) error {
+	selectSql := `
+		SELECT ` + sqlquery.UserColumns + `
+		FROM users
+		WHERE id = $1
+		FOR SHARE`
+	_, err := scanUser(tx.QueryRow(selectSql, id))
+	return err
+}
+
+func (p *Postgres) GetUsers() (_ []wallet.User, err error) {
+	defer translateError(&err)
+
+	selectSql := `
+		SELECT ` + sqlquery.UserColumns + `
+		FROM users
+		ORDER BY id ASC`
+
+	rows, err := p.Db.Query(selectSql)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	users := make([]wallet.User, 0)
+	for rows.Next() {
+		var u wallet.User
+		if err := rows.Scan(&u.ID, &u.Name, &u.CreatedAt); err != nil {
+			return nil, err
+		}
+		users = append(users, u)
+	}
+	return users, rows.Err()
+}
+
+func (p *Postgres) GetUserByID(id int) (_ wallet.User, err error) {
+	defer translateError(&err)
+
+	selectSql := `
+		SELECT ` + sqlquery.UserColumns + `
+		FROM users
+		WHERE id = $1`
+	return scanUser(p.Db.QueryRow(selectSql, id))
+}
+
+func (p *Postgres) CreateUser(u *wallet.User) (err error) {
+	defer translateError(&err)
+
+	insertSql := `
+		INSERT INTO users (name)
+		VALUES ($1)
+		RETURNING ` + sqlquery.UserColumns
+
+	created, err := scanUser(p.Db.QueryRow(insertSql, u.Name))
+	if err != nil {
+		return err
+	}
+
+	*u = created
+	return nil
+}
+
+func (p *Postgres) UpdateUser(u *wallet.User) (err error) {
+	defer translateError(&err)
+
+	updateSql := `
+		UPDATE users SET name = $1
+		WHERE id = $2
+		RETURNING ` + sqlquery.UserColumns
+
+	updated, err := scanUser(p.Db.QueryRow(updateSql, u.Name, u.ID))
+	if err != nil {
+		return err
+	}
+
+	*u = updated
+	return nil
+}
+
+func (p *Postgres) DeleteUser(id int) (err error) {
+	defer translateError(&err)
+
+	// soft-deleted wallets count too, they can still be restored
+	var hasWallets bool
+	err = p.Db.QueryRow(`SELECT EXISTS (SELECT 1 FROM user_wallet WHERE user_id = $1)`, id).Scan(&hasWallets)
+	if err != nil {
+		return err
+	}
+	if hasWallets {
+		return wallet.ErrUserHasWallets
+	}
+
+	result, err := p.Db.Exec(`DELETE FROM users WHERE id = $1`, id)
+	if hasCode(err, codeForeignKeyViolation) {
+		// a wallet was created for the user since the check
+		return wallet.ErrUserHasWallets
+	}
+	if err != nil {
+		return err
+	}
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if deleted == 0 {
+		return wallet.ErrUserNotFound
+	}
+	return nil
+}
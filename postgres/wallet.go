@@ -2,24 +2,35 @@ package postgres
 
 import (
 	"database/sql"
-	sq "github.com/Masterminds/squirrel"
+	"errors"
+	"github.com/golfz/fun-exercise-api/sqlquery"
 	"github.com/golfz/fun-exercise-api/wallet"
 	"log"
 )
 
 // type Wallet struct {
-//	ID         int       `postgres:"id"`
-//	UserID     int       `postgres:"user_id"`
-//	UserName   string    `postgres:"user_name"`
-//	WalletName string    `postgres:"wallet_name"`
-//	WalletType string    `postgres:"wallet_type"`
-//	Balance    float64   `postgres:"balance"`
-//	CreatedAt  time.Time `postgres:"created_at"`
+//	ID           int        `postgres:"id"`
+//	UserID       int        `postgres:"user_id"`
+//	UserName     string     `postgres:"user_name"`
+//	WalletName   string     `postgres:"wallet_name"`
+//	WalletType   string     `postgres:"wallet_type"`
+//	Currency     string     `postgres:"currency"`
+//	Balance      Money      `postgres:"balance"`
+//	Status       string     `postgres:"status"`
+//	StatusReason string     `postgres:"status_reason"`
+//	Version      int        `postgres:"version"`
+//	CreatedAt    time.Time  `postgres:"created_at"`
+//	DeletedAt    *time.Time `postgres:"deleted_at"`
 //}
 
+// walletFields returns the scan destinations matching sqlquery.WalletColumns.
+func walletFields(w *wallet.Wallet) []interface{} {
+	return []interface{}{&w.ID, &w.UserID, &w.UserName, &w.WalletName, &w.WalletType, &w.Currency, &w.Balance, &w.Status, &w.StatusReason, &w.Version, &w.CreatedAt, &w.DeletedAt}
+}
+
 func scanWalletFromRow(row *sql.Row) (wallet.Wallet, error) {
 	var w wallet.Wallet
-	err := row.Scan(&w.ID, &w.UserID, &w.UserName, &w.WalletName, &w.WalletType, &w.Balance, &w.CreatedAt)
+	err := row.Scan(walletFields(&w)...)
 	if err != nil {
 		return wallet.Wallet{}, err
 	}
@@ -30,7 +41,7 @@ func scanWalletsFromRows(rows *sql.Rows) ([]wallet.Wallet, error) {
 	wallets := make([]wallet.Wallet, 0)
 	for rows.Next() {
 		var w wallet.Wallet
-		err := rows.Scan(&w.ID, &w.UserID, &w.UserName, &w.WalletName, &w.WalletType, &w.Balance, &w.CreatedAt)
+		err := rows.Scan(walletFields(&w)...)
 		if err != nil {
 			return nil, err
 		}
@@ -40,93 +51,300 @@ func scanWalletsFromRows(rows *sql.Rows) ([]wallet.Wallet, error) {
 	return wallets, nil
 }
 
-func (p *Postgres) getWalletByID(id int) (wallet.Wallet, error) {
+// queryRower is satisfied by both *sql.DB and *sql.Tx.
+type queryRower interface {
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
+
+// getWallet reads a wallet that is not soft-deleted.
+func getWallet(q queryRower, id int) (wallet.Wallet, error) {
 	selectSql := `
-		SELECT id, user_id, user_name, wallet_name, wallet_type, balance, created_at 
+		SELECT ` + sqlquery.WalletColumns + ` 
 		FROM user_wallet 
-		WHERE id = $1`
-	row := p.Db.QueryRow(selectSql, id)
-
-	return scanWalletFromRow(row)
+		WHERE id = $1 AND deleted_at IS NULL`
+	return scanWalletFromRow(q.QueryRow(selectSql, id))
 }
 
-func prepareSelectSqlWithFilter(filter wallet.Wallet) (string, []interface{}, error) {
-	psql := sq.StatementBuilder.PlaceholderFormat(sq.Dollar)
-	selectQuery := psql.Select("id, user_id, user_name, wallet_name, wallet_type, balance, created_at").
-		From("user_wallet")
+func (p *Postgres) GetWalletByID(id int) (_ wallet.Wallet, err error) {
+	defer translateError(&err)
 
-	// prepare filter
-	if filter.WalletType != "" {
-		selectQuery = selectQuery.Where(sq.Eq{"wallet_type": filter.WalletType})
-	}
-	if filter.UserID != 0 {
-		selectQuery = selectQuery.Where(sq.Eq{"user_id": filter.UserID})
-	}
+	return getWallet(p.Db, id)
+}
 
-	selectQuery = selectQuery.OrderBy("id ASC")
+func (p *Postgres) GetWalletOwner(id int) (_ int, err error) {
+	defer translateError(&err)
 
-	return selectQuery.ToSql()
+	var userID int
+	err = p.Db.QueryRow(`SELECT user_id FROM user_wallet WHERE id = $1`, id).Scan(&userID)
+	return userID, err
 }
 
-func (p *Postgres) GetWallets(filter wallet.Wallet) ([]wallet.Wallet, error) {
-	selectSql, args, err := prepareSelectSqlWithFilter(filter)
+func (p *Postgres) GetWallets(filter wallet.Filter, page wallet.Page) (_ wallet.WalletPage, err error) {
+	defer translateError(&err)
+
+	selectSql, args, err := sqlquery.Postgres.SelectWallets(filter, page)
 	log.Println(selectSql)
 	if err != nil {
-		return nil, err
+		return wallet.WalletPage{}, err
 	}
 
 	rows, err := p.Db.Query(selectSql, args...)
 	if err != nil {
-		return nil, err
+		return wallet.WalletPage{}, err
 	}
 	defer rows.Close()
 
-	return scanWalletsFromRows(rows)
+	wallets, err := scanWalletsFromRows(rows)
+	if err != nil {
+		return wallet.WalletPage{}, err
+	}
+	return page.NewWalletPage(wallets), nil
 }
 
-func (p *Postgres) CreateWallet(wallet *wallet.Wallet) error {
+func (p *Postgres) CreateWallet(w *wallet.Wallet) (err error) {
+	defer translateError(&err)
+
+	tx, err := p.Db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err = lockUserForShare(tx, w.UserID); err != nil {
+		return err
+	}
+
 	insertSql := `
-		INSERT INTO user_wallet (user_id, user_name, wallet_name, wallet_type, balance)
+		INSERT INTO user_wallet (user_id, wallet_name, wallet_type, currency, balance)
 		VALUES ($1, $2, $3, $4, $5)
-		RETURNING id`
-	args := []interface{}{wallet.UserID, wallet.UserName, wallet.WalletName, wallet.WalletType, wallet.Balance}
+		RETURNING ` + sqlquery.WalletColumns
+	args := []interface{}{w.UserID, w.WalletName, w.WalletType, w.Currency, w.Balance}
 
-	err := p.Db.QueryRow(insertSql, args...).Scan(&wallet.ID)
+	created, err := scanWalletFromRow(tx.QueryRow(insertSql, args...))
 	if err != nil {
 		return err
 	}
 
-	*wallet, err = p.getWalletByID(wallet.ID)
+	err = insertTransaction(tx, created, created.Balance, wallet.ReasonOpeningBalance, wallet.NewCorrelationID())
 	if err != nil {
 		return err
 	}
 
+	if err = tx.Commit(); err != nil {
+		return err
+	}
+
+	*w = created
 	return nil
 }
 
-func (p *Postgres) UpdateWallet(wallet *wallet.Wallet) error {
-	updateSql := `UPDATE user_wallet SET balance = $1 WHERE id = $2`
+func (p *Postgres) UpdateWallet(w *wallet.Wallet) (err error) {
+	defer translateError(&err)
 
-	_, err := p.Db.Exec(updateSql, wallet.Balance, wallet.ID)
+	tx, err := p.Db.Begin()
 	if err != nil {
 		return err
 	}
+	defer tx.Rollback()
 
-	*wallet, err = p.getWalletByID(wallet.ID)
+	current, err := getWallet(tx, w.ID)
 	if err != nil {
 		return err
 	}
+	if current.Version != w.Version {
+		return wallet.ErrVersionMismatch
+	}
+	if err = wallet.CheckActive(current); err != nil {
+		return err
+	}
+	if err = wallet.CheckAmountPrecision(current.Currency, w.Balance); err != nil {
+		return err
+	}
+	if w.Balance.IsNegative() && !wallet.AllowsNegativeBalance(current.WalletType) {
+		return wallet.ErrNegativeBalance
+	}
+	change, err := w.Balance.Sub(current.Balance)
+	if err != nil {
+		return err
+	}
+
+	// the version check is repeated by the update itself, so a change
+	// committed since the read above is not overwritten
+	updateSql := `
+		UPDATE user_wallet SET balance = $1, version = version + 1 
+		WHERE id = $2 AND version = $3
+		RETURNING ` + sqlquery.WalletColumns
+
+	updated, err := scanWalletFromRow(tx.QueryRow(updateSql, w.Balance, w.ID, w.Version))
+	if errors.Is(err, sql.ErrNoRows) {
+		return wallet.ErrVersionMismatch
+	}
+	if err != nil {
+		return err
+	}
+
+	err = insertTransaction(tx, updated, change, wallet.ReasonBalanceUpdate, wallet.NewCorrelationID())
+	if err != nil {
+		return err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return err
+	}
 
+	*w = updated
 	return nil
 }
 
-func (p *Postgres) DeleteWallet(userID int) error {
-	deleteSql := `DELETE FROM user_wallet WHERE user_id = $1`
+func (p *Postgres) PatchWallet(id, version int, patch wallet.WalletPatch) (_ wallet.Wallet, err error) {
+	defer translateError(&err)
+
+	w, err := getWallet(p.Db, id)
+	if err != nil {
+		return wallet.Wallet{}, err
+	}
+	if w.Version != version {
+		return wallet.Wallet{}, wallet.ErrVersionMismatch
+	}
+	if err = patch.Apply(&w); err != nil {
+		return wallet.Wallet{}, err
+	}
+
+	updateSql := `
+		UPDATE user_wallet SET wallet_name = $1, wallet_type = $2, version = version + 1
+		WHERE id = $3 AND version = $4
+		RETURNING ` + sqlquery.WalletColumns
+
+	patched, err := scanWalletFromRow(p.Db.QueryRow(updateSql, w.WalletName, w.WalletType, id, version))
+	if errors.Is(err, sql.ErrNoRows) {
+		return wallet.Wallet{}, wallet.ErrVersionMismatch
+	}
+	return patched, err
+}
+
+func (p *Postgres) ChangeWalletStatus(id, version int, status, reason string) (_ wallet.Wallet, err error) {
+	defer translateError(&err)
+
+	tx, err := p.Db.Begin()
+	if err != nil {
+		return wallet.Wallet{}, err
+	}
+	defer tx.Rollback()
+
+	current, err := lockWalletForUpdate(tx, id)
+	if err != nil {
+		return wallet.Wallet{}, err
+	}
+	if current.Version != version {
+		return wallet.Wallet{}, wallet.ErrVersionMismatch
+	}
+	if err = wallet.CheckStatusChange(current, status); err != nil {
+		return wallet.Wallet{}, err
+	}
+
+	updateSql := `
+		UPDATE user_wallet SET status = $1, status_reason = $2, version = version + 1 
+		WHERE id = $3 AND version = $4
+		RETURNING ` + sqlquery.WalletColumns
+
+	changed, err := scanWalletFromRow(tx.QueryRow(updateSql, status, reason, id, version))
+	if errors.Is(err, sql.ErrNoRows) {
+		return wallet.Wallet{}, wallet.ErrVersionMismatch
+	}
+	if err != nil {
+		return wallet.Wallet{}, err
+	}
+
+	return changed, tx.Commit()
+}
+
+func (p *Postgres) DeleteWallet(id, version int) (err error) {
+	defer translateError(&err)
 
-	_, err := p.Db.Exec(deleteSql, userID)
+	tx, err := p.Db.Begin()
 	if err != nil {
 		return err
 	}
+	defer tx.Rollback()
 
-	return nil
+	// the lock keeps the wallet from being frozen between the check and the delete
+	current, err := lockWalletForUpdate(tx, id)
+	if err != nil {
+		return err
+	}
+	if current.Version != version {
+		return wallet.ErrVersionMismatch
+	}
+	if err = wallet.CheckDeletable(current); err != nil {
+		return err
+	}
+
+	deleteSql := `
+		UPDATE user_wallet SET deleted_at = CURRENT_TIMESTAMP, version = version + 1 
+		WHERE id = $1 AND version = $2 AND deleted_at IS NULL`
+
+	if _, err = tx.Exec(deleteSql, id, version); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// lockUserWallets locks the live wallets of the user.
+func lockUserWallets(tx *sql.Tx, userID int) ([]wallet.Wallet, error) {
+	selectSql := `
+		SELECT ` + sqlquery.WalletColumns + ` 
+		FROM user_wallet 
+		WHERE user_id = $1 AND deleted_at IS NULL
+		FOR UPDATE`
+
+	rows, err := tx.Query(selectSql, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanWalletsFromRows(rows)
+}
+
+func (p *Postgres) DeleteUserWallets(userID int) (err error) {
+	defer translateError(&err)
+
+	tx, err := p.Db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	live, err := lockUserWallets(tx, userID)
+	if err != nil {
+		return err
+	}
+	if err = wallet.CheckDeletable(live...); err != nil {
+		return err
+	}
+
+	deleteSql := `
+		UPDATE user_wallet SET deleted_at = CURRENT_TIMESTAMP, version = version + 1 
+		WHERE user_id = $1 AND deleted_at IS NULL`
+
+	if _, err = tx.Exec(deleteSql, userID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (p *Postgres) RestoreWallet(id int) (_ wallet.Wallet, err error) {
+	defer translateError(&err)
+
+	restoreSql := `
+		UPDATE user_wallet SET deleted_at = NULL, version = version + 1 
+		WHERE id = $1 AND deleted_at IS NOT NULL
+		RETURNING ` + sqlquery.WalletColumns
+
+	restored, err := scanWalletFromRow(p.Db.QueryRow(restoreSql, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		// not deleted, or no such wallet
+		return getWallet(p.Db, id)
+	}
+	return restored, err
 }
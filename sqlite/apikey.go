@@ -0,0 +1,198 @@
+package sqlite
+
+import (
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/golfz/fun-exercise-api/sqlquery"
+	"github.com/golfz/fun-exercise-api/wallet"
+)
+
+// SQLite has no array type, scopes are kept comma separated.
+func joinScopes(scopes []wallet.Permission) string {
+	s := make([]string, 0, len(scopes))
+	for _, scope := range scopes {
+		s = append(s, string(scope))
+	}
+	return strings.Join(s, ",")
+}
+
+func splitScopes(s string) []wallet.Permission {
+	scopes := make([]wallet.Permission, 0)
+	if s == "" {
+		return scopes
+	}
+	for _, scope := range strings.Split(s, ",") {
+		scopes = append(scopes, wallet.Permission(scope))
+	}
+	return scopes
+}
+
+func scanAPIKey(row sq.RowScanner) (wallet.APIKey, error) {
+	var k wallet.APIKey
+	var scopes string
+	err := row.Scan(&k.ID, &k.Prefix, &k.Hash, &k.Name, &k.OwnerID, &scopes, &k.CreatedAt, &k.LastUsedAt, &k.RevokedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return wallet.APIKey{}, wallet.ErrAPIKeyNotFound
+	}
+	k.Scopes = splitScopes(scopes)
+	return k, err
+}
+
+func getAPIKey(q queryRower, where sq.Eq) (wallet.APIKey, error) {
+	selectSql, args, err := sqlquery.SQLite.Builder().Select(sqlquery.APIKeyColumns).
+		From("api_key").
+		Where(where).
+		ToSql()
+	if err != nil {
+		return wallet.APIKey{}, err
+	}
+
+	return scanAPIKey(q.QueryRow(selectSql, args...))
+}
+
+func (s *SQLite) GetAPIKeys() (_ []wallet.APIKey, err error) {
+	defer translateError(&err)
+
+	selectSql, args, err := sqlquery.SQLite.Builder().Select(sqlquery.APIKeyColumns).
+		From("api_key").
+		OrderBy("id ASC").
+		ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := s.Db.Query(selectSql, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	keys := make([]wallet.APIKey, 0)
+	for rows.Next() {
+		k, err := scanAPIKey(rows)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, k)
+	}
+	return keys, rows.Err()
+}
+
+func (s *SQLite) GetAPIKeyByPrefix(prefix string) (_ wallet.APIKey, err error) {
+	defer translateError(&err)
+
+	return getAPIKey(s.Db, sq.Eq{"prefix": prefix})
+}
+
+func (s *SQLite) CreateAPIKey(k *wallet.APIKey) (err error) {
+	defer translateError(&err)
+
+	tx, err := s.Db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err = getUser(tx, k.OwnerID); err != nil {
+		return err
+	}
+
+	insertSql, args, err := sqlquery.SQLite.Builder().Insert("api_key").
+		Columns("prefix", "hash", "name", "owner_id", "scopes", "created_at").
+		Values(k.Prefix, k.Hash, k.Name, k.OwnerID, joinScopes(k.Scopes), now()).
+		Suffix("RETURNING " + sqlquery.APIKeyColumns).
+		ToSql()
+	if err != nil {
+		return err
+	}
+
+	created, err := scanAPIKey(tx.QueryRow(insertSql, args...))
+	if err != nil {
+		return err
+	}
+	if err = tx.Commit(); err != nil {
+		return err
+	}
+
+	*k = created
+	return nil
+}
+
+func (s *SQLite) RotateAPIKey(id int, prefix, hash string) (_ wallet.APIKey, err error) {
+	defer translateError(&err)
+
+	tx, err := s.Db.Begin()
+	if err != nil {
+		return wallet.APIKey{}, err
+	}
+	defer tx.Rollback()
+
+	k, err := getAPIKey(tx, sq.Eq{"id": id})
+	if err != nil {
+		return wallet.APIKey{}, err
+	}
+	if k.RevokedAt != nil {
+		return wallet.APIKey{}, wallet.ErrAPIKeyRevoked
+	}
+
+	updateSql, args, err := sqlquery.SQLite.Builder().Update("api_key").
+		Set("prefix", prefix).
+		Set("hash", hash).
+		Where(sq.Eq{"id": id}).
+		Suffix("RETURNING " + sqlquery.APIKeyColumns).
+		ToSql()
+	if err != nil {
+		return wallet.APIKey{}, err
+	}
+
+	rotated, err := scanAPIKey(tx.QueryRow(updateSql, args...))
+	if err != nil {
+		return wallet.APIKey{}, err
+	}
+	return rotated, tx.Commit()
+}
+
+func (s *SQLite) RevokeAPIKey(id int) (_ wallet.APIKey, err error) {
+	defer translateError(&err)
+
+	// a revoked key keeps the time it was first revoked
+	updateSql, args, err := sqlquery.SQLite.Builder().Update("api_key").
+		Set("revoked_at", sq.Expr("COALESCE(revoked_at, ?)", now())).
+		Where(sq.Eq{"id": id}).
+		Suffix("RETURNING " + sqlquery.APIKeyColumns).
+		ToSql()
+	if err != nil {
+		return wallet.APIKey{}, err
+	}
+
+	return scanAPIKey(s.Db.QueryRow(updateSql, args...))
+}
+
+func (s *SQLite) TouchAPIKey(id int, at time.Time) (err error) {
+	defer translateError(&err)
+
+	updateSql, args, err := sqlquery.SQLite.Builder().Update("api_key").
+		Set("last_used_at", at.UTC()).
+		Where(sq.Eq{"id": id}).
+		ToSql()
+	if err != nil {
+		return err
+	}
+
+	result, err := s.Db.Exec(updateSql, args...)
+	if err != nil {
+		return err
+	}
+	touched, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if touched == 0 {
+		return wallet.ErrAPIKeyNotFound
+	}
+	return nil
+}
@@ -0,0 +1,59 @@
+package sqlite
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/glebarez/go-sqlite"
+	"github.com/golfz/fun-exercise-api/wallet"
+)
+
+// https://www.sqlite.org/rescode.html
+const (
+	codeBusy             = 5
+	codeConstraintCheck  = 275
+	codeConstraintFK     = 787
+	codeConstraintUnique = 2067
+)
+
+// translateError replaces the database error in *err with the wallet error it means,
+// like the Postgres store.
+func translateError(err *error) {
+	*err = storeError(*err)
+}
+
+// storeError maps the violation of a known constraint to its wallet error, anything
+// unexpected stays a server error. SQLite names the constraint in the message only.
+func storeError(err error) error {
+	var sqliteErr *sqlite.Error
+	if !errors.As(err, &sqliteErr) {
+		return err
+	}
+	message := sqliteErr.Error()
+	switch sqliteErr.Code() {
+	case codeConstraintUnique:
+		switch {
+		case strings.Contains(message, "user_wallet.wallet_name"):
+			return wallet.ErrDuplicateWallet
+		case strings.Contains(message, "api_key.prefix"):
+			// two random prefixes met, a retry draws another
+			return wallet.ErrConflict
+		}
+	case codeConstraintCheck:
+		// the wallet_type CHECK stands in for the Postgres enum
+		if strings.Contains(message, "user_wallet_balance_check") {
+			return wallet.ErrInsufficientFunds
+		}
+		if strings.Contains(message, "user_wallet_wallet_type_check") {
+			return wallet.ErrInvalidRequest
+		}
+	case codeConstraintFK:
+		// every foreign key points at users and the store checks the user first,
+		// so the user was deleted under it
+		return wallet.ErrUserNotFound
+	case codeBusy:
+		return wallet.ErrConflict
+	}
+	return fmt.Errorf("sqlite: %w", err)
+}
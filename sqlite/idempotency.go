@@ -0,0 +1,103 @@
+package sqlite
+
+import (
+	"database/sql"
+	"errors"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/golfz/fun-exercise-api/sqlquery"
+	"github.com/golfz/fun-exercise-api/wallet"
+)
+
+func (s *SQLite) ReserveIdempotencyKey(record wallet.IdempotencyRecord) (err error) {
+	defer translateError(&err)
+
+	// expired records are only replaced when their key comes back, purge the rest here
+	purgeSql, args, err := sqlquery.SQLite.PurgeIdempotencyKeys(record.CreatedAt)
+	if err != nil {
+		return err
+	}
+	if _, err = s.Db.Exec(purgeSql, args...); err != nil {
+		return err
+	}
+
+	insertSql, args, err := sqlquery.SQLite.ReserveIdempotencyKey(record)
+	if err != nil {
+		return err
+	}
+
+	result, err := s.Db.Exec(insertSql, args...)
+	if err != nil {
+		return err
+	}
+	reserved, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if reserved == 0 {
+		return wallet.ErrIdempotencyKeyInUse
+	}
+	return nil
+}
+
+func (s *SQLite) GetIdempotencyKey(key string) (_ wallet.IdempotencyRecord, err error) {
+	defer translateError(&err)
+
+	selectSql, args, err := sqlquery.SQLite.Builder().Select(sqlquery.IdempotencyKeyColumns).
+		From("idempotency_key").
+		Where(sq.Eq{"key": key}).
+		ToSql()
+	if err != nil {
+		return wallet.IdempotencyRecord{}, err
+	}
+
+	var r wallet.IdempotencyRecord
+	err = s.Db.QueryRow(selectSql, args...).Scan(&r.Key, &r.RequestHash, &r.StatusCode, &r.ContentType, &r.ETag, &r.Body, &r.CreatedAt, &r.ExpiresAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return wallet.IdempotencyRecord{}, wallet.ErrIdempotencyKeyNotFound
+	}
+	return r, err
+}
+
+func (s *SQLite) CompleteIdempotencyKey(record wallet.IdempotencyRecord) (err error) {
+	defer translateError(&err)
+
+	if record.Body == nil {
+		record.Body = []byte{} // a nil slice would be written as NULL
+	}
+
+	updateSql, args, err := sqlquery.SQLite.Builder().Update("idempotency_key").
+		SetMap(map[string]interface{}{"status_code": record.StatusCode, "content_type": record.ContentType, "etag": record.ETag, "response_body": record.Body}).
+		Where(sq.Eq{"key": record.Key}).
+		ToSql()
+	if err != nil {
+		return err
+	}
+
+	result, err := s.Db.Exec(updateSql, args...)
+	if err != nil {
+		return err
+	}
+	completed, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if completed == 0 {
+		return wallet.ErrIdempotencyKeyNotFound
+	}
+	return nil
+}
+
+func (s *SQLite) DeleteIdempotencyKey(key string) (err error) {
+	defer translateError(&err)
+
+	deleteSql, args, err := sqlquery.SQLite.Builder().Delete("idempotency_key").
+		Where(sq.Eq{"key": key}).
+		ToSql()
+	if err != nil {
+		return err
+	}
+
+	_, err = s.Db.Exec(deleteSql, args...)
+	return err
+}
@@ -0,0 +1,86 @@
+-- SQLite has no DECIMAL type, money columns hold INTEGER counts of
+-- 1/100000000 units (see wallet.Money)
+CREATE TABLE IF NOT EXISTS users (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	name TEXT NOT NULL,
+	created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS user_wallet (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	user_id INTEGER NOT NULL CONSTRAINT user_wallet_user_id_fkey REFERENCES users (id),
+	wallet_name TEXT NOT NULL,
+	wallet_type TEXT NOT NULL CONSTRAINT user_wallet_wallet_type_check
+		CHECK (wallet_type IN ('Savings', 'Credit Card', 'Crypto Wallet')),
+	currency TEXT NOT NULL DEFAULT 'THB',
+	balance INTEGER NOT NULL,
+	status TEXT NOT NULL DEFAULT 'active' CONSTRAINT user_wallet_status_check
+		CHECK (status IN ('active', 'frozen', 'closed')),
+	status_reason TEXT NOT NULL DEFAULT '',
+	version INTEGER NOT NULL DEFAULT 1,
+	created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	-- set while the wallet is soft-deleted
+	deleted_at TIMESTAMP,
+	-- only credit cards may be overdrawn
+	CONSTRAINT user_wallet_balance_check CHECK (wallet_type = 'Credit Card' OR balance >= 0)
+);
+
+-- a user cannot have two live wallets with the same name
+CREATE UNIQUE INDEX IF NOT EXISTS user_wallet_user_id_wallet_name_idx ON user_wallet (user_id, wallet_name)
+	WHERE deleted_at IS NULL;
+
+CREATE TABLE IF NOT EXISTS wallet_transaction (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	wallet_id INTEGER NOT NULL,
+	type TEXT NOT NULL CHECK (type IN ('credit', 'debit')),
+	amount INTEGER NOT NULL CHECK (amount > 0),
+	balance INTEGER NOT NULL,
+	reason TEXT NOT NULL,
+	correlation_id TEXT NOT NULL,
+	created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS wallet_transaction_wallet_id_idx ON wallet_transaction (wallet_id);
+
+CREATE TABLE IF NOT EXISTS fx_conversion (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	from_wallet_id INTEGER NOT NULL,
+	to_wallet_id INTEGER NOT NULL,
+	from_currency TEXT NOT NULL,
+	to_currency TEXT NOT NULL,
+	from_amount INTEGER NOT NULL,
+	to_amount INTEGER NOT NULL,
+	rate TEXT NOT NULL,
+	correlation_id TEXT NOT NULL,
+	created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- responses kept for Idempotency-Key replays, status_code is 0 while the request runs
+CREATE TABLE IF NOT EXISTS idempotency_key (
+	key TEXT PRIMARY KEY,
+	request_hash TEXT NOT NULL,
+	status_code INTEGER NOT NULL DEFAULT 0,
+	content_type TEXT NOT NULL DEFAULT '',
+	etag TEXT NOT NULL DEFAULT '',
+	response_body BLOB NOT NULL DEFAULT '',
+	created_at TIMESTAMP NOT NULL,
+	expires_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idempotency_key_expires_at_idx ON idempotency_key (expires_at);
+
+-- keys of service callers, only the SHA-256 hash of a key is kept, the prefix finds it;
+-- scopes are comma separated
+CREATE TABLE IF NOT EXISTS api_key (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	prefix TEXT NOT NULL UNIQUE,
+	hash TEXT NOT NULL,
+	name TEXT NOT NULL,
+	owner_id INTEGER NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+	scopes TEXT NOT NULL DEFAULT '',
+	created_at TIMESTAMP NOT NULL,
+	last_used_at TIMESTAMP,
+	revoked_at TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS api_key_owner_id_idx ON api_key (owner_id);
@@ -0,0 +1,52 @@
+// Package sqlite is a wallet.Storer backed by an embedded SQLite database,
+// for single-binary deployments that cannot reach Postgres.
+package sqlite
+
+import (
+	"database/sql"
+	_ "embed"
+	"strings"
+	"time"
+
+	_ "github.com/glebarez/go-sqlite"
+)
+
+//go:embed schema.sql
+var schema string
+
+type SQLite struct {
+	Db *sql.DB
+}
+
+// New opens (or creates) the database file at path, ":memory:" keeps it in memory.
+func New(path string) (*SQLite, error) {
+	db, err := sql.Open("sqlite", dsn(path))
+	if err != nil {
+		return nil, err
+	}
+
+	// SQLite allows one writer at a time, a single connection serialises
+	// every transaction, which is what keeps balance changes atomic
+	db.SetMaxOpenConns(1)
+
+	if _, err = db.Exec(schema); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return &SQLite{Db: db}, nil
+}
+
+// dsn adds the pragmas to path, SQLite leaves foreign keys unchecked unless asked,
+// and the driver runs the pragmas of the DSN on every connection it opens.
+func dsn(path string) string {
+	separator := "?"
+	if strings.Contains(path, "?") {
+		separator = "&"
+	}
+	return path + separator + "_pragma=foreign_keys(1)"
+}
+
+func now() time.Time {
+	return time.Now().UTC()
+}
@@ -0,0 +1,50 @@
+package sqlite
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/golfz/fun-exercise-api/wallet"
+	"github.com/golfz/fun-exercise-api/wallet/storertest"
+)
+
+func newStore(t *testing.T) *SQLite {
+	s, err := New(":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { s.Db.Close() })
+	return s
+}
+
+func TestStorerConformance(t *testing.T) {
+	storertest.Run(t, func(t *testing.T) wallet.Storer {
+		return newStore(t)
+	})
+}
+
+func TestIdempotencyStorerConformance(t *testing.T) {
+	storertest.RunIdempotency(t, func(t *testing.T) wallet.IdempotencyStorer {
+		return newStore(t)
+	})
+}
+
+func TestForeignKeysOnEveryConnection(t *testing.T) {
+	s, err := New(filepath.Join(t.TempDir(), "wallet.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Db.Close()
+	// no idle connection is kept, so every query runs on a new one
+	s.Db.SetMaxIdleConns(0)
+
+	for i := 0; i < 2; i++ {
+		var enabled int
+		if err := s.Db.QueryRow(`PRAGMA foreign_keys`).Scan(&enabled); err != nil {
+			t.Fatal(err)
+		}
+		if enabled != 1 {
+			t.Errorf("foreign_keys = %d on connection %d, want 1", enabled, i+1)
+		}
+	}
+}
@@ -0,0 +1,107 @@
+package sqlite
+
+import (
+	"database/sql"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/golfz/fun-exercise-api/sqlquery"
+	"github.com/golfz/fun-exercise-api/wallet"
+)
+
+// insertTransaction appends a ledger entry for a signed balance change,
+// it must run in the same transaction as the change itself.
+func insertTransaction(tx *sql.Tx, w wallet.Wallet, amount wallet.Money, reason, correlationID string) error {
+	if amount.IsZero() {
+		return nil
+	}
+
+	insertSql, args, err := sqlquery.SQLite.Builder().Insert("wallet_transaction").
+		Columns("wallet_id", "type", "amount", "balance", "reason", "correlation_id", "created_at").
+		Values(w.ID, wallet.TransactionTypeOf(amount), money(amount.Abs()), money(w.Balance), reason, correlationID, now()).
+		ToSql()
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(insertSql, args...)
+	return err
+}
+
+func (s *SQLite) GetTransactions(walletID int) (_ []wallet.Transaction, err error) {
+	defer translateError(&err)
+
+	selectSql, args, err := sqlquery.SQLite.Builder().
+		Select("id, wallet_id, type, amount, balance, reason, correlation_id, created_at").
+		From("wallet_transaction").
+		Where(sq.Eq{"wallet_id": walletID}).
+		OrderBy("id ASC").
+		ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := s.Db.Query(selectSql, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	transactions := make([]wallet.Transaction, 0)
+	for rows.Next() {
+		var t wallet.Transaction
+		err := rows.Scan(&t.ID, &t.WalletID, &t.Type, moneyScanner(&t.Amount), moneyScanner(&t.Balance), &t.Reason, &t.CorrelationID, &t.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		transactions = append(transactions, t)
+	}
+	return transactions, rows.Err()
+}
+
+// changeBalance adds the signed amount to the wallet balance and records it in the ledger.
+func (s *SQLite) changeBalance(walletID int, amount wallet.Money, reason string) (_ wallet.Wallet, err error) {
+	defer translateError(&err)
+
+	tx, err := s.Db.Begin()
+	if err != nil {
+		return wallet.Wallet{}, err
+	}
+	defer tx.Rollback()
+
+	current, err := getWallet(tx, walletID)
+	if err != nil {
+		return wallet.Wallet{}, err
+	}
+	if err = wallet.CheckActive(current); err != nil {
+		return wallet.Wallet{}, err
+	}
+	if err = wallet.CheckAmountPrecision(current.Currency, amount); err != nil {
+		return wallet.Wallet{}, err
+	}
+	if amount.IsNegative() && !wallet.CanDebit(current, amount.Neg()) {
+		return wallet.Wallet{}, wallet.ErrInsufficientFunds
+	}
+
+	updated, err := addBalance(tx, current, amount)
+	if err != nil {
+		return wallet.Wallet{}, err
+	}
+
+	if err = insertTransaction(tx, updated, amount, reason, wallet.NewCorrelationID()); err != nil {
+		return wallet.Wallet{}, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return wallet.Wallet{}, err
+	}
+
+	return updated, nil
+}
+
+func (s *SQLite) Deposit(walletID int, amount wallet.Money) (wallet.Wallet, error) {
+	return s.changeBalance(walletID, amount, wallet.ReasonDeposit)
+}
+
+func (s *SQLite) Withdraw(walletID int, amount wallet.Money) (wallet.Wallet, error) {
+	return s.changeBalance(walletID, amount.Neg(), wallet.ReasonWithdrawal)
+}
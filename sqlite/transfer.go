@@ -0,0 +1,137 @@
+package sqlite
+
+import (
+	"github.com/golfz/fun-exercise-api/sqlquery"
+	"github.com/golfz/fun-exercise-api/wallet"
+)
+
+func (s *SQLite) Transfer(fromID, toID int, amount wallet.Money) (_ wallet.TransferResult, err error) {
+	defer translateError(&err)
+
+	tx, err := s.Db.Begin()
+	if err != nil {
+		return wallet.TransferResult{}, err
+	}
+	defer tx.Rollback()
+
+	from, err := getWallet(tx, fromID)
+	if err != nil {
+		return wallet.TransferResult{}, err
+	}
+	to, err := getWallet(tx, toID)
+	if err != nil {
+		return wallet.TransferResult{}, err
+	}
+	if err = wallet.CheckActive(from, to); err != nil {
+		return wallet.TransferResult{}, err
+	}
+
+	if from.Currency != to.Currency {
+		return wallet.TransferResult{}, wallet.ErrCurrencyMismatch
+	}
+	if err = wallet.CheckAmountPrecision(from.Currency, amount); err != nil {
+		return wallet.TransferResult{}, err
+	}
+	if !wallet.CanDebit(from, amount) {
+		return wallet.TransferResult{}, wallet.ErrInsufficientFunds
+	}
+
+	var result wallet.TransferResult
+	if result.From, err = addBalance(tx, from, amount.Neg()); err != nil {
+		return wallet.TransferResult{}, err
+	}
+	if result.To, err = addBalance(tx, to, amount); err != nil {
+		return wallet.TransferResult{}, err
+	}
+
+	// both ledger entries share one correlation id
+	correlationID := wallet.NewCorrelationID()
+	if err = insertTransaction(tx, result.From, amount.Neg(), wallet.ReasonTransferOut, correlationID); err != nil {
+		return wallet.TransferResult{}, err
+	}
+	if err = insertTransaction(tx, result.To, amount, wallet.ReasonTransferIn, correlationID); err != nil {
+		return wallet.TransferResult{}, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return wallet.TransferResult{}, err
+	}
+
+	return result, nil
+}
+
+func (s *SQLite) Convert(fromID, toID int, amount wallet.Money, rates wallet.FXRateProvider) (_ wallet.Conversion, err error) {
+	defer translateError(&err)
+
+	tx, err := s.Db.Begin()
+	if err != nil {
+		return wallet.Conversion{}, err
+	}
+	defer tx.Rollback()
+
+	from, err := getWallet(tx, fromID)
+	if err != nil {
+		return wallet.Conversion{}, err
+	}
+	to, err := getWallet(tx, toID)
+	if err != nil {
+		return wallet.Conversion{}, err
+	}
+	if err = wallet.CheckActive(from, to); err != nil {
+		return wallet.Conversion{}, err
+	}
+
+	quote, err := wallet.Quote(rates, from.Currency, to.Currency, amount)
+	if err != nil {
+		return wallet.Conversion{}, err
+	}
+	if !wallet.CanDebit(from, amount) {
+		return wallet.Conversion{}, wallet.ErrInsufficientFunds
+	}
+
+	conversion := wallet.Conversion{
+		FromWalletID:  fromID,
+		ToWalletID:    toID,
+		FromCurrency:  quote.From,
+		ToCurrency:    quote.To,
+		FromAmount:    quote.Amount,
+		ToAmount:      quote.ConvertedAmount,
+		Rate:          quote.Rate,
+		CorrelationID: wallet.NewCorrelationID(),
+		CreatedAt:     now(),
+	}
+
+	if conversion.From, err = addBalance(tx, from, conversion.FromAmount.Neg()); err != nil {
+		return wallet.Conversion{}, err
+	}
+	if conversion.To, err = addBalance(tx, to, conversion.ToAmount); err != nil {
+		return wallet.Conversion{}, err
+	}
+
+	err = insertTransaction(tx, conversion.From, conversion.FromAmount.Neg(), wallet.ReasonConversionOut, conversion.CorrelationID)
+	if err != nil {
+		return wallet.Conversion{}, err
+	}
+	err = insertTransaction(tx, conversion.To, conversion.ToAmount, wallet.ReasonConversionIn, conversion.CorrelationID)
+	if err != nil {
+		return wallet.Conversion{}, err
+	}
+
+	insertSql, args, err := sqlquery.SQLite.Builder().Insert("fx_conversion").
+		Columns("from_wallet_id", "to_wallet_id", "from_currency", "to_currency", "from_amount", "to_amount", "rate", "correlation_id", "created_at").
+		Values(fromID, toID, conversion.FromCurrency, conversion.ToCurrency, money(conversion.FromAmount), money(conversion.ToAmount), conversion.Rate, conversion.CorrelationID, conversion.CreatedAt).
+		Suffix("RETURNING id").
+		ToSql()
+	if err != nil {
+		return wallet.Conversion{}, err
+	}
+	if err = tx.QueryRow(insertSql, args...).Scan(&conversion.ID); err != nil {
+		return wallet.Conversion{}, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return wallet.Conversion{}, err
+	}
+
+	return conversion, nil
+}
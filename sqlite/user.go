@@ -0,0 +1,143 @@
+package sqlite
+
+import (
+	"database/sql"
+	"errors"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/golfz/fun-exercise-api/sqlquery"
+	"github.com/golfz/fun-exercise-api/wallet"
+)
+
+func scanUser(row sq.RowScanner) (wallet.User, error) {
+	var u wallet.User
+	err := row.Scan(&u.ID, &u.Name, &u.CreatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return wallet.User{}, wallet.ErrUserNotFound
+	}
+	return u, err
+}
+
+func getUser(q queryRower, id int) (wallet.User, error) {
+	selectSql, args, err := sqlquery.SQLite.Builder().Select(sqlquery.UserColumns).
+		From("users").
+		Where(sq.Eq{"id": id}).
+		ToSql()
+	if err != nil {
+		return wallet.User{}, err
+	}
+
+	return scanUser(q.QueryRow(selectSql, args...))
+}
+
+func (s *SQLite) GetUsers() (_ []wallet.User, err error) {
+	defer translateError(&err)
+
+	selectSql, args, err := sqlquery.SQLite.Builder().Select(sqlquery.UserColumns).
+		From("users").
+		OrderBy("id ASC").
+		ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := s.Db.Query(selectSql, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	users := make([]wallet.User, 0)
+	for rows.Next() {
+		u, err := scanUser(rows)
+		if err != nil {
+			return nil, err
+		}
+		users = append(users, u)
+	}
+	return users, rows.Err()
+}
+
+func (s *SQLite) GetUserByID(id int) (_ wallet.User, err error) {
+	defer translateError(&err)
+
+	return getUser(s.Db, id)
+}
+
+func (s *SQLite) CreateUser(u *wallet.User) (err error) {
+	defer translateError(&err)
+
+	insertSql, args, err := sqlquery.SQLite.Builder().Insert("users").
+		Columns("name", "created_at").
+		Values(u.Name, now()).
+		Suffix("RETURNING " + sqlquery.UserColumns).
+		ToSql()
+	if err != nil {
+		return err
+	}
+
+	created, err := scanUser(s.Db.QueryRow(insertSql, args...))
+	if err != nil {
+		return err
+	}
+
+	*u = created
+	return nil
+}
+
+func (s *SQLite) UpdateUser(u *wallet.User) (err error) {
+	defer translateError(&err)
+
+	updateSql, args, err := sqlquery.SQLite.Builder().Update("users").
+		Set("name", u.Name).
+		Where(sq.Eq{"id": u.ID}).
+		Suffix("RETURNING " + sqlquery.UserColumns).
+		ToSql()
+	if err != nil {
+		return err
+	}
+
+	updated, err := scanUser(s.Db.QueryRow(updateSql, args...))
+	if err != nil {
+		return err
+	}
+
+	*u = updated
+	return nil
+}
+
+func (s *SQLite) DeleteUser(id int) (err error) {
+	defer translateError(&err)
+
+	tx, err := s.Db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err = getUser(tx, id); err != nil {
+		return err
+	}
+
+	// soft-deleted wallets count too, they can still be restored
+	var hasWallets bool
+	err = tx.QueryRow(`SELECT EXISTS (SELECT 1 FROM user_wallet WHERE user_id = ?)`, id).Scan(&hasWallets)
+	if err != nil {
+		return err
+	}
+	if hasWallets {
+		return wallet.ErrUserHasWallets
+	}
+
+	deleteSql, args, err := sqlquery.SQLite.Builder().Delete("users").
+		Where(sq.Eq{"id": id}).
+		ToSql()
+	if err != nil {
+		return err
+	}
+
+	if _, err = tx.Exec(deleteSql, args...); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
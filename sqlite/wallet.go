@@ -0,0 +1,405 @@
+package sqlite
+
+import (
+	"database/sql"
+	"errors"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/golfz/fun-exercise-api/sqlquery"
+	"github.com/golfz/fun-exercise-api/wallet"
+)
+
+var (
+	money        = sqlquery.SQLite.MoneyValue
+	moneyScanner = sqlquery.SQLite.MoneyScanner
+)
+
+// walletFields returns the scan destinations matching sqlquery.WalletColumns.
+func walletFields(w *wallet.Wallet) []interface{} {
+	return []interface{}{&w.ID, &w.UserID, &w.UserName, &w.WalletName, &w.WalletType, &w.Currency, moneyScanner(&w.Balance), &w.Status, &w.StatusReason, &w.Version, &w.CreatedAt, &w.DeletedAt}
+}
+
+func scanWallet(row sq.RowScanner) (wallet.Wallet, error) {
+	var w wallet.Wallet
+	err := row.Scan(walletFields(&w)...)
+	if errors.Is(err, sql.ErrNoRows) {
+		return wallet.Wallet{}, wallet.ErrWalletNotFound
+	}
+	return w, err
+}
+
+// queryRower is satisfied by both *sql.DB and *sql.Tx.
+type queryRower interface {
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
+
+// getWallet reads a wallet that is not soft-deleted.
+func getWallet(q queryRower, id int) (wallet.Wallet, error) {
+	selectSql, args, err := sqlquery.SQLite.Builder().Select(sqlquery.WalletColumns).
+		From("user_wallet").
+		Where(sq.Eq{"id": id, "deleted_at": nil}).
+		ToSql()
+	if err != nil {
+		return wallet.Wallet{}, err
+	}
+
+	return scanWallet(q.QueryRow(selectSql, args...))
+}
+
+func (s *SQLite) GetWalletByID(id int) (_ wallet.Wallet, err error) {
+	defer translateError(&err)
+
+	return getWallet(s.Db, id)
+}
+
+func (s *SQLite) GetWalletOwner(id int) (_ int, err error) {
+	defer translateError(&err)
+
+	selectSql, args, err := sqlquery.SQLite.Builder().Select("user_id").
+		From("user_wallet").
+		Where(sq.Eq{"id": id}).
+		ToSql()
+	if err != nil {
+		return 0, err
+	}
+
+	var userID int
+	err = s.Db.QueryRow(selectSql, args...).Scan(&userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, wallet.ErrWalletNotFound
+	}
+	return userID, err
+}
+
+func (s *SQLite) GetWallets(filter wallet.Filter, page wallet.Page) (_ wallet.WalletPage, err error) {
+	defer translateError(&err)
+
+	selectSql, args, err := sqlquery.SQLite.SelectWallets(filter, page)
+	if err != nil {
+		return wallet.WalletPage{}, err
+	}
+
+	rows, err := s.Db.Query(selectSql, args...)
+	if err != nil {
+		return wallet.WalletPage{}, err
+	}
+	defer rows.Close()
+
+	wallets := make([]wallet.Wallet, 0)
+	for rows.Next() {
+		w, err := scanWallet(rows)
+		if err != nil {
+			return wallet.WalletPage{}, err
+		}
+		wallets = append(wallets, w)
+	}
+	if err = rows.Err(); err != nil {
+		return wallet.WalletPage{}, err
+	}
+	return page.NewWalletPage(wallets), nil
+}
+
+func (s *SQLite) CreateWallet(w *wallet.Wallet) (err error) {
+	defer translateError(&err)
+
+	tx, err := s.Db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err = getUser(tx, w.UserID); err != nil {
+		return err
+	}
+
+	insertSql, args, err := sqlquery.SQLite.Builder().Insert("user_wallet").
+		Columns("user_id", "wallet_name", "wallet_type", "currency", "balance", "created_at").
+		Values(w.UserID, w.WalletName, w.WalletType, w.Currency, money(w.Balance), now()).
+		Suffix("RETURNING " + sqlquery.WalletColumns).
+		ToSql()
+	if err != nil {
+		return err
+	}
+
+	created, err := scanWallet(tx.QueryRow(insertSql, args...))
+	if err != nil {
+		return err
+	}
+
+	err = insertTransaction(tx, created, created.Balance, wallet.ReasonOpeningBalance, wallet.NewCorrelationID())
+	if err != nil {
+		return err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return err
+	}
+
+	*w = created
+	return nil
+}
+
+func (s *SQLite) UpdateWallet(w *wallet.Wallet) (err error) {
+	defer translateError(&err)
+
+	tx, err := s.Db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// transactions run one at a time on the single connection,
+	// so the version cannot move between this read and the update
+	current, err := getWallet(tx, w.ID)
+	if err != nil {
+		return err
+	}
+	if current.Version != w.Version {
+		return wallet.ErrVersionMismatch
+	}
+	if err = wallet.CheckActive(current); err != nil {
+		return err
+	}
+	if err = wallet.CheckAmountPrecision(current.Currency, w.Balance); err != nil {
+		return err
+	}
+	if w.Balance.IsNegative() && !wallet.AllowsNegativeBalance(current.WalletType) {
+		return wallet.ErrNegativeBalance
+	}
+	change, err := w.Balance.Sub(current.Balance)
+	if err != nil {
+		return err
+	}
+
+	updated, err := setBalance(tx, w.ID, sq.Expr("?", money(w.Balance)))
+	if err != nil {
+		return err
+	}
+
+	err = insertTransaction(tx, updated, change, wallet.ReasonBalanceUpdate, wallet.NewCorrelationID())
+	if err != nil {
+		return err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return err
+	}
+
+	*w = updated
+	return nil
+}
+
+func (s *SQLite) PatchWallet(id, version int, patch wallet.WalletPatch) (_ wallet.Wallet, err error) {
+	defer translateError(&err)
+
+	tx, err := s.Db.Begin()
+	if err != nil {
+		return wallet.Wallet{}, err
+	}
+	defer tx.Rollback()
+
+	w, err := getWallet(tx, id)
+	if err != nil {
+		return wallet.Wallet{}, err
+	}
+	if w.Version != version {
+		return wallet.Wallet{}, wallet.ErrVersionMismatch
+	}
+	if err = patch.Apply(&w); err != nil {
+		return wallet.Wallet{}, err
+	}
+
+	updateSql, args, err := sqlquery.SQLite.Builder().Update("user_wallet").
+		SetMap(map[string]interface{}{"wallet_name": w.WalletName, "wallet_type": w.WalletType, "version": sq.Expr("version + 1")}).
+		Where(sq.Eq{"id": id}).
+		Suffix("RETURNING " + sqlquery.WalletColumns).
+		ToSql()
+	if err != nil {
+		return wallet.Wallet{}, err
+	}
+
+	patched, err := scanWallet(tx.QueryRow(updateSql, args...))
+	if err != nil {
+		return wallet.Wallet{}, err
+	}
+
+	return patched, tx.Commit()
+}
+
+func (s *SQLite) ChangeWalletStatus(id, version int, status, reason string) (_ wallet.Wallet, err error) {
+	defer translateError(&err)
+
+	tx, err := s.Db.Begin()
+	if err != nil {
+		return wallet.Wallet{}, err
+	}
+	defer tx.Rollback()
+
+	current, err := getWallet(tx, id)
+	if err != nil {
+		return wallet.Wallet{}, err
+	}
+	if current.Version != version {
+		return wallet.Wallet{}, wallet.ErrVersionMismatch
+	}
+	if err = wallet.CheckStatusChange(current, status); err != nil {
+		return wallet.Wallet{}, err
+	}
+
+	updateSql, args, err := sqlquery.SQLite.Builder().Update("user_wallet").
+		SetMap(map[string]interface{}{"status": status, "status_reason": reason, "version": sq.Expr("version + 1")}).
+		Where(sq.Eq{"id": id, "version": version}).
+		Suffix("RETURNING " + sqlquery.WalletColumns).
+		ToSql()
+	if err != nil {
+		return wallet.Wallet{}, err
+	}
+
+	changed, err := scanWallet(tx.QueryRow(updateSql, args...))
+	if errors.Is(err, wallet.ErrWalletNotFound) {
+		return wallet.Wallet{}, wallet.ErrVersionMismatch
+	}
+	if err != nil {
+		return wallet.Wallet{}, err
+	}
+
+	return changed, tx.Commit()
+}
+
+func (s *SQLite) DeleteWallet(id, version int) (err error) {
+	defer translateError(&err)
+
+	tx, err := s.Db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	current, err := getWallet(tx, id)
+	if err != nil {
+		return err
+	}
+	if current.Version != version {
+		return wallet.ErrVersionMismatch
+	}
+	if err = wallet.CheckDeletable(current); err != nil {
+		return err
+	}
+
+	if err = softDelete(tx, sq.Eq{"id": id}); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (s *SQLite) DeleteUserWallets(userID int) (err error) {
+	defer translateError(&err)
+
+	tx, err := s.Db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	live, err := liveStatuses(tx, userID)
+	if err != nil {
+		return err
+	}
+	if err = wallet.CheckDeletable(live...); err != nil {
+		return err
+	}
+
+	if err = softDelete(tx, sq.Eq{"user_id": userID}); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// liveStatuses reads the status of every live wallet of the user, enough for wallet.CheckDeletable.
+func liveStatuses(tx *sql.Tx, userID int) ([]wallet.Wallet, error) {
+	selectSql, args, err := sqlquery.SQLite.Builder().Select("status").
+		From("user_wallet").
+		Where(sq.Eq{"user_id": userID, "deleted_at": nil}).
+		ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := tx.Query(selectSql, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	wallets := make([]wallet.Wallet, 0)
+	for rows.Next() {
+		var w wallet.Wallet
+		if err := rows.Scan(&w.Status); err != nil {
+			return nil, err
+		}
+		wallets = append(wallets, w)
+	}
+	return wallets, rows.Err()
+}
+
+// softDelete marks the live wallets matching where as deleted.
+func softDelete(tx *sql.Tx, where sq.Eq) error {
+	deleteSql, args, err := sqlquery.SQLite.Builder().Update("user_wallet").
+		Set("deleted_at", now()).
+		Set("version", sq.Expr("version + 1")).
+		Where(where).
+		Where(sq.Eq{"deleted_at": nil}).
+		ToSql()
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(deleteSql, args...)
+	return err
+}
+
+func (s *SQLite) RestoreWallet(id int) (_ wallet.Wallet, err error) {
+	defer translateError(&err)
+
+	restoreSql, args, err := sqlquery.SQLite.Builder().Update("user_wallet").
+		Set("deleted_at", nil).
+		Set("version", sq.Expr("version + 1")).
+		Where(sq.Eq{"id": id}).
+		Where(sq.NotEq{"deleted_at": nil}).
+		Suffix("RETURNING " + sqlquery.WalletColumns).
+		ToSql()
+	if err != nil {
+		return wallet.Wallet{}, err
+	}
+
+	restored, err := scanWallet(s.Db.QueryRow(restoreSql, args...))
+	if errors.Is(err, wallet.ErrWalletNotFound) {
+		// not deleted, or no such wallet
+		return getWallet(s.Db, id)
+	}
+	return restored, err
+}
+
+// setBalance sets the balance of the wallet to the given SQL expression.
+func setBalance(tx *sql.Tx, id int, balance sq.Sqlizer) (wallet.Wallet, error) {
+	updateSql, args, err := sqlquery.SQLite.Builder().Update("user_wallet").
+		Set("balance", balance).
+		Set("version", sq.Expr("version + 1")).
+		Where(sq.Eq{"id": id}).
+		Suffix("RETURNING " + sqlquery.WalletColumns).
+		ToSql()
+	if err != nil {
+		return wallet.Wallet{}, err
+	}
+
+	return scanWallet(tx.QueryRow(updateSql, args...))
+}
+
+// addBalance adds the signed amount to the balance of w, read in the same transaction,
+// and returns ErrInvalidMoney when the new balance would not fit.
+func addBalance(tx *sql.Tx, w wallet.Wallet, amount wallet.Money) (wallet.Wallet, error) {
+	if _, err := w.Balance.Add(amount); err != nil {
+		return wallet.Wallet{}, err
+	}
+	return setBalance(tx, w.ID, sq.Expr("balance + ?", money(amount)))
+}
@@ -0,0 +1,169 @@
+// Package sqlquery builds the SQL shared by the database-backed wallet stores,
+// each store picks the Dialect matching its driver.
+package sqlquery
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/golfz/fun-exercise-api/wallet"
+)
+
+// WalletColumns reads user_name from users with a subquery rather than a join,
+// so the same columns also work in the RETURNING clause of an INSERT or UPDATE.
+const WalletColumns = "id, user_id, (SELECT name FROM users WHERE users.id = user_wallet.user_id) AS user_name, wallet_name, wallet_type, currency, balance, status, status_reason, version, created_at, deleted_at"
+
+type Dialect struct {
+	Placeholder sq.PlaceholderFormat
+	// MoneyValue converts an amount into the value kept in money columns.
+	MoneyValue func(m wallet.Money) interface{}
+	// MoneyScanner reads a money column back into m.
+	MoneyScanner func(m *wallet.Money) sql.Scanner
+}
+
+var (
+	// Postgres keeps money in DECIMAL columns, written as decimal text.
+	Postgres = Dialect{
+		Placeholder:  sq.Dollar,
+		MoneyValue:   func(m wallet.Money) interface{} { return m },
+		MoneyScanner: func(m *wallet.Money) sql.Scanner { return m },
+	}
+	// SQLite keeps money in INTEGER columns counting 1/100000000 units,
+	// SQLite has no exact decimal type.
+	SQLite = Dialect{
+		Placeholder:  sq.Question,
+		MoneyValue:   func(m wallet.Money) interface{} { return int64(m) },
+		MoneyScanner: func(m *wallet.Money) sql.Scanner { return moneyUnits{m} },
+	}
+)
+
+// moneyUnits scans an INTEGER count of 1/100000000 units into a wallet.Money.
+type moneyUnits struct {
+	money *wallet.Money
+}
+
+func (u moneyUnits) Scan(src interface{}) error {
+	units, ok := src.(int64)
+	if !ok {
+		return fmt.Errorf("%w: cannot scan %T as units", wallet.ErrInvalidMoney, src)
+	}
+	*u.money = wallet.Money(units)
+	return nil
+}
+
+func (d Dialect) Builder() sq.StatementBuilderType {
+	return sq.StatementBuilder.PlaceholderFormat(d.Placeholder)
+}
+
+// SelectWallets returns the query listing wallets that match the filter.
+// It fetches one row more than page.Limit, so the store can tell whether
+// there is a next page.
+func (d Dialect) SelectWallets(filter wallet.Filter, page wallet.Page) (string, []interface{}, error) {
+	if err := page.Validate(); err != nil {
+		return "", nil, err
+	}
+
+	selectQuery := d.Builder().Select(WalletColumns).
+		From("user_wallet")
+
+	// prepare filter
+	if !filter.IncludeDeleted {
+		selectQuery = selectQuery.Where(sq.Eq{"deleted_at": nil})
+	}
+	if len(filter.WalletTypes) == 1 {
+		selectQuery = selectQuery.Where(sq.Eq{"wallet_type": filter.WalletTypes[0]})
+	} else if len(filter.WalletTypes) > 1 {
+		selectQuery = selectQuery.Where(sq.Eq{"wallet_type": filter.WalletTypes})
+	}
+	if filter.UserID != 0 {
+		selectQuery = selectQuery.Where(sq.Eq{"user_id": filter.UserID})
+	}
+	if filter.Currency != "" {
+		selectQuery = selectQuery.Where(sq.Eq{"currency": filter.Currency})
+	}
+	if filter.MinBalance != nil {
+		selectQuery = selectQuery.Where(sq.GtOrEq{"balance": d.MoneyValue(*filter.MinBalance)})
+	}
+	if filter.MaxBalance != nil {
+		selectQuery = selectQuery.Where(sq.LtOrEq{"balance": d.MoneyValue(*filter.MaxBalance)})
+	}
+	if filter.CreatedAfter != nil {
+		selectQuery = selectQuery.Where(sq.Gt{"created_at": *filter.CreatedAfter})
+	}
+	if filter.CreatedBefore != nil {
+		selectQuery = selectQuery.Where(sq.Lt{"created_at": *filter.CreatedBefore})
+	}
+	// LOWER(...) LIKE works the same in Postgres and SQLite, unlike ILIKE
+	if filter.WalletName != "" {
+		selectQuery = selectQuery.Where(`LOWER(wallet_name) LIKE ? ESCAPE '\'`, escapeLike(strings.ToLower(filter.WalletName))+"%")
+	}
+	if filter.UserName != "" {
+		selectQuery = selectQuery.Where(`user_id IN (SELECT id FROM users WHERE LOWER(name) LIKE ? ESCAPE '\')`, "%"+escapeLike(strings.ToLower(filter.UserName))+"%")
+	}
+
+	// prepare keyset pagination: id breaks ties between equal sort values
+	direction, comparison := "ASC", ">"
+	if page.Desc {
+		direction, comparison = "DESC", "<"
+	}
+	if page.Cursor != nil {
+		value, err := page.Cursor.SortValue()
+		if err != nil {
+			return "", nil, err
+		}
+		if money, ok := value.(wallet.Money); ok {
+			value = d.MoneyValue(money)
+		}
+
+		if page.Sort == wallet.SortByID {
+			selectQuery = selectQuery.Where("id "+comparison+" ?", page.Cursor.ID)
+		} else {
+			selectQuery = selectQuery.Where("("+page.Sort+", id) "+comparison+" (?, ?)", value, page.Cursor.ID)
+		}
+	}
+
+	if page.Sort != wallet.SortByID {
+		selectQuery = selectQuery.OrderBy(page.Sort + " " + direction)
+	}
+	selectQuery = selectQuery.OrderBy("id " + direction).
+		Limit(uint64(page.Limit) + 1)
+
+	return selectQuery.ToSql()
+}
+
+var likeEscaper = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+
+// escapeLike makes LIKE wildcards in s match literally.
+func escapeLike(s string) string {
+	return likeEscaper.Replace(s)
+}
+
+const UserColumns = "id, name, created_at"
+
+const APIKeyColumns = "id, prefix, hash, name, owner_id, scopes, created_at, last_used_at, revoked_at"
+
+const IdempotencyKeyColumns = "key, request_hash, status_code, content_type, etag, response_body, created_at, expires_at"
+
+// ReserveIdempotencyKey returns the statement inserting the record as in progress.
+// An expired record under the same key is replaced, a live one is left alone,
+// so the statement affects no row when the key is in use.
+func (d Dialect) ReserveIdempotencyKey(record wallet.IdempotencyRecord) (string, []interface{}, error) {
+	return d.Builder().Insert("idempotency_key").
+		Columns("key", "request_hash", "created_at", "expires_at").
+		Values(record.Key, record.RequestHash, record.CreatedAt, record.ExpiresAt).
+		Suffix(`ON CONFLICT (key) DO UPDATE SET
+			request_hash = excluded.request_hash, status_code = 0, content_type = '', etag = '', response_body = '',
+			created_at = excluded.created_at, expires_at = excluded.expires_at
+		WHERE idempotency_key.expires_at <= excluded.created_at`).
+		ToSql()
+}
+
+// PurgeIdempotencyKeys returns the statement deleting the records expired at now.
+func (d Dialect) PurgeIdempotencyKeys(now time.Time) (string, []interface{}, error) {
+	return d.Builder().Delete("idempotency_key").
+		Where(sq.LtOrEq{"expires_at": now}).
+		ToSql()
+}
@@ -0,0 +1,118 @@
+package sqlquery
+
+import (
+	"testing"
+	"time"
+
+	"github.com/golfz/fun-exercise-api/wallet"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSelectWallets(t *testing.T) {
+	minBalance := wallet.MustParseMoney("10")
+	createdAfter := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		dialect  Dialect
+		filter   wallet.Filter
+		page     wallet.Page
+		wantSql  string
+		wantArgs []interface{}
+	}{
+		{
+			name:     "Postgres without filter",
+			dialect:  Postgres,
+			filter:   wallet.Filter{},
+			page:     wallet.DefaultPage(),
+			wantSql:  "SELECT " + WalletColumns + " FROM user_wallet WHERE deleted_at IS NULL ORDER BY id ASC LIMIT 51",
+			wantArgs: nil,
+		},
+		{
+			name:     "Postgres including deleted wallets",
+			dialect:  Postgres,
+			filter:   wallet.Filter{IncludeDeleted: true},
+			page:     wallet.DefaultPage(),
+			wantSql:  "SELECT " + WalletColumns + " FROM user_wallet ORDER BY id ASC LIMIT 51",
+			wantArgs: nil,
+		},
+		{
+			name:     "Postgres with every filter",
+			dialect:  Postgres,
+			filter:   wallet.Filter{WalletTypes: []string{wallet.WalletTypeSavings}, UserID: 1, Currency: wallet.CurrencyTHB},
+			page:     wallet.DefaultPage(),
+			wantSql:  "SELECT " + WalletColumns + " FROM user_wallet WHERE deleted_at IS NULL AND wallet_type = $1 AND user_id = $2 AND currency = $3 ORDER BY id ASC LIMIT 51",
+			wantArgs: []interface{}{wallet.WalletTypeSavings, 1, wallet.CurrencyTHB},
+		},
+		{
+			name:     "SQLite with user filter",
+			dialect:  SQLite,
+			filter:   wallet.Filter{UserID: 1, Currency: wallet.CurrencyTHB},
+			page:     wallet.DefaultPage(),
+			wantSql:  "SELECT " + WalletColumns + " FROM user_wallet WHERE deleted_at IS NULL AND user_id = ? AND currency = ? ORDER BY id ASC LIMIT 51",
+			wantArgs: []interface{}{1, wallet.CurrencyTHB},
+		},
+		{
+			name:    "Postgres with ranges, names and several wallet types",
+			dialect: Postgres,
+			filter: wallet.Filter{
+				WalletTypes:  []string{wallet.WalletTypeSavings, wallet.WalletTypeCreditCard},
+				MinBalance:   &minBalance,
+				CreatedAfter: &createdAfter,
+				WalletName:   "John_",
+				UserName:     "100%",
+			},
+			page: wallet.DefaultPage(),
+			wantSql: "SELECT " + WalletColumns + " FROM user_wallet WHERE deleted_at IS NULL AND wallet_type IN ($1,$2) AND balance >= $3 AND created_at > $4" +
+				` AND LOWER(wallet_name) LIKE $5 ESCAPE '\' AND user_id IN (SELECT id FROM users WHERE LOWER(name) LIKE $6 ESCAPE '\') ORDER BY id ASC LIMIT 51`,
+			// squirrel expands driver.Valuer arguments of comparison clauses
+			wantArgs: []interface{}{wallet.WalletTypeSavings, wallet.WalletTypeCreditCard, "10.00", createdAfter, `john\_%`, `%100\%%`},
+		},
+		{
+			name:     "SQLite with a balance range keeps money in units",
+			dialect:  SQLite,
+			filter:   wallet.Filter{MinBalance: &minBalance, MaxBalance: &minBalance},
+			page:     wallet.DefaultPage(),
+			wantSql:  "SELECT " + WalletColumns + " FROM user_wallet WHERE deleted_at IS NULL AND balance >= ? AND balance <= ? ORDER BY id ASC LIMIT 51",
+			wantArgs: []interface{}{int64(1000000000), int64(1000000000)},
+		},
+		{
+			name:     "Postgres after an id cursor",
+			dialect:  Postgres,
+			filter:   wallet.Filter{UserID: 1},
+			page:     wallet.Page{Limit: 10, Sort: wallet.SortByID, Cursor: &wallet.Cursor{Sort: wallet.SortByID, ID: 20}},
+			wantSql:  "SELECT " + WalletColumns + " FROM user_wallet WHERE deleted_at IS NULL AND user_id = $1 AND id > $2 ORDER BY id ASC LIMIT 11",
+			wantArgs: []interface{}{1, 20},
+		},
+		{
+			name:     "Postgres descending by balance after a cursor",
+			dialect:  Postgres,
+			page:     wallet.Page{Limit: 10, Sort: wallet.SortByBalance, Desc: true, Cursor: &wallet.Cursor{Sort: wallet.SortByBalance, Desc: true, Value: "10.5", ID: 20}},
+			wantSql:  "SELECT " + WalletColumns + " FROM user_wallet WHERE deleted_at IS NULL AND (balance, id) < ($1, $2) ORDER BY balance DESC, id DESC LIMIT 11",
+			wantArgs: []interface{}{wallet.MustParseMoney("10.5"), 20},
+		},
+		{
+			name:     "SQLite by balance after a cursor keeps money in units",
+			dialect:  SQLite,
+			page:     wallet.Page{Limit: 10, Sort: wallet.SortByBalance, Cursor: &wallet.Cursor{Sort: wallet.SortByBalance, Value: "10.5", ID: 20}},
+			wantSql:  "SELECT " + WalletColumns + " FROM user_wallet WHERE deleted_at IS NULL AND (balance, id) > (?, ?) ORDER BY balance ASC, id ASC LIMIT 11",
+			wantArgs: []interface{}{int64(1050000000), 20},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			gotSql, gotArgs, err := test.dialect.SelectWallets(test.filter, test.page)
+
+			assert.NoError(t, err)
+			assert.Equal(t, test.wantSql, gotSql)
+			assert.Equal(t, test.wantArgs, gotArgs)
+		})
+	}
+}
+
+func TestSelectWalletsRejectsInvalidPage(t *testing.T) {
+	_, _, err := Postgres.SelectWallets(wallet.Filter{}, wallet.Page{Limit: 10, Sort: "id; DROP TABLE user_wallet"})
+
+	assert.ErrorIs(t, err, wallet.ErrInvalidPage)
+}
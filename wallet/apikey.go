@@ -0,0 +1,291 @@
+package wallet
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	// HeaderAPIKey carries the API key of a service caller, instead of a bearer token.
+	HeaderAPIKey = "X-API-Key"
+
+	// apiKeyTag starts every key, so a leaked key is easy to recognise.
+	apiKeyTag          = "wk"
+	apiKeyPrefixBytes  = 8
+	apiKeySecretBytes  = 32
+	apiKeySubjectStart = "api_key:"
+
+	// APIKeyTouchInterval is how stale last_used_at may get, so a busy key is not
+	// written to the database on every request.
+	APIKeyTouchInterval = time.Minute
+)
+
+var (
+	ErrAPIKeyNotFound = errors.New("api key not found")
+	// ErrAPIKeyRevoked means the key was revoked, it can no longer be used or rotated.
+	ErrAPIKeyRevoked = errors.New("api key is revoked")
+)
+
+// APIKey lets a batch job call the API without a bearer token, acting as its owner
+// with the permissions of its scopes. Only the SHA-256 hash of the key is stored,
+// the prefix finds it.
+type APIKey struct {
+	ID         int          `json:"id" example:"1"`
+	Prefix     string       `json:"prefix" example:"3f9a0c2e7b41d658"`
+	Name       string       `json:"name" example:"nightly settlement"`
+	OwnerID    int          `json:"owner_id" example:"1"`
+	Scopes     []Permission `json:"scopes" swaggertype:"array,string" example:"own"`
+	CreatedAt  time.Time    `json:"created_at" example:"2024-03-25T14:19:00.729237Z"`
+	LastUsedAt *time.Time   `json:"last_used_at,omitempty" example:"2024-03-26T02:00:00Z"`
+	RevokedAt  *time.Time   `json:"revoked_at,omitempty" example:"2024-04-01T09:30:00Z"`
+	Hash       string       `json:"-"`
+}
+
+// IssuedAPIKey is an APIKey together with the key itself, answered once when the key
+// is created or rotated, the key cannot be read back later.
+type IssuedAPIKey struct {
+	APIKey
+	Key string `json:"key" example:"wk_3f9a0c2e7b41d658_9c1d..."`
+}
+
+type APIKeyForCreate struct {
+	OwnerID int      `json:"owner_id" example:"1"`
+	Name    string   `json:"name" example:"nightly settlement"`
+	Scopes  []string `json:"scopes" example:"own"`
+}
+
+type APIKeyStorer interface {
+	GetAPIKeys() ([]APIKey, error)
+	// GetAPIKeyByPrefix returns the key with prefix, revoked or not.
+	GetAPIKeyByPrefix(prefix string) (APIKey, error)
+	// CreateAPIKey returns ErrUserNotFound when the owner does not exist.
+	CreateAPIKey(key *APIKey) error
+	// RotateAPIKey replaces the prefix and hash of the key, and returns
+	// ErrAPIKeyRevoked when the key is revoked.
+	RotateAPIKey(id int, prefix, hash string) (APIKey, error)
+	// RevokeAPIKey revokes the key, a revoked key is returned as it is.
+	RevokeAPIKey(id int) (APIKey, error)
+	// TouchAPIKey records that the key was last used at.
+	TouchAPIKey(id int, at time.Time) error
+}
+
+// NewAPIKey generates a key "wk_<prefix>_<secret>", returning the key, its prefix and its hash.
+func NewAPIKey() (key, prefix, hash string) {
+	b := make([]byte, apiKeyPrefixBytes+apiKeySecretBytes)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	prefix = hex.EncodeToString(b[:apiKeyPrefixBytes])
+	key = apiKeyTag + "_" + prefix + "_" + hex.EncodeToString(b[apiKeyPrefixBytes:])
+	return key, prefix, HashAPIKey(key)
+}
+
+// HashAPIKey is the hash stored for key, the hex encoded SHA-256 of it.
+func HashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// apiKeyPrefix reads the prefix out of key, false when key is not shaped like one.
+func apiKeyPrefix(key string) (string, bool) {
+	parts := strings.Split(key, "_")
+	if len(parts) != 3 || parts[0] != apiKeyTag || len(parts[1]) != 2*apiKeyPrefixBytes || parts[2] == "" {
+		return "", false
+	}
+	return parts[1], true
+}
+
+// Principal is the caller the key acts as, its owner limited to the scopes of the key.
+func (k APIKey) Principal() Principal {
+	return Principal{Subject: apiKeySubjectStart + strconv.Itoa(k.ID), UserID: k.OwnerID, Scopes: k.Scopes}
+}
+
+// APIKeyAuth authenticates a request carrying an X-API-Key header with a key in store that
+// is not revoked, and records when the key was used, at most once per APIKeyTouchInterval.
+// Requests without the header are passed through for JWTAuth, a key that does not verify
+// is answered with 401.
+func APIKeyAuth(store APIKeyStorer) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			key := c.Request().Header.Get(HeaderAPIKey)
+			if key == "" {
+				return next(c)
+			}
+
+			prefix, ok := apiKeyPrefix(key)
+			if !ok {
+				return fmt.Errorf("%w: malformed API key", ErrUnauthorized)
+			}
+			stored, err := store.GetAPIKeyByPrefix(prefix)
+			if errors.Is(err, ErrAPIKeyNotFound) {
+				return fmt.Errorf("%w: unknown API key", ErrUnauthorized)
+			}
+			if err != nil {
+				return err
+			}
+			if subtle.ConstantTimeCompare([]byte(HashAPIKey(key)), []byte(stored.Hash)) != 1 {
+				return fmt.Errorf("%w: unknown API key", ErrUnauthorized)
+			}
+			if stored.RevokedAt != nil {
+				return fmt.Errorf("%w: %v", ErrUnauthorized, ErrAPIKeyRevoked)
+			}
+
+			now := time.Now().UTC()
+			if stored.LastUsedAt == nil || now.Sub(*stored.LastUsedAt) >= APIKeyTouchInterval {
+				if err := store.TouchAPIKey(stored.ID, now); err != nil {
+					return err
+				}
+			}
+
+			SetPrincipal(c, stored.Principal())
+			return next(c)
+		}
+	}
+}
+
+// ValidateAPIKey checks a key sent to CreateAPIKeyHandler.
+func ValidateAPIKey(k APIKey) error {
+	v := validator{}
+	v.check(k.OwnerID > 0, "owner_id", "is required")
+	v.checkName("name", k.Name)
+	if v.check(len(k.Scopes) > 0, "scopes", "must not be empty") {
+		for i, scope := range k.Scopes {
+			v.check(IsPermissionValid(scope), fmt.Sprintf("scopes[%d]", i), "must be a known permission")
+		}
+	}
+	return v.err()
+}
+
+// bindAPIKey reads the key of a create request.
+func bindAPIKey(c echo.Context) (APIKey, error) {
+	key := APIKeyForCreate{}
+	if err := c.Bind(&key); err != nil {
+		return APIKey{}, err
+	}
+	k := APIKey{OwnerID: key.OwnerID, Name: key.Name, Scopes: make([]Permission, 0, len(key.Scopes))}
+	for _, scope := range key.Scopes {
+		k.Scopes = append(k.Scopes, Permission(scope))
+	}
+	return k, ValidateAPIKey(k)
+}
+
+// GetAPIKeysHandler
+//
+//	@Summary		Get all API keys
+//	@Description	Get all API keys in id order, revoked ones included, without the keys themselves
+//	@Tags			api key
+//	@Produce		json
+//	@Success		200	{array}		APIKey
+//	@Failure		403	{object}	Problem
+//	@Failure		500	{object}	Problem
+//	@Router			/api/v1/admin/api-keys [get]
+func (h *Handler) GetAPIKeysHandler(c echo.Context) error {
+	keys, err := h.store.GetAPIKeys()
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, keys)
+}
+
+// CreateAPIKeyHandler
+//
+//	@Summary		Issue API key
+//	@Description	Issue an API key acting as its owner with the permissions of its scopes, the key is only answered now
+//	@Tags			api key
+//	@Accept			json
+//	@Produce		json
+//	@Param			api_key	body	APIKeyForCreate	true	"API key object"
+//	@Success		201	{object}	IssuedAPIKey
+//	@Failure		400	{object}	Problem
+//	@Failure		403	{object}	Problem
+//	@Failure		422	{object}	Problem
+//	@Failure		500	{object}	Problem
+//	@Router			/api/v1/admin/api-keys [post]
+func (h *Handler) CreateAPIKeyHandler(c echo.Context) error {
+	k, err := bindAPIKey(c)
+	if err != nil {
+		return err
+	}
+
+	// create key
+	key, prefix, hash := NewAPIKey()
+	k.Prefix, k.Hash = prefix, hash
+	err = h.store.CreateAPIKey(&k)
+	if errors.Is(err, ErrUserNotFound) {
+		// the owner is part of the body, not of the path
+		return WithStatus(http.StatusUnprocessableEntity, err)
+	}
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusCreated, IssuedAPIKey{APIKey: k, Key: key})
+}
+
+// RotateAPIKeyHandler
+//
+//	@Summary		Rotate API key
+//	@Description	Replace the key keeping its name, owner and scopes, the old key stops working at once
+//	@Tags			api key
+//	@Produce		json
+//	@Param			id	path		int	true	"API key ID"
+//	@Success		200	{object}	IssuedAPIKey
+//	@Failure		400	{object}	Problem
+//	@Failure		403	{object}	Problem
+//	@Failure		404	{object}	Problem
+//	@Failure		409	{object}	Problem
+//	@Failure		500	{object}	Problem
+//	@Router			/api/v1/admin/api-keys/{id}/rotate [post]
+func (h *Handler) RotateAPIKeyHandler(c echo.Context) error {
+	id, err := ParseAPIKeyID(c)
+	if err != nil {
+		return err
+	}
+
+	// rotate key
+	key, prefix, hash := NewAPIKey()
+	rotated, err := h.store.RotateAPIKey(id, prefix, hash)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, IssuedAPIKey{APIKey: rotated, Key: key})
+}
+
+// RevokeAPIKeyHandler
+//
+//	@Summary		Revoke API key
+//	@Description	Revoke the key for good, revoking it again changes nothing
+//	@Tags			api key
+//	@Produce		json
+//	@Param			id	path		int	true	"API key ID"
+//	@Success		200	{object}	APIKey
+//	@Failure		400	{object}	Problem
+//	@Failure		403	{object}	Problem
+//	@Failure		404	{object}	Problem
+//	@Failure		500	{object}	Problem
+//	@Router			/api/v1/admin/api-keys/{id}/revoke [post]
+func (h *Handler) RevokeAPIKeyHandler(c echo.Context) error {
+	id, err := ParseAPIKeyID(c)
+	if err != nil {
+		return err
+	}
+
+	revoked, err := h.store.RevokeAPIKey(id)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, revoked)
+}
@@ -0,0 +1,275 @@
+package wallet
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golfz/fun-exercise-api/wallet/authtest"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// apiKeyAuthSetup serves GetWalletsHandler behind APIKeyAuth and JWTAuth, the way main wires them,
+// with stored as the key the mock finds.
+func apiKeyAuthSetup(stored APIKey, key string) (*httptest.ResponseRecorder, echo.Context, echo.HandlerFunc, *mockWalletStorer) {
+	resp, c, h, mock := anonymousSetup(http.MethodGet, "/api/v1/wallets", nil)
+	if key != "" {
+		c.Request().Header.Set(HeaderAPIKey, key)
+	}
+	mock.apiKey = stored
+	handler := APIKeyAuth(mock)(JWTAuth(authtest.Keys())(RequirePermission(PermissionOwn)(h.GetWalletsHandler)))
+	return resp, c, handler, mock
+}
+
+func TestAPIKeyAuth(t *testing.T) {
+	key, prefix, hash := NewAPIKey()
+	stored := APIKey{ID: 3, Prefix: prefix, Hash: hash, OwnerID: 1, Scopes: []Permission{PermissionOwn}}
+
+	t.Run("given a valid key should act as its owner with its scopes", func(t *testing.T) {
+		// Arrange
+		resp, c, handler, mock := apiKeyAuthSetup(stored, key)
+		mock.ExpectToCall("TouchAPIKey")
+
+		// Act
+		err := serve(c, handler)
+
+		// Assert
+		mock.Verify(t)
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusOK, resp.Code)
+		assert.Equal(t, prefix, mock.whatIsPrefix)
+		assert.Equal(t, 3, mock.whatIsID)
+		p, _ := CurrentPrincipal(c)
+		assert.Equal(t, Principal{Subject: "api_key:3", UserID: 1, Scopes: []Permission{PermissionOwn}}, p)
+		assert.Equal(t, Filter{UserID: 1}, mock.whatIsFilter)
+	})
+
+	t.Run("given a key used a moment ago should not record the use again", func(t *testing.T) {
+		// Arrange
+		lastUsedAt := time.Now().UTC().Add(-APIKeyTouchInterval / 2)
+		recent := stored
+		recent.LastUsedAt = &lastUsedAt
+		resp, c, handler, mock := apiKeyAuthSetup(recent, key)
+
+		// Act
+		err := serve(c, handler)
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusOK, resp.Code)
+		assert.False(t, mock.methodToCall["TouchAPIKey"])
+	})
+
+	t.Run("given no key should leave the request to JWTAuth", func(t *testing.T) {
+		// Arrange
+		resp, c, handler, mock := apiKeyAuthSetup(stored, "")
+		c.Request().Header.Set(echo.HeaderAuthorization, authtest.Bearer(t, "1"))
+
+		// Act
+		err := serve(c, handler)
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusOK, resp.Code)
+		assert.False(t, mock.methodToCall["GetAPIKeyByPrefix"])
+		assert.Equal(t, "1", Subject(c))
+	})
+
+	t.Run("given a key without the scope should return 403", func(t *testing.T) {
+		// Arrange
+		quoteOnly := stored
+		quoteOnly.Scopes = []Permission{PermissionFXQuote}
+		resp, c, handler, _ := apiKeyAuthSetup(quoteOnly, key)
+
+		// Act
+		err := serve(c, handler)
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusForbidden, resp.Code)
+	})
+
+	revokedAt := time.Now()
+	revoked := stored
+	revoked.RevokedAt = &revokedAt
+	_, _, otherHash := NewAPIKey()
+	otherKey := stored
+	otherKey.Hash = otherHash
+
+	tests := []struct {
+		name   string
+		stored APIKey
+		key    string
+		err    error
+	}{
+		{name: "malformed key", stored: stored, key: "not-a-key"},
+		{name: "unknown key", stored: stored, key: key, err: ErrAPIKeyNotFound},
+		{name: "key not matching the stored hash", stored: otherKey, key: key},
+		{name: "revoked key", stored: revoked, key: key},
+	}
+	for _, tt := range tests {
+		t.Run("given "+tt.name+" should return 401", func(t *testing.T) {
+			// Arrange
+			resp, c, handler, mock := apiKeyAuthSetup(tt.stored, tt.key)
+			// a bearer token does not stand in for a key that fails
+			c.Request().Header.Set(echo.HeaderAuthorization, authtest.Bearer(t, "1"))
+			mock.err = tt.err
+
+			// Act
+			err := serve(c, handler)
+
+			// Assert
+			assert.NoError(t, err)
+			assert.Equal(t, http.StatusUnauthorized, resp.Code)
+			assert.Contains(t, resp.Body.String(), CodeUnauthorized)
+			assert.False(t, mock.methodToCall["TouchAPIKey"])
+			assert.False(t, mock.methodToCall["GetWallets"])
+		})
+	}
+}
+
+func TestNewAPIKey(t *testing.T) {
+	key, prefix, hash := NewAPIKey()
+	other, _, _ := NewAPIKey()
+
+	got, ok := apiKeyPrefix(key)
+	assert.True(t, ok)
+	assert.Equal(t, prefix, got)
+	assert.Equal(t, HashAPIKey(key), hash)
+	assert.NotContains(t, hash, key)
+	assert.NotEqual(t, key, other)
+}
+
+func TestCreateAPIKey(t *testing.T) {
+	t.Run("given owner, name and scopes should answer the key once", func(t *testing.T) {
+		// Arrange
+		resp, c, h, mock := testSetup(http.MethodPost, "/api/v1/admin/api-keys", strings.NewReader(`{"owner_id": 1, "name": "nightly", "scopes": ["own", "fx:quote"]}`))
+		c.Request().Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		mock.ExpectToCall("CreateAPIKey")
+
+		// Act
+		err := serve(c, h.CreateAPIKeyHandler)
+
+		// Assert
+		mock.Verify(t)
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusCreated, resp.Code)
+		assert.Equal(t, 1, mock.whatIsAPIKey.OwnerID)
+		assert.Equal(t, []Permission{PermissionOwn, PermissionFXQuote}, mock.whatIsAPIKey.Scopes)
+		var got IssuedAPIKey
+		require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &got))
+		assert.Equal(t, mock.whatIsAPIKey.Hash, HashAPIKey(got.Key))
+		assert.Equal(t, mock.whatIsAPIKey.Prefix, got.Prefix)
+		assert.NotContains(t, resp.Body.String(), mock.whatIsAPIKey.Hash)
+	})
+
+	t.Run("given unknown scope should return 400 and error code", func(t *testing.T) {
+		// Arrange
+		resp, c, h, mock := testSetup(http.MethodPost, "/api/v1/admin/api-keys", strings.NewReader(`{"owner_id": 1, "name": "nightly", "scopes": ["own", "root"]}`))
+		c.Request().Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+
+		// Act
+		err := serve(c, h.CreateAPIKeyHandler)
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusBadRequest, resp.Code)
+		assert.Contains(t, resp.Body.String(), CodeValidationFailed)
+		assert.Contains(t, resp.Body.String(), "scopes[1]")
+		assert.False(t, mock.methodToCall["CreateAPIKey"])
+	})
+
+	t.Run("given unknown owner should return 422 and error code", func(t *testing.T) {
+		// Arrange
+		resp, c, h, mock := testSetup(http.MethodPost, "/api/v1/admin/api-keys", strings.NewReader(`{"owner_id": 99, "name": "nightly", "scopes": ["own"]}`))
+		c.Request().Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		mock.err = ErrUserNotFound
+
+		// Act
+		err := serve(c, h.CreateAPIKeyHandler)
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusUnprocessableEntity, resp.Code)
+		assert.Contains(t, resp.Body.String(), CodeUserNotFound)
+	})
+}
+
+func apiKeySetup(method string) (*httptest.ResponseRecorder, echo.Context, *Handler, *mockWalletStorer) {
+	resp, c, h, mock := testSetup(method, "/", nil)
+	c.SetParamNames("id")
+	c.SetParamValues("3")
+	return resp, c, h, mock
+}
+
+func TestRotateAPIKey(t *testing.T) {
+	t.Run("given live key should answer the new key", func(t *testing.T) {
+		// Arrange
+		resp, c, h, mock := apiKeySetup(http.MethodPost)
+		mock.apiKey = APIKey{ID: 3, Prefix: "3f9a0c2e7b41d658"}
+
+		// Act
+		err := serve(c, h.RotateAPIKeyHandler)
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusOK, resp.Code)
+		assert.Equal(t, 3, mock.whatIsID)
+		var got IssuedAPIKey
+		require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &got))
+		prefix, ok := apiKeyPrefix(got.Key)
+		assert.True(t, ok)
+		assert.Equal(t, mock.whatIsPrefix, prefix)
+		assert.Equal(t, mock.whatIsHash, HashAPIKey(got.Key))
+	})
+
+	t.Run("given revoked key should return 409 and error code", func(t *testing.T) {
+		// Arrange
+		resp, c, h, mock := apiKeySetup(http.MethodPost)
+		mock.err = ErrAPIKeyRevoked
+
+		// Act
+		err := serve(c, h.RotateAPIKeyHandler)
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusConflict, resp.Code)
+		assert.Contains(t, resp.Body.String(), CodeAPIKeyRevoked)
+	})
+}
+
+func TestRevokeAPIKey(t *testing.T) {
+	t.Run("given key should revoke it", func(t *testing.T) {
+		// Arrange
+		resp, c, h, mock := apiKeySetup(http.MethodPost)
+		mock.ExpectToCall("RevokeAPIKey")
+
+		// Act
+		err := serve(c, h.RevokeAPIKeyHandler)
+
+		// Assert
+		mock.Verify(t)
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusOK, resp.Code)
+		assert.Equal(t, 3, mock.whatIsID)
+	})
+
+	t.Run("given unknown key should return 404 and error code", func(t *testing.T) {
+		// Arrange
+		resp, c, h, mock := apiKeySetup(http.MethodPost)
+		mock.err = ErrAPIKeyNotFound
+
+		// Act
+		err := serve(c, h.RevokeAPIKeyHandler)
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusNotFound, resp.Code)
+		assert.Contains(t, resp.Body.String(), CodeAPIKeyNotFound)
+	})
+}
@@ -0,0 +1,284 @@
+package wallet
+
+import (
+	"bytes"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/labstack/echo/v4"
+	"io"
+	"math/big"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// MinHMACKeyLength is the shortest HS256 secret accepted, 256 bits like the hash.
+	MinHMACKeyLength = 32
+
+	// JWTLeeway is the clock skew allowed when checking exp and nbf.
+	JWTLeeway = 30 * time.Second
+
+	contextKeyPrincipal = "auth.principal"
+)
+
+var (
+	// ErrUnauthorized means the request has neither a bearer token nor an API key that verifies.
+	ErrUnauthorized = errors.New("missing or invalid credentials")
+	errNoJWTKeys    = errors.New("no key to verify bearer tokens with")
+)
+
+// JWTKeys are the keys bearer tokens are verified with, by the kid of the token.
+// An HS256 secret is a []byte and an RS256 key a *rsa.PublicKey, the key stored
+// under "" verifies tokens whose kid matches no other key.
+type JWTKeys map[string]interface{}
+
+// keyFor is the jwt.Keyfunc picking the key of token, only a key of the kind
+// the token is signed with is returned, so an RSA public key is never used as an HS256 secret.
+func (k JWTKeys) keyFor(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+	key, ok := k[kid]
+	if !ok {
+		if key, ok = k[""]; !ok {
+			return nil, fmt.Errorf("unknown key id %q", kid)
+		}
+	}
+
+	switch token.Method.Alg() {
+	case jwt.SigningMethodHS256.Alg():
+		if _, ok := key.([]byte); ok {
+			return key, nil
+		}
+	case jwt.SigningMethodRS256.Alg():
+		if _, ok := key.(*rsa.PublicKey); ok {
+			return key, nil
+		}
+	}
+	return nil, fmt.Errorf("key %q does not verify %s tokens", kid, token.Method.Alg())
+}
+
+// LoadJWTKey reads a single key: a PEM encoded RSA public key verifies RS256 tokens,
+// anything else is the HS256 secret with surrounding whitespace trimmed.
+func LoadJWTKey(r io.Reader) (JWTKeys, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	if block, _ := pem.Decode(data); block != nil {
+		key, err := parseRSAPublicKey(block)
+		if err != nil {
+			return nil, err
+		}
+		return JWTKeys{"": key}, nil
+	}
+
+	secret := bytes.TrimSpace(data)
+	if len(secret) < MinHMACKeyLength {
+		return nil, fmt.Errorf("HS256 secret must be at least %d bytes", MinHMACKeyLength)
+	}
+	return JWTKeys{"": secret}, nil
+}
+
+// LoadJWTKeyFile loads the key from a file, see LoadJWTKey.
+func LoadJWTKeyFile(name string) (JWTKeys, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return LoadJWTKey(f)
+}
+
+func parseRSAPublicKey(block *pem.Block) (*rsa.PublicKey, error) {
+	switch block.Type {
+	case "RSA PUBLIC KEY":
+		return x509.ParsePKCS1PublicKey(block.Bytes)
+	case "PUBLIC KEY":
+		key, err := x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		if rsaKey, ok := key.(*rsa.PublicKey); ok {
+			return rsaKey, nil
+		}
+		return nil, errors.New("public key is not an RSA key")
+	default:
+		return nil, fmt.Errorf("unexpected PEM block %q, expected an RSA public key", block.Type)
+	}
+}
+
+// jwk is the part of a JSON Web Key (RFC 7517) needed to verify HS256 and RS256 tokens.
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	K   string `json:"k"`
+}
+
+// LoadJWKS reads a JSON Web Key Set, RSA keys verify RS256 tokens and oct keys
+// HS256 tokens. Encryption keys are skipped, other key types are refused.
+func LoadJWKS(r io.Reader) (JWTKeys, error) {
+	var set struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := json.NewDecoder(r).Decode(&set); err != nil {
+		return nil, fmt.Errorf("jwks: %w", err)
+	}
+
+	keys := make(JWTKeys)
+	for i, k := range set.Keys {
+		if k.Use == "enc" {
+			continue
+		}
+		key, err := k.key()
+		if err != nil {
+			return nil, fmt.Errorf("jwks key %d: %w", i, err)
+		}
+		if _, ok := keys[k.Kid]; ok {
+			return nil, fmt.Errorf("jwks key %d: duplicate kid %q", i, k.Kid)
+		}
+		keys[k.Kid] = key
+	}
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("jwks: %w", errNoJWTKeys)
+	}
+	return keys, nil
+}
+
+// LoadJWKSFile loads a JSON Web Key Set from a file, see LoadJWKS.
+func LoadJWKSFile(name string) (JWTKeys, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return LoadJWKS(f)
+}
+
+func (k jwk) key() (interface{}, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := base64.RawURLEncoding.DecodeString(k.N)
+		if err != nil {
+			return nil, fmt.Errorf("invalid modulus: %w", err)
+		}
+		e, err := base64.RawURLEncoding.DecodeString(k.E)
+		if err != nil {
+			return nil, fmt.Errorf("invalid exponent: %w", err)
+		}
+		exponent := new(big.Int).SetBytes(e)
+		if len(n) == 0 || !exponent.IsInt64() || exponent.Int64() < 3 || exponent.Int64() > 1<<31-1 {
+			return nil, errors.New("invalid RSA key")
+		}
+		return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(exponent.Int64())}, nil
+	case "oct":
+		secret, err := base64.RawURLEncoding.DecodeString(k.K)
+		if err != nil {
+			return nil, fmt.Errorf("invalid secret: %w", err)
+		}
+		if len(secret) < MinHMACKeyLength {
+			return nil, fmt.Errorf("HS256 secret must be at least %d bytes", MinHMACKeyLength)
+		}
+		return secret, nil
+	default:
+		return nil, fmt.Errorf("unsupported key type %q", k.Kty)
+	}
+}
+
+// JWTClaims are the claims read from a bearer token, Roles defaults to RoleCustomer.
+type JWTClaims struct {
+	jwt.RegisteredClaims
+	Roles []string `json:"roles,omitempty"`
+}
+
+// JWTAuth lets a request through only with an "Authorization: Bearer" token signed
+// with HS256 or RS256 by one of keys, carrying an exp and a sub claim.
+// The caller is then available through CurrentPrincipal, anything else is answered with 401.
+// A request an earlier middleware like APIKeyAuth already authenticated is passed through.
+func JWTAuth(keys JWTKeys) echo.MiddlewareFunc {
+	parser := jwt.NewParser(
+		jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg(), jwt.SigningMethodRS256.Alg()}),
+		jwt.WithExpirationRequired(),
+		jwt.WithLeeway(JWTLeeway),
+	)
+
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			if _, ok := CurrentPrincipal(c); ok {
+				return next(c)
+			}
+
+			scheme, token, found := strings.Cut(c.Request().Header.Get(echo.HeaderAuthorization), " ")
+			if !found || !strings.EqualFold(scheme, "Bearer") || strings.TrimSpace(token) == "" {
+				c.Response().Header().Set(echo.HeaderWWWAuthenticate, "Bearer")
+				return ErrUnauthorized
+			}
+
+			claims := JWTClaims{}
+			if _, err := parser.ParseWithClaims(strings.TrimSpace(token), &claims, keys.keyFor); err != nil {
+				c.Response().Header().Set(echo.HeaderWWWAuthenticate, `Bearer error="invalid_token"`)
+				return fmt.Errorf("%w: %v", ErrUnauthorized, err)
+			}
+			if claims.Subject == "" {
+				c.Response().Header().Set(echo.HeaderWWWAuthenticate, `Bearer error="invalid_token"`)
+				return fmt.Errorf("%w: token has no subject", ErrUnauthorized)
+			}
+
+			SetPrincipal(c, NewPrincipal(claims.Subject, claims.Roles...))
+			return next(c)
+		}
+	}
+}
+
+// Principal is the authenticated caller of a request.
+type Principal struct {
+	Subject string
+	// UserID is the user the subject names, 0 when the subject is not a user id.
+	UserID int
+	Roles  []string
+	// Scopes are the permissions of the API key the caller authenticated with.
+	Scopes []Permission
+}
+
+// NewPrincipal is the caller named by subject, a user id like "42" makes it that user.
+// Without roles the caller is a customer.
+func NewPrincipal(subject string, roles ...string) Principal {
+	userID, err := strconv.Atoi(subject)
+	if err != nil || userID < 1 {
+		userID = 0
+	}
+	if len(roles) == 0 {
+		roles = []string{RoleCustomer}
+	}
+	return Principal{Subject: subject, UserID: userID, Roles: roles}
+}
+
+// SetPrincipal places the caller on the context, for the handlers to authorize against.
+func SetPrincipal(c echo.Context, p Principal) {
+	c.Set(contextKeyPrincipal, p)
+}
+
+// CurrentPrincipal is the caller placed on the context by SetPrincipal, false without one.
+func CurrentPrincipal(c echo.Context) (Principal, bool) {
+	p, ok := c.Get(contextKeyPrincipal).(Principal)
+	return p, ok
+}
+
+// Subject is the sub claim of the bearer token JWTAuth verified, "api_key:<id>" for an
+// API key, "" without either.
+func Subject(c echo.Context) string {
+	p, _ := CurrentPrincipal(c)
+	return p.Subject
+}
@@ -0,0 +1,246 @@
+package wallet
+
+import (
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/golfz/fun-exercise-api/wallet/authtest"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// anonymousSetup is testSetup for a request no middleware authenticated yet.
+func anonymousSetup(method, url string, body io.Reader) (*httptest.ResponseRecorder, echo.Context, *Handler, *mockWalletStorer) {
+	resp, c, h, mock := testSetup(method, url, body)
+	c.Set(contextKeyPrincipal, nil)
+	return resp, c, h, mock
+}
+
+// authSetup serves GetWalletsHandler behind JWTAuth with keys, sending authorization when not empty,
+// subject is the Subject the handler saw.
+func authSetup(keys JWTKeys, authorization string) (resp *httptest.ResponseRecorder, c echo.Context, handler echo.HandlerFunc, subject *string) {
+	resp, c, h, _ := anonymousSetup(http.MethodGet, "/api/v1/wallets", nil)
+	if authorization != "" {
+		c.Request().Header.Set(echo.HeaderAuthorization, authorization)
+	}
+	subject = new(string)
+	handler = JWTAuth(keys)(func(c echo.Context) error {
+		*subject = Subject(c)
+		return h.GetWalletsHandler(c)
+	})
+	return resp, c, handler, subject
+}
+
+func TestJWTAuth(t *testing.T) {
+	rsaKey := authtest.RSAKey(t)
+	jwks := JWTKeys{"rsa-1": &rsaKey.PublicKey, "hmac-1": authtest.Secret}
+
+	tests := []struct {
+		name          string
+		keys          JWTKeys
+		authorization func(t *testing.T) string
+		wantStatus    int
+		wantSubject   string
+	}{
+		{
+			name:          "given HS256 token should pass the subject on",
+			keys:          authtest.Keys(),
+			authorization: func(t *testing.T) string { return authtest.Bearer(t, "42") },
+			wantStatus:    http.StatusOK,
+			wantSubject:   "42",
+		},
+		{
+			name: "given RS256 token should pick the key by kid",
+			keys: jwks,
+			authorization: func(t *testing.T) string {
+				return "Bearer " + authtest.Sign(t, jwt.SigningMethodRS256, rsaKey, "rsa-1", authtest.Claims("7"))
+			},
+			wantStatus:  http.StatusOK,
+			wantSubject: "7",
+		},
+		{
+			name:          "given lowercase scheme should accept the token",
+			keys:          authtest.Keys(),
+			authorization: func(t *testing.T) string { return "bearer " + authtest.Token(t, "42") },
+			wantStatus:    http.StatusOK,
+			wantSubject:   "42",
+		},
+		{
+			name:          "given no header should return 401",
+			keys:          authtest.Keys(),
+			authorization: func(t *testing.T) string { return "" },
+			wantStatus:    http.StatusUnauthorized,
+		},
+		{
+			name:          "given basic credentials should return 401",
+			keys:          authtest.Keys(),
+			authorization: func(t *testing.T) string { return "Basic dXNlcjpwYXNz" },
+			wantStatus:    http.StatusUnauthorized,
+		},
+		{
+			name: "given token signed with another secret should return 401",
+			keys: authtest.Keys(),
+			authorization: func(t *testing.T) string {
+				return "Bearer " + authtest.Sign(t, jwt.SigningMethodHS256, []byte(strings.Repeat("x", 32)), "", authtest.Claims("42"))
+			},
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name: "given expired token should return 401",
+			keys: authtest.Keys(),
+			authorization: func(t *testing.T) string {
+				claims := authtest.Claims("42")
+				claims.ExpiresAt = jwt.NewNumericDate(time.Now().Add(-time.Hour))
+				return "Bearer " + authtest.Sign(t, jwt.SigningMethodHS256, authtest.Secret, "", claims)
+			},
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name: "given token without expiry should return 401",
+			keys: authtest.Keys(),
+			authorization: func(t *testing.T) string {
+				claims := authtest.Claims("42")
+				claims.ExpiresAt = nil
+				return "Bearer " + authtest.Sign(t, jwt.SigningMethodHS256, authtest.Secret, "", claims)
+			},
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name:          "given token without subject should return 401",
+			keys:          authtest.Keys(),
+			authorization: func(t *testing.T) string { return authtest.Bearer(t, "") },
+			wantStatus:    http.StatusUnauthorized,
+		},
+		{
+			name: "given unsigned token should return 401",
+			keys: authtest.Keys(),
+			authorization: func(t *testing.T) string {
+				return "Bearer " + authtest.Sign(t, jwt.SigningMethodNone, jwt.UnsafeAllowNoneSignatureType, "", authtest.Claims("42"))
+			},
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name: "given HS256 token signed with the RSA public key should return 401",
+			keys: JWTKeys{"": &rsaKey.PublicKey},
+			authorization: func(t *testing.T) string {
+				public := x509.MarshalPKCS1PublicKey(&rsaKey.PublicKey)
+				return "Bearer " + authtest.Sign(t, jwt.SigningMethodHS256, public, "", authtest.Claims("42"))
+			},
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name: "given unknown kid should return 401",
+			keys: jwks,
+			authorization: func(t *testing.T) string {
+				return "Bearer " + authtest.Sign(t, jwt.SigningMethodRS256, rsaKey, "rsa-2", authtest.Claims("7"))
+			},
+			wantStatus: http.StatusUnauthorized,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			resp, c, handler, subject := authSetup(tt.keys, tt.authorization(t))
+
+			// Act
+			err := serve(c, handler)
+
+			// Assert
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantStatus, resp.Code)
+			assert.Equal(t, tt.wantSubject, *subject)
+			if tt.wantStatus == http.StatusUnauthorized {
+				assert.Contains(t, resp.Body.String(), CodeUnauthorized)
+				assert.True(t, strings.HasPrefix(resp.Header().Get(echo.HeaderWWWAuthenticate), "Bearer"))
+			}
+		})
+	}
+}
+
+func TestLoadJWTKey(t *testing.T) {
+	t.Run("given a secret should trim it", func(t *testing.T) {
+		keys, err := LoadJWTKey(strings.NewReader(string(authtest.Secret) + "\n"))
+
+		require.NoError(t, err)
+		assert.Equal(t, JWTKeys{"": authtest.Secret}, keys)
+	})
+
+	t.Run("given a short secret should return error", func(t *testing.T) {
+		_, err := LoadJWTKey(strings.NewReader("too short"))
+
+		assert.Error(t, err)
+	})
+
+	t.Run("given a PEM public key should read the RSA key", func(t *testing.T) {
+		key := authtest.RSAKey(t)
+		der, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+		require.NoError(t, err)
+		data := pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})
+
+		keys, err := LoadJWTKey(strings.NewReader(string(data)))
+
+		require.NoError(t, err)
+		assert.Equal(t, JWTKeys{"": &key.PublicKey}, keys)
+	})
+
+	t.Run("given a PEM private key should return error", func(t *testing.T) {
+		key := authtest.RSAKey(t)
+		data := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})
+
+		_, err := LoadJWTKey(strings.NewReader(string(data)))
+
+		assert.Error(t, err)
+	})
+}
+
+func TestLoadJWKS(t *testing.T) {
+	key := authtest.RSAKey(t)
+	b64 := base64.RawURLEncoding.EncodeToString
+	rsaJWK := fmt.Sprintf(`{"kty": "RSA", "kid": "rsa-1", "use": "sig", "n": %q, "e": %q}`, b64(key.N.Bytes()), b64(big.NewInt(int64(key.E)).Bytes()))
+	octJWK := fmt.Sprintf(`{"kty": "oct", "kid": "hmac-1", "k": %q}`, b64(authtest.Secret))
+
+	t.Run("given RSA and oct keys should index them by kid", func(t *testing.T) {
+		keys, err := LoadJWKS(strings.NewReader(`{"keys": [` + rsaJWK + `, ` + octJWK + `]}`))
+
+		require.NoError(t, err)
+		assert.Equal(t, JWTKeys{"rsa-1": &rsa.PublicKey{N: key.N, E: key.E}, "hmac-1": authtest.Secret}, keys)
+	})
+
+	t.Run("given an encryption key should skip it", func(t *testing.T) {
+		keys, err := LoadJWKS(strings.NewReader(`{"keys": [` + rsaJWK + `, {"kty": "EC", "kid": "ec-1", "use": "enc"}]}`))
+
+		require.NoError(t, err)
+		assert.Len(t, keys, 1)
+	})
+
+	tests := []struct {
+		name string
+		jwks string
+	}{
+		{name: "no keys", jwks: `{"keys": []}`},
+		{name: "unsupported key type", jwks: `{"keys": [{"kty": "EC", "kid": "ec-1"}]}`},
+		{name: "short oct secret", jwks: `{"keys": [{"kty": "oct", "k": "c2hvcnQ"}]}`},
+		{name: "duplicate kid", jwks: `{"keys": [` + rsaJWK + `, ` + rsaJWK + `]}`},
+		{name: "not JSON", jwks: `keys`},
+	}
+	for _, tt := range tests {
+		t.Run("given "+tt.name+" should return error", func(t *testing.T) {
+			_, err := LoadJWKS(strings.NewReader(tt.jwks))
+
+			assert.Error(t, err)
+		})
+	}
+}
@@ -0,0 +1,70 @@
+// Package authtest mints bearer tokens for tests of handlers behind wallet.JWTAuth,
+// it does not import wallet so the wallet tests can use it too.
+package authtest
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/require"
+)
+
+// Secret is the HS256 secret of Keys.
+var Secret = []byte("authtest-secret-at-least-32-bytes")
+
+// Keys are the wallet.JWTKeys verifying the HS256 tokens minted by Token.
+func Keys() map[string]interface{} {
+	return map[string]interface{}{"": Secret}
+}
+
+// Claims are valid for an hour from now, for subject.
+func Claims(subject string) jwt.RegisteredClaims {
+	now := time.Now()
+	return jwt.RegisteredClaims{
+		Subject:   subject,
+		IssuedAt:  jwt.NewNumericDate(now),
+		ExpiresAt: jwt.NewNumericDate(now.Add(time.Hour)),
+	}
+}
+
+// roleClaims carry the roles claim read by wallet.JWTAuth.
+type roleClaims struct {
+	jwt.RegisteredClaims
+	Roles []string `json:"roles,omitempty"`
+}
+
+// Token is an HS256 token for subject signed with Secret, without roles the
+// subject is a customer.
+func Token(t *testing.T, subject string, roles ...string) string {
+	t.Helper()
+	return Sign(t, jwt.SigningMethodHS256, Secret, "", roleClaims{RegisteredClaims: Claims(subject), Roles: roles})
+}
+
+// Bearer is the Authorization header value carrying Token.
+func Bearer(t *testing.T, subject string, roles ...string) string {
+	t.Helper()
+	return "Bearer " + Token(t, subject, roles...)
+}
+
+// Sign signs claims with key, kid is left out of the header when empty.
+func Sign(t *testing.T, method jwt.SigningMethod, key interface{}, kid string, claims jwt.Claims) string {
+	t.Helper()
+	token := jwt.NewWithClaims(method, claims)
+	if kid != "" {
+		token.Header["kid"] = kid
+	}
+	signed, err := token.SignedString(key)
+	require.NoError(t, err)
+	return signed
+}
+
+// RSAKey generates a key pair for RS256 tokens.
+func RSAKey(t *testing.T) *rsa.PrivateKey {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	return key
+}
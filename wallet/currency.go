@@ -0,0 +1,56 @@
+package wallet
+
+import "fmt"
+
+const (
+	CurrencyTHB = "THB"
+	CurrencyUSD = "USD"
+	CurrencyEUR = "EUR"
+	CurrencyJPY = "JPY"
+	CurrencyBTC = "BTC"
+)
+
+// DefaultCurrency is used when a wallet is created without a currency.
+const DefaultCurrency = CurrencyTHB
+
+// CurrencyDecimals is the number of decimal places each supported currency allows.
+var CurrencyDecimals = map[string]int{
+	CurrencyTHB: 2,
+	CurrencyUSD: 2,
+	CurrencyEUR: 2,
+	CurrencyJPY: 0,
+	CurrencyBTC: 8,
+}
+
+// cryptoCurrencies can only be held in a Crypto Wallet.
+var cryptoCurrencies = map[string]bool{
+	CurrencyBTC: true,
+}
+
+func IsCurrencyValid(currency string) bool {
+	_, ok := CurrencyDecimals[currency]
+	return ok
+}
+
+// CheckCurrency reports whether a wallet of the given type may hold the currency.
+func CheckCurrency(walletType, currency string) error {
+	if !IsCurrencyValid(currency) {
+		return fmt.Errorf("%w: %q", ErrUnsupportedCurrency, currency)
+	}
+	if cryptoCurrencies[currency] && walletType != WalletTypeCryptoWallet {
+		return fmt.Errorf("%w: %s is only available for %s", ErrUnsupportedCurrency, currency, WalletTypeCryptoWallet)
+	}
+	return nil
+}
+
+// CheckAmountPrecision reports whether amount fits the decimal places of the currency.
+func CheckAmountPrecision(currency string, amount Money) error {
+	decimals, ok := CurrencyDecimals[currency]
+	if !ok {
+		return fmt.Errorf("%w: %q", ErrUnsupportedCurrency, currency)
+	}
+	if amount.Decimals() > decimals {
+		return fmt.Errorf("%w: %s allows at most %d decimal places", ErrInvalidPrecision, currency, decimals)
+	}
+	return nil
+}
@@ -0,0 +1,97 @@
+package wallet
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestCheckCurrency(t *testing.T) {
+	tests := []struct {
+		name       string
+		walletType string
+		currency   string
+		wantErr    error
+	}{
+		{
+			name:       "Fiat currency in Savings",
+			walletType: WalletTypeSavings,
+			currency:   CurrencyUSD,
+		},
+		{
+			name:       "Crypto currency in Crypto Wallet",
+			walletType: WalletTypeCryptoWallet,
+			currency:   CurrencyBTC,
+		},
+		{
+			name:       "Crypto currency in Savings",
+			walletType: WalletTypeSavings,
+			currency:   CurrencyBTC,
+			wantErr:    ErrUnsupportedCurrency,
+		},
+		{
+			name:       "Unknown currency",
+			walletType: WalletTypeSavings,
+			currency:   "XYZ",
+			wantErr:    ErrUnsupportedCurrency,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if err := CheckCurrency(test.walletType, test.currency); !errors.Is(err, test.wantErr) {
+				t.Errorf("CheckCurrency() = %v, want %v", err, test.wantErr)
+			}
+		})
+	}
+}
+
+func TestCheckAmountPrecision(t *testing.T) {
+	tests := []struct {
+		name     string
+		currency string
+		amount   Money
+		wantErr  error
+	}{
+		{
+			name:     "THB with cents",
+			currency: CurrencyTHB,
+			amount:   MustParseMoney("10.25"),
+		},
+		{
+			name:     "THB with fraction of a cent",
+			currency: CurrencyTHB,
+			amount:   MustParseMoney("10.255"),
+			wantErr:  ErrInvalidPrecision,
+		},
+		{
+			name:     "JPY whole yen",
+			currency: CurrencyJPY,
+			amount:   MustParseMoney("1500"),
+		},
+		{
+			name:     "JPY with decimals",
+			currency: CurrencyJPY,
+			amount:   MustParseMoney("1500.5"),
+			wantErr:  ErrInvalidPrecision,
+		},
+		{
+			name:     "BTC with satoshi",
+			currency: CurrencyBTC,
+			amount:   MustParseMoney("0.00000001"),
+		},
+		{
+			name:     "Unknown currency",
+			currency: "XYZ",
+			amount:   MustParseMoney("1"),
+			wantErr:  ErrUnsupportedCurrency,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if err := CheckAmountPrecision(test.currency, test.amount); !errors.Is(err, test.wantErr) {
+				t.Errorf("CheckAmountPrecision() = %v, want %v", err, test.wantErr)
+			}
+		})
+	}
+}
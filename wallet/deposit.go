@@ -0,0 +1,104 @@
+package wallet
+
+import (
+	"github.com/labstack/echo/v4"
+	"net/http"
+)
+
+type BalanceChange struct {
+	Amount Money `json:"amount" swaggertype:"number" example:"100.00"`
+}
+
+// AllowsNegativeBalance reports whether a wallet of the given type may be overdrawn.
+func AllowsNegativeBalance(walletType string) bool {
+	return walletType == WalletTypeCreditCard
+}
+
+// CanDebit reports whether amount can be taken out of the wallet.
+func CanDebit(w Wallet, amount Money) bool {
+	return AllowsNegativeBalance(w.WalletType) || w.Balance >= amount
+}
+
+// bindBalanceChange reads the id of a wallet the caller owns and the amount to move.
+func (h *Handler) bindBalanceChange(c echo.Context) (int, Money, error) {
+	walletID, err := h.ownedWalletID(c)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	change := BalanceChange{}
+	if err := c.Bind(&change); err != nil {
+		return 0, 0, err
+	}
+	v := validator{}
+	v.checkPositive("amount", change.Amount)
+	if err := v.err(); err != nil {
+		return 0, 0, err
+	}
+
+	return walletID, change.Amount, nil
+}
+
+// DepositHandler
+//
+//	@Summary		Deposit money into the wallet
+//	@Description	Add the amount to the wallet balance, frozen and closed wallets refuse it
+//	@Tags			wallet
+//	@Accept			json
+//	@Produce		json
+//	@Param			id		path	int				true	"Wallet ID"
+//	@Param			deposit	body	BalanceChange	true	"Amount to deposit"
+//	@Param			Idempotency-Key	header	string	false	"Key to run a retried request only once"
+//	@Success		200	{object}	Wallet
+//	@Failure		400	{object}	Problem
+//	@Failure		403	{object}	Problem
+//	@Failure		404	{object}	Problem
+//	@Failure		422	{object}	Problem
+//	@Failure		500	{object}	Problem
+//	@Router			/api/v1/wallets/{id}/deposits [post]
+func (h *Handler) DepositHandler(c echo.Context) error {
+	walletID, amount, err := h.bindBalanceChange(c)
+	if err != nil {
+		return err
+	}
+
+	// deposit money
+	wallet, err := h.store.Deposit(walletID, amount)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, wallet)
+}
+
+// WithdrawHandler
+//
+//	@Summary		Withdraw money from the wallet
+//	@Description	Subtract the amount from the wallet balance, only Credit Card wallets may go below zero
+//	@Tags			wallet
+//	@Accept			json
+//	@Produce		json
+//	@Param			id			path	int				true	"Wallet ID"
+//	@Param			withdrawal	body	BalanceChange	true	"Amount to withdraw"
+//	@Param			Idempotency-Key	header	string	false	"Key to run a retried request only once"
+//	@Success		200	{object}	Wallet
+//	@Failure		400	{object}	Problem
+//	@Failure		403	{object}	Problem
+//	@Failure		404	{object}	Problem
+//	@Failure		422	{object}	Problem
+//	@Failure		500	{object}	Problem
+//	@Router			/api/v1/wallets/{id}/withdrawals [post]
+func (h *Handler) WithdrawHandler(c echo.Context) error {
+	walletID, amount, err := h.bindBalanceChange(c)
+	if err != nil {
+		return err
+	}
+
+	// withdraw money
+	wallet, err := h.store.Withdraw(walletID, amount)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, wallet)
+}
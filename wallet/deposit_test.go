@@ -0,0 +1,186 @@
+package wallet
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestCanDebit(t *testing.T) {
+	tests := []struct {
+		name   string
+		wallet Wallet
+		amount Money
+		want   bool
+	}{
+		{
+			name:   "Savings with enough balance",
+			wallet: Wallet{WalletType: WalletTypeSavings, Balance: MustParseMoney("100")},
+			amount: MustParseMoney("100"),
+			want:   true,
+		},
+		{
+			name:   "Savings without enough balance",
+			wallet: Wallet{WalletType: WalletTypeSavings, Balance: MustParseMoney("100")},
+			amount: MustParseMoney("100.01"),
+			want:   false,
+		},
+		{
+			name:   "Crypto Wallet without enough balance",
+			wallet: Wallet{WalletType: WalletTypeCryptoWallet, Balance: MustParseMoney("0")},
+			amount: MustParseMoney("1"),
+			want:   false,
+		},
+		{
+			name:   "Credit Card without enough balance",
+			wallet: Wallet{WalletType: WalletTypeCreditCard, Balance: MustParseMoney("0")},
+			amount: MustParseMoney("1"),
+			want:   true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := CanDebit(test.wallet, test.amount); got != test.want {
+				t.Errorf("CanDebit() = %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestDepositAndWithdraw(t *testing.T) {
+	handlers := []struct {
+		name    string
+		path    string
+		method  string
+		handler func(h *Handler, c echo.Context) error
+	}{
+		{name: "deposit", path: "/api/v1/wallets/:id/deposits", method: "Deposit", handler: (*Handler).DepositHandler},
+		{name: "withdraw", path: "/api/v1/wallets/:id/withdrawals", method: "Withdraw", handler: (*Handler).WithdrawHandler},
+	}
+
+	for _, hh := range handlers {
+		badRequests := []struct {
+			name string
+			id   string
+			body string
+		}{
+			{name: "given wallet id is not number", id: "abc", body: `{"amount": 10}`},
+			{name: "given invalid json", id: "1", body: `{"amount": "ten"}`},
+			{name: "given zero amount", id: "1", body: `{"amount": 0}`},
+			{name: "given negative amount", id: "1", body: `{"amount": -10}`},
+		}
+		for _, test := range badRequests {
+			t.Run(hh.name+": "+test.name+" should return 400 and error message", func(t *testing.T) {
+				// Arrange
+				resp, c, h, mock := testSetup(http.MethodPost, "/", strings.NewReader(test.body))
+				c.Request().Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+				c.SetPath(hh.path)
+				c.SetParamNames("id")
+				c.SetParamValues(test.id)
+
+				// Act
+				err := serve(c, func(c echo.Context) error { return hh.handler(h, c) })
+
+				// Assert
+				assert.NoError(t, err)
+				assert.False(t, mock.methodToCall[hh.method])
+				assert.Equal(t, http.StatusBadRequest, resp.Code)
+				var got Problem
+				if err := json.Unmarshal(resp.Body.Bytes(), &got); err != nil {
+					t.Errorf("expected response body to be valid json, got %s", resp.Body.String())
+				}
+				assert.NotEmpty(t, got.Code)
+			})
+		}
+
+		storeErrors := []struct {
+			name     string
+			err      error
+			wantCode int
+		}{
+			{name: "given wallet not found should return 404", err: ErrWalletNotFound, wantCode: http.StatusNotFound},
+			{name: "given unable to change balance should return 500", err: errors.New("unable to change balance"), wantCode: http.StatusInternalServerError},
+		}
+		for _, test := range storeErrors {
+			t.Run(hh.name+": "+test.name+" and error message", func(t *testing.T) {
+				// Arrange
+				resp, c, h, mock := testSetup(http.MethodPost, "/", strings.NewReader(`{"amount": 10}`))
+				c.Request().Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+				c.SetPath(hh.path)
+				c.SetParamNames("id")
+				c.SetParamValues("1")
+				mock.err = test.err
+				mock.ExpectToCall(hh.method)
+
+				// Act
+				err := serve(c, func(c echo.Context) error { return hh.handler(h, c) })
+
+				// Assert
+				mock.Verify(t)
+				assert.NoError(t, err)
+				assert.Equal(t, test.wantCode, resp.Code)
+				var got Problem
+				if err := json.Unmarshal(resp.Body.Bytes(), &got); err != nil {
+					t.Errorf("expected response body to be valid json, got %s", resp.Body.String())
+				}
+				assert.NotEmpty(t, got.Code)
+			})
+		}
+
+		t.Run(hh.name+": given valid amount should return 200 and the wallet", func(t *testing.T) {
+			// Arrange
+			resp, c, h, mock := testSetup(http.MethodPost, "/", strings.NewReader(`{"amount": 10.5}`))
+			c.Request().Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			c.SetPath(hh.path)
+			c.SetParamNames("id")
+			c.SetParamValues("3")
+			want := Wallet{ID: 3, WalletType: WalletTypeSavings, Balance: MustParseMoney("110.5")}
+			mock.wallet = want
+			mock.ExpectToCall(hh.method)
+
+			// Act
+			err := serve(c, func(c echo.Context) error { return hh.handler(h, c) })
+
+			// Assert
+			mock.Verify(t)
+			assert.NoError(t, err)
+			assert.Equal(t, 3, mock.whatIsID)
+			assert.Equal(t, MustParseMoney("10.5"), mock.whatIsAmount)
+			assert.Equal(t, http.StatusOK, resp.Code)
+			var got Wallet
+			if err := json.Unmarshal(resp.Body.Bytes(), &got); err != nil {
+				t.Errorf("expected response body to be valid json, got %s", resp.Body.String())
+			}
+			assert.Equal(t, want, got)
+		})
+	}
+
+	t.Run("withdraw: given insufficient funds should return 422 and error message", func(t *testing.T) {
+		// Arrange
+		resp, c, h, mock := testSetup(http.MethodPost, "/", strings.NewReader(`{"amount": 10}`))
+		c.Request().Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		c.SetPath("/api/v1/wallets/:id/withdrawals")
+		c.SetParamNames("id")
+		c.SetParamValues("1")
+		mock.err = ErrInsufficientFunds
+		mock.ExpectToCall("Withdraw")
+
+		// Act
+		err := serve(c, h.WithdrawHandler)
+
+		// Assert
+		mock.Verify(t)
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusUnprocessableEntity, resp.Code)
+		var got Problem
+		if err := json.Unmarshal(resp.Body.Bytes(), &got); err != nil {
+			t.Errorf("expected response body to be valid json, got %s", resp.Body.String())
+		}
+		assert.NotEmpty(t, got.Code)
+	})
+}
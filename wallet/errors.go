@@ -0,0 +1,30 @@
+package wallet
+
+import "errors"
+
+var (
+	ErrWalletNotFound      = errors.New("wallet not found")
+	ErrUserNotFound        = errors.New("user not found")
+	ErrUserHasWallets      = errors.New("user still has wallets")
+	ErrInsufficientFunds   = errors.New("insufficient funds")
+	ErrUnsupportedCurrency = errors.New("unsupported currency")
+	ErrInvalidPrecision    = errors.New("too many decimal places for currency")
+	ErrCurrencyMismatch    = errors.New("wallets have different currencies")
+	ErrRateNotFound        = errors.New("fx rate not found")
+	ErrConversionTooSmall  = errors.New("converted amount rounds to zero")
+	ErrNegativeBalance     = errors.New("wallet type does not allow a negative balance")
+	ErrDuplicateWallet     = errors.New("user already has a wallet with this name")
+	ErrVersionMismatch     = errors.New("wallet was changed since it was read")
+	ErrWalletFrozen        = errors.New("wallet is frozen")
+	ErrWalletClosed        = errors.New("wallet is closed")
+	// ErrInvalidStatusTransition means the wallet cannot move from its status to the requested one.
+	ErrInvalidStatusTransition = errors.New("wallet status cannot change that way")
+	// ErrWalletNotEmpty means the wallet still holds money or debt, so it cannot be closed.
+	ErrWalletNotEmpty = errors.New("wallet balance must be zero to close it")
+	// ErrWalletTypeChange means a patch would give the wallet a type allowing a negative balance.
+	ErrWalletTypeChange = errors.New("wallet type cannot change to one allowing a negative balance")
+	// ErrPreconditionRequired means the If-Match header protecting a change is missing.
+	ErrPreconditionRequired = errors.New("If-Match header with the wallet ETag is required")
+	// ErrConflict means a concurrent request got in the way, the request may be retried.
+	ErrConflict = errors.New("wallet was changed by a concurrent request")
+)
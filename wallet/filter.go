@@ -0,0 +1,156 @@
+package wallet
+
+import (
+	"github.com/labstack/echo/v4"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Filter selects wallets, zero-valued fields match everything.
+type Filter struct {
+	UserID      int
+	WalletTypes []string
+	Currency    string
+	MinBalance  *Money
+	MaxBalance  *Money
+	// CreatedAfter and CreatedBefore are exclusive bounds.
+	CreatedAfter  *time.Time
+	CreatedBefore *time.Time
+	// WalletName matches wallet names starting with it, ignoring case.
+	WalletName string
+	// UserName matches user names containing it, ignoring case.
+	UserName string
+	// IncludeDeleted also matches soft-deleted wallets.
+	IncludeDeleted bool
+}
+
+// Match reports whether w passes the filter, the in-memory twin of the SQL filter.
+func (f Filter) Match(w Wallet) bool {
+	if !f.IncludeDeleted && w.DeletedAt != nil {
+		return false
+	}
+	if f.UserID != 0 && w.UserID != f.UserID {
+		return false
+	}
+	if len(f.WalletTypes) > 0 && !containsString(f.WalletTypes, w.WalletType) {
+		return false
+	}
+	if f.Currency != "" && w.Currency != f.Currency {
+		return false
+	}
+	if f.MinBalance != nil && w.Balance < *f.MinBalance {
+		return false
+	}
+	if f.MaxBalance != nil && w.Balance > *f.MaxBalance {
+		return false
+	}
+	if f.CreatedAfter != nil && !w.CreatedAt.After(*f.CreatedAfter) {
+		return false
+	}
+	if f.CreatedBefore != nil && !w.CreatedAt.Before(*f.CreatedBefore) {
+		return false
+	}
+	if f.WalletName != "" && !strings.HasPrefix(strings.ToLower(w.WalletName), strings.ToLower(f.WalletName)) {
+		return false
+	}
+	if f.UserName != "" && !strings.Contains(strings.ToLower(w.UserName), strings.ToLower(f.UserName)) {
+		return false
+	}
+	return true
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// ParseFilter reads the wallet_type, currency, min_balance, max_balance, created_after,
+// created_before, wallet_name, user_name and include_deleted query params.
+// wallet_type may repeat or hold a comma-separated list.
+func ParseFilter(c echo.Context) (Filter, error) {
+	filter := Filter{}
+	params := c.QueryParams()
+	v := validator{}
+
+	// prepare filter: wallet_type
+	for _, value := range params["wallet_type"] {
+		for _, walletType := range strings.Split(value, ",") {
+			walletType = strings.TrimSpace(walletType)
+			if v.checkWalletType("wallet_type", walletType) {
+				filter.WalletTypes = append(filter.WalletTypes, walletType)
+			}
+		}
+	}
+
+	// prepare filter: currency
+	if currency := c.QueryParam("currency"); currency != "" {
+		filter.Currency = strings.ToUpper(currency)
+		v.check(IsCurrencyValid(filter.Currency), "currency", "is not supported")
+	}
+
+	// prepare filter: balance range
+	filter.MinBalance = parseMoneyParam(c, &v, "min_balance")
+	filter.MaxBalance = parseMoneyParam(c, &v, "max_balance")
+	if filter.MinBalance != nil && filter.MaxBalance != nil {
+		v.check(*filter.MinBalance <= *filter.MaxBalance, "min_balance", "must not be greater than max_balance")
+	}
+
+	// prepare filter: created_at range
+	filter.CreatedAfter = parseTimeParam(c, &v, "created_after")
+	filter.CreatedBefore = parseTimeParam(c, &v, "created_before")
+	if filter.CreatedAfter != nil && filter.CreatedBefore != nil {
+		v.check(filter.CreatedAfter.Before(*filter.CreatedBefore), "created_after", "must be before created_before")
+	}
+
+	// prepare filter: names
+	filter.WalletName = strings.TrimSpace(c.QueryParam("wallet_name"))
+	filter.UserName = strings.TrimSpace(c.QueryParam("user_name"))
+
+	// prepare filter: soft-deleted wallets
+	if includeDeleted := c.QueryParam("include_deleted"); includeDeleted != "" {
+		var err error
+		filter.IncludeDeleted, err = strconv.ParseBool(includeDeleted)
+		v.check(err == nil, "include_deleted", "must be true or false")
+	}
+
+	if err := v.err(); err != nil {
+		return Filter{}, err
+	}
+	return filter, nil
+}
+
+func parseMoneyParam(c echo.Context, v *validator, name string) *Money {
+	value := c.QueryParam(name)
+	if value == "" {
+		return nil
+	}
+
+	m, err := ParseMoney(value)
+	if !v.check(err == nil, name, "must be a number") {
+		return nil
+	}
+	return &m
+}
+
+// parseTimeParam accepts RFC 3339 timestamps or plain dates, read as midnight UTC.
+func parseTimeParam(c echo.Context, v *validator, name string) *time.Time {
+	value := c.QueryParam(name)
+	if value == "" {
+		return nil
+	}
+
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		t, err = time.Parse("2006-01-02", value)
+	}
+	if !v.check(err == nil, name, "must be an RFC 3339 time or YYYY-MM-DD") {
+		return nil
+	}
+	t = t.UTC()
+	return &t
+}
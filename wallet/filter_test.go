@@ -0,0 +1,89 @@
+package wallet
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseFilter(t *testing.T) {
+	tests := []struct {
+		name    string
+		query   string
+		want    Filter
+		wantErr bool
+	}{
+		{name: "No params", query: "", want: Filter{}},
+		{name: "Repeated and comma-separated wallet types", query: "?wallet_type=Savings,%20Credit%20Card&wallet_type=Crypto%20Wallet", want: Filter{WalletTypes: []string{WalletTypeSavings, WalletTypeCreditCard, WalletTypeCryptoWallet}}},
+		{name: "Lower-case currency", query: "?currency=usd", want: Filter{Currency: CurrencyUSD}},
+		{name: "Names are trimmed", query: "?wallet_name=%20john%20&user_name=doe", want: Filter{WalletName: "john", UserName: "doe"}},
+		{name: "Unknown wallet type", query: "?wallet_type=Savings,Piggy", wantErr: true},
+		{name: "Unsupported currency", query: "?currency=XYZ", wantErr: true},
+		{name: "Balance not a number", query: "?min_balance=lots", wantErr: true},
+		{name: "Min balance above max balance", query: "?min_balance=10&max_balance=9.99", wantErr: true},
+		{name: "Date not a date", query: "?created_after=yesterday", wantErr: true},
+		{name: "Created after is not before created before", query: "?created_after=2024-03-25&created_before=2024-03-25", wantErr: true},
+		{name: "Include deleted", query: "?include_deleted=true", want: Filter{IncludeDeleted: true}},
+		{name: "Include deleted not a boolean", query: "?include_deleted=maybe", wantErr: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/api/v1/wallets"+test.query, nil)
+			c := echo.New().NewContext(req, httptest.NewRecorder())
+
+			got, err := ParseFilter(c)
+
+			if test.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, test.want, got)
+		})
+	}
+}
+
+func TestFilterMatch(t *testing.T) {
+	minBalance := MustParseMoney("10")
+	createdAfter := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	w := Wallet{
+		UserID:     1,
+		UserName:   "John Doe",
+		WalletName: "John Savings",
+		WalletType: WalletTypeSavings,
+		Currency:   CurrencyTHB,
+		Balance:    MustParseMoney("10"),
+		CreatedAt:  time.Date(2024, 3, 25, 0, 0, 0, 0, time.UTC),
+	}
+
+	tests := []struct {
+		name   string
+		filter Filter
+		want   bool
+	}{
+		{name: "Empty filter", filter: Filter{}, want: true},
+		{name: "Every field matching", filter: Filter{UserID: 1, WalletTypes: []string{WalletTypeCreditCard, WalletTypeSavings}, Currency: CurrencyTHB, MinBalance: &minBalance, MaxBalance: &minBalance, CreatedAfter: &createdAfter, WalletName: "jOhN s", UserName: "DOE"}, want: true},
+		{name: "Other wallet type", filter: Filter{WalletTypes: []string{WalletTypeCreditCard}}, want: false},
+		{name: "Created before the bound", filter: Filter{CreatedBefore: &createdAfter}, want: false},
+		{name: "Wallet name not a prefix", filter: Filter{WalletName: "Savings"}, want: false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(t, test.want, test.filter.Match(w))
+		})
+	}
+
+	t.Run("Deleted wallet only with include deleted", func(t *testing.T) {
+		deleted := w
+		deleted.DeletedAt = &createdAfter
+
+		assert.False(t, Filter{}.Match(deleted))
+		assert.True(t, Filter{IncludeDeleted: true}.Match(deleted))
+	})
+}
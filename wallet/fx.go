@@ -0,0 +1,232 @@
+package wallet
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"github.com/labstack/echo/v4"
+	"io"
+	"math/big"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// FXRateProvider returns how many units of to one unit of from is worth.
+type FXRateProvider interface {
+	Rate(from, to string) (*big.Rat, error)
+}
+
+// RateTable is an FXRateProvider backed by a fixed table of rates,
+// a missing pair is answered with the inverse of the opposite pair.
+type RateTable map[string]*big.Rat
+
+func rateKey(from, to string) string {
+	return from + "/" + to
+}
+
+func (t RateTable) Rate(from, to string) (*big.Rat, error) {
+	if from == to {
+		return big.NewRat(1, 1), nil
+	}
+	if rate, ok := t[rateKey(from, to)]; ok {
+		return new(big.Rat).Set(rate), nil
+	}
+	if rate, ok := t[rateKey(to, from)]; ok {
+		return new(big.Rat).Inv(rate), nil
+	}
+	return nil, fmt.Errorf("%w: %s to %s", ErrRateNotFound, from, to)
+}
+
+// LoadRateTable reads "from,to,rate" records, a header row is optional.
+func LoadRateTable(r io.Reader) (RateTable, error) {
+	records, err := csv.NewReader(r).ReadAll()
+	if err != nil {
+		return nil, err
+	}
+
+	table := make(RateTable)
+	for i, record := range records {
+		if len(record) != 3 {
+			return nil, fmt.Errorf("fx rates line %d: expected from,to,rate", i+1)
+		}
+		if i == 0 && strings.EqualFold(record[2], "rate") {
+			continue
+		}
+
+		from, to := strings.ToUpper(strings.TrimSpace(record[0])), strings.ToUpper(strings.TrimSpace(record[1]))
+		if !IsCurrencyValid(from) || !IsCurrencyValid(to) {
+			return nil, fmt.Errorf("fx rates line %d: %w", i+1, ErrUnsupportedCurrency)
+		}
+		rate, err := ParseRate(strings.TrimSpace(record[2]))
+		if err != nil {
+			return nil, fmt.Errorf("fx rates line %d: %w", i+1, err)
+		}
+		table[rateKey(from, to)] = rate
+	}
+
+	return table, nil
+}
+
+// LoadRateTableFile loads a RateTable from a CSV file, see LoadRateTable.
+func LoadRateTableFile(name string) (RateTable, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return LoadRateTable(f)
+}
+
+// ParseRate parses a positive decimal exchange rate such as "0.0285".
+func ParseRate(s string) (*big.Rat, error) {
+	rate, ok := new(big.Rat).SetString(s)
+	if !ok || rate.Sign() <= 0 {
+		return nil, fmt.Errorf("invalid fx rate %q", s)
+	}
+	return rate, nil
+}
+
+// RateScale is the number of decimal places an exchange rate is recorded with.
+const RateScale = 12
+
+// roundRate rounds rate to RateScale decimal places, the rate FormatRate records.
+func roundRate(rate *big.Rat) *big.Rat {
+	rounded, _ := new(big.Rat).SetString(rate.FloatString(RateScale))
+	return rounded
+}
+
+// FormatRate formats rate as a decimal with at most RateScale decimal places.
+func FormatRate(rate *big.Rat) string {
+	text := rate.FloatString(RateScale)
+	text = strings.TrimRight(strings.TrimRight(text, "0"), ".")
+	return text
+}
+
+type FXQuote struct {
+	From            string `json:"from" example:"THB"`
+	To              string `json:"to" example:"USD"`
+	Rate            string `json:"rate" example:"0.0285"`
+	Amount          Money  `json:"amount" swaggertype:"number" example:"100.00"`
+	ConvertedAmount Money  `json:"converted_amount" swaggertype:"number" example:"2.85"`
+}
+
+type Conversion struct {
+	ID            int       `json:"id" example:"1"`
+	FromWalletID  int       `json:"from_wallet_id" example:"1"`
+	ToWalletID    int       `json:"to_wallet_id" example:"2"`
+	FromCurrency  string    `json:"from_currency" example:"THB"`
+	ToCurrency    string    `json:"to_currency" example:"USD"`
+	FromAmount    Money     `json:"from_amount" swaggertype:"number" example:"100.00"`
+	ToAmount      Money     `json:"to_amount" swaggertype:"number" example:"2.85"`
+	Rate          string    `json:"rate" example:"0.0285"`
+	CorrelationID string    `json:"correlation_id" example:"6f1c2a8e-3b7d-4c1e-9f0a-2d5b8e7c4a10"`
+	CreatedAt     time.Time `json:"created_at" example:"2024-03-25T14:19:00.729237Z"`
+	From          Wallet    `json:"from"`
+	To            Wallet    `json:"to"`
+}
+
+// Quote converts amount of from into to at the provider's current rate.
+func Quote(rates FXRateProvider, from, to string, amount Money) (FXQuote, error) {
+	if err := CheckAmountPrecision(from, amount); err != nil {
+		return FXQuote{}, err
+	}
+	if !IsCurrencyValid(to) {
+		return FXQuote{}, fmt.Errorf("%w: %q", ErrUnsupportedCurrency, to)
+	}
+
+	rate, err := rates.Rate(from, to)
+	if err != nil {
+		return FXQuote{}, err
+	}
+	// price at the rate the quote records, so the converted amount can be recomputed from it
+	rate = roundRate(rate)
+	converted, err := amount.Convert(rate, CurrencyDecimals[to])
+	if err != nil {
+		return FXQuote{}, err
+	}
+	if converted.IsZero() {
+		return FXQuote{}, ErrConversionTooSmall
+	}
+
+	return FXQuote{From: from, To: to, Rate: FormatRate(rate), Amount: amount, ConvertedAmount: converted}, nil
+}
+
+// GetFXQuoteHandler
+//
+//	@Summary		Get an FX quote
+//	@Description	Convert an amount between two currencies at the current rate without moving money
+//	@Tags			fx
+//	@Produce		json
+//	@Param			from	query		string	true	"Currency to convert from"
+//	@Param			to		query		string	true	"Currency to convert to"
+//	@Param			amount	query		number	true	"Amount to convert"
+//	@Success		200		{object}	FXQuote
+//	@Failure		400		{object}	Problem
+//	@Failure		404		{object}	Problem
+//	@Router			/api/v1/fx/quote [get]
+func (h *Handler) GetFXQuoteHandler(c echo.Context) error {
+	from := strings.ToUpper(c.QueryParam("from"))
+	to := strings.ToUpper(c.QueryParam("to"))
+	amount, amountErr := ParseMoney(c.QueryParam("amount"))
+
+	v := validator{}
+	v.check(from != "", "from", "is required")
+	v.check(to != "", "to", "is required")
+	if v.check(amountErr == nil, "amount", "must be a number") {
+		v.checkPositive("amount", amount)
+	}
+	if err := v.err(); err != nil {
+		return err
+	}
+
+	quote, err := Quote(h.rates, from, to, amount)
+	if errors.Is(err, ErrRateNotFound) {
+		return WithStatus(http.StatusNotFound, err)
+	}
+	if err != nil {
+		return WithStatus(http.StatusBadRequest, err)
+	}
+
+	return c.JSON(http.StatusOK, quote)
+}
+
+// ConvertHandler
+//
+//	@Summary		Convert money between wallets of different currencies
+//	@Description	Debit one wallet and credit another with the converted amount in a single transaction
+//	@Tags			fx
+//	@Accept			json
+//	@Produce		json
+//	@Param			conversion	body	Transfer	true	"Amount in the currency of the source wallet"
+//	@Param			Idempotency-Key	header	string	false	"Key to run a retried request only once"
+//	@Success		200	{object}	Conversion
+//	@Failure		400	{object}	Problem
+//	@Failure		403	{object}	Problem
+//	@Failure		404	{object}	Problem
+//	@Failure		422	{object}	Problem
+//	@Failure		500	{object}	Problem
+//	@Router			/api/v1/conversions [post]
+func (h *Handler) ConvertHandler(c echo.Context) error {
+	// bind request body to transfer
+	transfer := Transfer{}
+	if err := c.Bind(&transfer); err != nil {
+		return err
+	}
+	if err := transfer.Validate(); err != nil {
+		return err
+	}
+	if err := h.authorizeWallet(c, transfer.FromWalletID); err != nil {
+		return err
+	}
+
+	// convert and move money
+	conversion, err := h.store.Convert(transfer.FromWalletID, transfer.ToWalletID, transfer.Amount, h.rates)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, conversion)
+}
@@ -0,0 +1,255 @@
+package wallet
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"math/big"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestRateTable(t *testing.T) {
+	table := RateTable{
+		rateKey(CurrencyUSD, CurrencyTHB): big.NewRat(365, 10),
+	}
+
+	tests := []struct {
+		name    string
+		from    string
+		to      string
+		want    *big.Rat
+		wantErr error
+	}{
+		{name: "Direct pair", from: CurrencyUSD, to: CurrencyTHB, want: big.NewRat(365, 10)},
+		{name: "Inverse pair", from: CurrencyTHB, to: CurrencyUSD, want: big.NewRat(10, 365)},
+		{name: "Same currency", from: CurrencyJPY, to: CurrencyJPY, want: big.NewRat(1, 1)},
+		{name: "Unknown pair", from: CurrencyEUR, to: CurrencyJPY, wantErr: ErrRateNotFound},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := table.Rate(test.from, test.to)
+			if !errors.Is(err, test.wantErr) {
+				t.Fatalf("Rate() error = %v, want %v", err, test.wantErr)
+			}
+			if test.want != nil && got.Cmp(test.want) != 0 {
+				t.Errorf("Rate() = %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestLoadRateTable(t *testing.T) {
+	t.Run("given csv with header should load every pair", func(t *testing.T) {
+		table, err := LoadRateTable(strings.NewReader("from,to,rate\nusd,thb,36.50\nEUR,USD,1.08\n"))
+
+		assert.NoError(t, err)
+		assert.Len(t, table, 2)
+		rate, err := table.Rate(CurrencyEUR, CurrencyUSD)
+		assert.NoError(t, err)
+		assert.Equal(t, "1.08", FormatRate(rate))
+	})
+
+	invalid := []struct {
+		name string
+		csv  string
+	}{
+		{name: "given unsupported currency", csv: "USD,XYZ,1\n"},
+		{name: "given zero rate", csv: "USD,THB,0\n"},
+		{name: "given not a number", csv: "USD,THB,abc\n"},
+		{name: "given missing column", csv: "USD,THB\n"},
+	}
+	for _, test := range invalid {
+		t.Run(test.name+" should return error", func(t *testing.T) {
+			_, err := LoadRateTable(strings.NewReader(test.csv))
+
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestMoneyConvert(t *testing.T) {
+	tests := []struct {
+		name     string
+		amount   Money
+		rate     *big.Rat
+		decimals int
+		want     Money
+	}{
+		{name: "USD to THB", amount: MustParseMoney("10"), rate: big.NewRat(365, 10), decimals: 2, want: MustParseMoney("365")},
+		{name: "Round half away from zero", amount: MustParseMoney("0.01"), rate: big.NewRat(1, 2), decimals: 2, want: MustParseMoney("0.01")},
+		{name: "Round down", amount: MustParseMoney("100"), rate: big.NewRat(10, 365), decimals: 2, want: MustParseMoney("2.74")},
+		{name: "To JPY", amount: MustParseMoney("10"), rate: big.NewRat(1000, 241), decimals: 0, want: MustParseMoney("41")},
+		{name: "To BTC", amount: MustParseMoney("1000"), rate: big.NewRat(1, 2450000), decimals: 8, want: MustParseMoney("0.00040816")},
+		{name: "Negative", amount: MustParseMoney("-0.01"), rate: big.NewRat(1, 2), decimals: 2, want: MustParseMoney("-0.01")},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := test.amount.Convert(test.rate, test.decimals)
+			if err != nil || got != test.want {
+				t.Errorf("Convert() = %v, %v, want %v", got, err, test.want)
+			}
+		})
+	}
+}
+
+func TestQuoteUsesRecordedRate(t *testing.T) {
+	rates := RateTable{"THB/BTC": big.NewRat(1, 3)}
+
+	quote, err := Quote(rates, "THB", "BTC", MustParseMoney("1000000000"))
+
+	assert.NoError(t, err)
+	assert.Equal(t, "0.333333333333", quote.Rate)
+	recorded, err := ParseRate(quote.Rate)
+	assert.NoError(t, err)
+	recomputed, err := quote.Amount.Convert(recorded, CurrencyDecimals["BTC"])
+	assert.NoError(t, err)
+	assert.Equal(t, MustParseMoney("333333333.333"), quote.ConvertedAmount)
+	assert.Equal(t, recomputed, quote.ConvertedAmount)
+}
+
+func TestGetFXQuote(t *testing.T) {
+	badRequests := []struct {
+		name     string
+		url      string
+		wantCode int
+	}{
+		{name: "given no currencies should return 400", url: "/api/v1/fx/quote?amount=10", wantCode: http.StatusBadRequest},
+		{name: "given no amount should return 400", url: "/api/v1/fx/quote?from=USD&to=THB", wantCode: http.StatusBadRequest},
+		{name: "given negative amount should return 400", url: "/api/v1/fx/quote?from=USD&to=THB&amount=-1", wantCode: http.StatusBadRequest},
+		{name: "given unsupported currency should return 400", url: "/api/v1/fx/quote?from=USD&to=XYZ&amount=1", wantCode: http.StatusBadRequest},
+		{name: "given too many decimals should return 400", url: "/api/v1/fx/quote?from=USD&to=THB&amount=1.001", wantCode: http.StatusBadRequest},
+		{name: "given unknown rate should return 404", url: "/api/v1/fx/quote?from=EUR&to=JPY&amount=1", wantCode: http.StatusNotFound},
+	}
+	for _, test := range badRequests {
+		t.Run(test.name+" and error message", func(t *testing.T) {
+			// Arrange
+			resp, c, h, _ := testSetup(http.MethodGet, test.url, nil)
+
+			// Act
+			err := serve(c, h.GetFXQuoteHandler)
+
+			// Assert
+			assert.NoError(t, err)
+			assert.Equal(t, test.wantCode, resp.Code)
+			var got Problem
+			if err := json.Unmarshal(resp.Body.Bytes(), &got); err != nil {
+				t.Errorf("expected response body to be valid json, got %s", resp.Body.String())
+			}
+			assert.NotEmpty(t, got.Code)
+		})
+	}
+
+	t.Run("given known rate should return 200 and the quote", func(t *testing.T) {
+		// Arrange
+		resp, c, h, _ := testSetup(http.MethodGet, "/api/v1/fx/quote?from=thb&to=usd&amount=100", nil)
+		want := FXQuote{
+			From:            CurrencyTHB,
+			To:              CurrencyUSD,
+			Rate:            "0.027397260274",
+			Amount:          MustParseMoney("100"),
+			ConvertedAmount: MustParseMoney("2.74"),
+		}
+
+		// Act
+		err := serve(c, h.GetFXQuoteHandler)
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusOK, resp.Code)
+		var got FXQuote
+		if err := json.Unmarshal(resp.Body.Bytes(), &got); err != nil {
+			t.Errorf("expected response body to be valid json, got %s", resp.Body.String())
+		}
+		assert.Equal(t, want, got)
+	})
+}
+
+func TestConvert(t *testing.T) {
+	t.Run("given same wallet should return 400 and error message", func(t *testing.T) {
+		// Arrange
+		body := `{"from_wallet_id": 1, "to_wallet_id": 1, "amount": 10}`
+		resp, c, h, mock := testSetup(http.MethodPost, "/api/v1/conversions", strings.NewReader(body))
+		c.Request().Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+
+		// Act
+		err := serve(c, h.ConvertHandler)
+
+		// Assert
+		assert.NoError(t, err)
+		assert.False(t, mock.methodToCall["Convert"])
+		assert.Equal(t, http.StatusBadRequest, resp.Code)
+	})
+
+	storeErrors := []struct {
+		name     string
+		err      error
+		wantCode int
+	}{
+		{name: "given wallet not found should return 404", err: ErrWalletNotFound, wantCode: http.StatusNotFound},
+		{name: "given too many decimals should return 400", err: ErrInvalidPrecision, wantCode: http.StatusBadRequest},
+		{name: "given insufficient funds should return 422", err: ErrInsufficientFunds, wantCode: http.StatusUnprocessableEntity},
+		{name: "given unknown rate should return 422", err: ErrRateNotFound, wantCode: http.StatusUnprocessableEntity},
+		{name: "given unable to convert should return 500", err: errors.New("unable to convert"), wantCode: http.StatusInternalServerError},
+	}
+	for _, test := range storeErrors {
+		t.Run(test.name+" and error message", func(t *testing.T) {
+			// Arrange
+			body := `{"from_wallet_id": 1, "to_wallet_id": 2, "amount": 10}`
+			resp, c, h, mock := testSetup(http.MethodPost, "/api/v1/conversions", strings.NewReader(body))
+			c.Request().Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			mock.err = test.err
+			mock.ExpectToCall("Convert")
+
+			// Act
+			err := serve(c, h.ConvertHandler)
+
+			// Assert
+			mock.Verify(t)
+			assert.NoError(t, err)
+			assert.Equal(t, test.wantCode, resp.Code)
+			var got Problem
+			if err := json.Unmarshal(resp.Body.Bytes(), &got); err != nil {
+				t.Errorf("expected response body to be valid json, got %s", resp.Body.String())
+			}
+			assert.NotEmpty(t, got.Code)
+		})
+	}
+
+	t.Run("given valid conversion should return 200 and the conversion", func(t *testing.T) {
+		// Arrange
+		body := `{"from_wallet_id": 1, "to_wallet_id": 2, "amount": 10}`
+		resp, c, h, mock := testSetup(http.MethodPost, "/api/v1/conversions", strings.NewReader(body))
+		c.Request().Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		want := Conversion{
+			ID:           1,
+			FromWalletID: 1,
+			ToWalletID:   2,
+			FromCurrency: CurrencyUSD,
+			ToCurrency:   CurrencyTHB,
+			FromAmount:   MustParseMoney("10"),
+			ToAmount:     MustParseMoney("365"),
+			Rate:         "36.5",
+		}
+		mock.conversion = want
+		mock.ExpectToCall("Convert")
+
+		// Act
+		err := serve(c, h.ConvertHandler)
+
+		// Assert
+		mock.Verify(t)
+		assert.NoError(t, err)
+		assert.Equal(t, Transfer{FromWalletID: 1, ToWalletID: 2, Amount: MustParseMoney("10")}, mock.whatIsTransfer)
+		assert.Equal(t, http.StatusOK, resp.Code)
+		var got Conversion
+		if err := json.Unmarshal(resp.Body.Bytes(), &got); err != nil {
+			t.Errorf("expected response body to be valid json, got %s", resp.Body.String())
+		}
+		assert.Equal(t, want, got)
+	})
+}
@@ -1,66 +1,135 @@
 package wallet
 
 import (
+	"errors"
 	"github.com/labstack/echo/v4"
-	"log"
 	"net/http"
-	"strconv"
+	"strings"
 )
 
 type Handler struct {
 	store Storer
-}
-
-type Filter struct {
-	WalletType string
+	rates FXRateProvider
 }
 
 type Storer interface {
-	GetWallets(filter Wallet) ([]Wallet, error)
+	UserStorer
+	APIKeyStorer
+	GetWallets(filter Filter, page Page) (WalletPage, error)
+	GetWalletByID(id int) (Wallet, error)
+	// GetWalletOwner returns the user id of the wallet, soft-deleted or not.
+	GetWalletOwner(id int) (int, error)
 	CreateWallet(wallet *Wallet) error
+	// UpdateWallet and PatchWallet change the wallet only while it is at the
+	// expected version, and return ErrVersionMismatch otherwise.
 	UpdateWallet(wallet *Wallet) error
-	DeleteWallet(userID int) error
+	PatchWallet(id, version int, patch WalletPatch) (Wallet, error)
+	// DeleteWallet soft-deletes the wallet at the expected version, the stores
+	// leave deleted wallets out of every read and balance change until restored.
+	// It returns ErrWalletFrozen when CheckDeletable forbids it.
+	DeleteWallet(id, version int) error
+	// DeleteUserWallets soft-deletes every wallet of the user, or none of them
+	// and returns ErrWalletFrozen when one of them is frozen.
+	DeleteUserWallets(userID int) error
+	// RestoreWallet brings back a soft-deleted wallet with the status it had,
+	// a live wallet is returned as it is.
+	RestoreWallet(id int) (Wallet, error)
+	// ChangeWalletStatus moves the wallet at the expected version to status, recording
+	// the reason, and returns the error of CheckStatusChange when it forbids the change.
+	ChangeWalletStatus(id, version int, status, reason string) (Wallet, error)
+	Transfer(fromID, toID int, amount Money) (TransferResult, error)
+	Convert(fromID, toID int, amount Money, rates FXRateProvider) (Conversion, error)
+	GetTransactions(walletID int) ([]Transaction, error)
+	Deposit(walletID int, amount Money) (Wallet, error)
+	Withdraw(walletID int, amount Money) (Wallet, error)
 }
 
-func New(db Storer) *Handler {
-	return &Handler{store: db}
-}
-
-type Err struct {
-	Message string `json:"message"`
+func New(db Storer, rates FXRateProvider) *Handler {
+	return &Handler{store: db, rates: rates}
 }
 
 // GetWalletsHandler
 //
 //		@Summary		Get all wallets
-//		@Description	Get all wallets
+//		@Description	Get all wallets, a page at a time
 //		@Tags			wallet
 //		@Produce		json
-//	    @Param			wallet_type     query       string false "Filter by wallet type"
-//		@Success		200	            {array}	    Wallet
-//		@Failure		500	            {object}	Err
+//	    @Param			wallet_type     query       []string false "Filter by wallet types, repeated or comma-separated" collectionFormat(multi)
+//	    @Param			currency        query       string false "Filter by currency code"
+//	    @Param			min_balance     query       number false "Balance at least"
+//	    @Param			max_balance     query       number false "Balance at most"
+//	    @Param			created_after   query       string false "Created after, RFC 3339 time or YYYY-MM-DD"
+//	    @Param			created_before  query       string false "Created before, RFC 3339 time or YYYY-MM-DD"
+//	    @Param			wallet_name     query       string false "Wallet name starts with, ignoring case"
+//	    @Param			user_name       query       string false "User name contains, ignoring case"
+//	    @Param			include_deleted query       bool   false "Also list soft-deleted wallets, admin only"
+//	    @Param			limit           query       int    false "Page size, 1 to 500" default(50)
+//	    @Param			sort            query       string false "Sort by" Enums(id, balance, created_at, wallet_name) default(id)
+//	    @Param			order           query       string false "Sort order" Enums(asc, desc) default(asc)
+//	    @Param			cursor          query       string false "next_cursor of the previous page"
+//		@Success		200	            {object}    WalletPage
+//		@Failure		400	            {object}	Problem
+//		@Failure		403	            {object}	Problem
+//		@Failure		500	            {object}	Problem
 //		@Router			/api/v1/wallets [get]
+//		@Router			/api/v1/admin/wallets [get]
 func (h *Handler) GetWalletsHandler(c echo.Context) error {
-	filter := Wallet{}
+	// prepare page
+	page, err := ParsePage(c)
+	if err != nil {
+		return err
+	}
 
-	// prepare filter: wallet_type
-	if walletType := c.QueryParam("wallet_type"); walletType != "" {
-		filter.WalletType = walletType
-		if !IsWalletTypeValid(walletType) {
-			return c.JSON(http.StatusOK, []Wallet{})
-		}
+	// prepare filter
+	filter, err := ParseFilter(c)
+	if err != nil {
+		return err
+	}
+	if filter, err = h.ownFilter(c, filter); err != nil {
+		return err
 	}
 
 	// get wallets
-	wallets, err := h.store.GetWallets(filter)
+	wallets, err := h.store.GetWallets(filter, page)
 	if err != nil {
-		log.Printf("error: %v\n", err)
-		return c.JSON(http.StatusInternalServerError, Err{Message: "error getting wallets"})
+		return err
 	}
 
 	return c.JSON(http.StatusOK, wallets)
 }
 
+// GetWalletHandler
+//
+//	@Summary		Get wallet
+//	@Description	Get a single wallet by its id
+//	@Tags			wallet
+//	@Produce		json
+//	@Param			id	path		int	true	"Wallet ID"
+//	@Success		200	{object}	Wallet
+//	@Header			200	{string}	ETag	"Version of the wallet, for If-Match"
+//	@Failure		400	{object}	Problem
+//	@Failure		403	{object}	Problem
+//	@Failure		404	{object}	Problem
+//	@Failure		500	{object}	Problem
+//	@Router			/api/v1/wallets/{id} [get]
+//	@Router			/api/v1/admin/wallets/{id} [get]
+func (h *Handler) GetWalletHandler(c echo.Context) error {
+	// parse wallet id
+	walletID, err := h.ownedWalletID(c)
+	if err != nil {
+		return err
+	}
+
+	// get wallet
+	wallet, err := h.store.GetWalletByID(walletID)
+	if err != nil {
+		return err
+	}
+
+	setETag(c, wallet)
+	return c.JSON(http.StatusOK, wallet)
+}
+
 // CreateWalletHandler
 //
 //	@Summary		Create wallet
@@ -69,22 +138,42 @@ func (h *Handler) GetWalletsHandler(c echo.Context) error {
 //	@Accept			json
 //	@Produce		json
 //	@Param			wallet	body	WalletForCreate	true	"Wallet object"
+//	@Param			Idempotency-Key	header	string	false	"Key to run a retried request only once"
 //	@Success		201	{object}	Wallet
-//	@Failure		400	{object}	Err
-//	@Failure		500	{object}	Err
+//	@Failure		400	{object}	Problem
+//	@Failure		403	{object}	Problem
+//	@Failure		409	{object}	Problem
+//	@Failure		422	{object}	Problem
+//	@Failure		500	{object}	Problem
 //	@Router			/api/v1/wallets [post]
 func (h *Handler) CreateWalletHandler(c echo.Context) error {
-	// bind request body to wallet
-	wallet := Wallet{}
-	if err := c.Bind(&wallet); err != nil {
-		log.Printf("error: %v\n", err)
-		return c.JSON(http.StatusBadRequest, Err{Message: "invalid request"})
+	// bind request body to wallet, only the fields a client may choose
+	body := WalletForCreate{}
+	if err := c.Bind(&body); err != nil {
+		return err
+	}
+	wallet := Wallet{UserID: body.UserID, WalletName: body.WalletName, WalletType: body.WalletType, Currency: body.Currency, Balance: body.Balance}
+
+	// validate wallet
+	if wallet.Currency == "" {
+		wallet.Currency = DefaultCurrency
+	}
+	wallet.Currency = strings.ToUpper(wallet.Currency)
+	if err := ValidateNewWallet(wallet); err != nil {
+		return err
+	}
+	if err := h.authorizeUser(c, wallet.UserID); err != nil {
+		return err
 	}
 
 	// create wallet
-	if err := h.store.CreateWallet(&wallet); err != nil {
-		log.Printf("error: %v\n", err)
-		return c.JSON(http.StatusInternalServerError, Err{Message: "error creating wallet"})
+	err := h.store.CreateWallet(&wallet)
+	if errors.Is(err, ErrUserNotFound) {
+		// the user is part of the body, not of the path
+		return WithStatus(http.StatusUnprocessableEntity, err)
+	}
+	if err != nil {
+		return err
 	}
 
 	return c.JSON(http.StatusCreated, wallet)
@@ -98,24 +187,48 @@ func (h *Handler) CreateWalletHandler(c echo.Context) error {
 //	@Accept			json
 //	@Produce		json
 //	@Param			wallet	body	WalletForUpdate	true	"Wallet object"
+//	@Param			If-Match	header	string	true	"ETag of the wallet from a previous read"
+//	@Param			Idempotency-Key	header	string	false	"Key to run a retried request only once"
 //	@Success		200	{object}	Wallet
-//	@Failure		400	{object}	Err
-//	@Failure		500	{object}	Err
-//	@Router			/api/v1/wallets [put]
+//	@Header			200	{string}	ETag	"New version of the wallet"
+//	@Failure		400	{object}	Problem
+//	@Failure		403	{object}	Problem
+//	@Failure		404	{object}	Problem
+//	@Failure		412	{object}	Problem
+//	@Failure		422	{object}	Problem
+//	@Failure		428	{object}	Problem
+//	@Failure		500	{object}	Problem
+//	@Router			/api/v1/admin/wallets [put]
 func (h *Handler) UpdateWalletHandler(c echo.Context) error {
 	// bind request body to wallet
-	wallet := Wallet{}
-	if err := c.Bind(&wallet); err != nil {
-		log.Printf("error: %v\n", err)
-		return c.JSON(http.StatusBadRequest, Err{Message: "invalid request"})
+	body := WalletForUpdate{}
+	if err := c.Bind(&body); err != nil {
+		return err
+	}
+
+	// validate wallet
+	if err := ValidateWalletUpdate(body); err != nil {
+		return err
+	}
+	wallet := Wallet{ID: body.ID, Balance: *body.Balance}
+	if err := h.authorizeWallet(c, wallet.ID); err != nil {
+		return err
+	}
+
+	// the version comes from the If-Match header, not from the body
+	version, err := IfMatchVersion(c)
+	if err != nil {
+		return err
 	}
+	wallet.Version = version
 
 	// update wallet
-	if err := h.store.UpdateWallet(&wallet); err != nil {
-		log.Printf("error: %v\n", err)
-		return c.JSON(http.StatusInternalServerError, Err{Message: "error updating wallet"})
+	err = h.store.UpdateWallet(&wallet)
+	if err != nil {
+		return err
 	}
 
+	setETag(c, wallet)
 	return c.JSON(http.StatusOK, wallet)
 }
 
@@ -126,27 +239,32 @@ func (h *Handler) UpdateWalletHandler(c echo.Context) error {
 // @Tags		user wallet
 // @Produce		json
 // @Param		id      path        int true "User ID"
-// @Success		200     {array}	    Wallet
-// @Failure		400	    {object}	Err
-// @Failure		500	    {object}	Err
+// @Param		limit   query       int    false "Page size, 1 to 500" default(50)
+// @Param		sort    query       string false "Sort by" Enums(id, balance, created_at, wallet_name) default(id)
+// @Param		order   query       string false "Sort order" Enums(asc, desc) default(asc)
+// @Param		cursor  query       string false "next_cursor of the previous page"
+// @Success		200     {object}    WalletPage
+// @Failure		400	    {object}	Problem
+// @Failure		403	    {object}	Problem
+// @Failure		500	    {object}	Problem
 // @Router		/api/v1/users/{id}/wallets [get]
+// @Router		/api/v1/admin/users/{id}/wallets [get]
 func (h *Handler) GetUserWalletHandler(c echo.Context) error {
-	filter := Wallet{}
+	filter := Filter{}
 
 	// prepare filter: user_id
-	var userID int
-	var err error
-	strUserID := c.Param("id") // ⚠️ I found a problem here in the unit test.
-	if strUserID == "" {
-		log.Printf("error: user_id is ''\n")
-		return c.JSON(http.StatusBadRequest, Err{Message: "user_id is required"})
-	}
-	if userID, err = strconv.Atoi(strUserID); err != nil {
-		log.Printf("error: %v\n", err)
-		return c.JSON(http.StatusBadRequest, Err{Message: "invalid user_id"})
+	userID, err := h.ownedUserID(c)
+	if err != nil {
+		return err
 	}
 	filter.UserID = userID
 
+	// prepare page
+	page, err := ParsePage(c)
+	if err != nil {
+		return err
+	}
+
 	// prepare filter: wallet_type
 	//if walletType := c.QueryParam("wallet_type"); walletType != "" {
 	//	filter.WalletType = walletType
@@ -156,10 +274,9 @@ func (h *Handler) GetUserWalletHandler(c echo.Context) error {
 	//}
 
 	// get wallets
-	wallets, err := h.store.GetWallets(filter)
+	wallets, err := h.store.GetWallets(filter, page)
 	if err != nil {
-		log.Printf("error: %v\n", err)
-		return c.JSON(http.StatusInternalServerError, Err{Message: "error getting wallets"})
+		return err
 	}
 
 	return c.JSON(http.StatusOK, wallets)
@@ -168,25 +285,96 @@ func (h *Handler) GetUserWalletHandler(c echo.Context) error {
 //	DeleteUserWalletHandler
 //
 // @Summary		Delete wallet for the user
-// @Description	Delete wallet for the user
+// @Description	Soft-delete every wallet of the user, none of them while one is frozen
 // @Tags		user wallet
 // @Produce		json
 // @Param		id      path        int true "User ID"
 // @Success		204
-// @Failure		400	    {object}	Err
-// @Failure		500	    {object}	Err
-// @Router		/api/v1/user/{id}/wallets [delete]
+// @Failure		400	    {object}	Problem
+// @Failure		403	    {object}	Problem
+// @Failure		422	    {object}	Problem
+// @Failure		500	    {object}	Problem
+// @Router		/api/v1/admin/users/{id}/wallets [delete]
 func (h *Handler) DeleteUserWalletHandler(c echo.Context) error {
 	// parse user id
-	userID, err := ParseUserID(c)
+	userID, err := h.ownedUserID(c)
+	if err != nil {
+		return err
+	}
+
+	// delete wallet
+	if err = h.store.DeleteUserWallets(userID); err != nil {
+		return err
+	}
+	return c.NoContent(http.StatusNoContent)
+}
+
+// DeleteWalletHandler
+//
+//	@Summary		Delete wallet
+//	@Description	Soft-delete a single wallet that is not frozen, it can be brought back with restore
+//	@Tags			wallet
+//	@Produce		json
+//	@Param			id			path	int		true	"Wallet ID"
+//	@Param			If-Match	header	string	true	"ETag of the wallet from a previous read"
+//	@Success		204
+//	@Failure		400	{object}	Problem
+//	@Failure		403	{object}	Problem
+//	@Failure		404	{object}	Problem
+//	@Failure		412	{object}	Problem
+//	@Failure		422	{object}	Problem
+//	@Failure		428	{object}	Problem
+//	@Failure		500	{object}	Problem
+//	@Router			/api/v1/wallets/{id} [delete]
+func (h *Handler) DeleteWalletHandler(c echo.Context) error {
+	// parse wallet id
+	walletID, err := h.ownedWalletID(c)
 	if err != nil {
-		return c.JSON(http.StatusBadRequest, Err{Message: err.Error()})
+		return err
+	}
+
+	version, err := IfMatchVersion(c)
+	if err != nil {
+		return err
 	}
 
 	// delete wallet
-	if err = h.store.DeleteWallet(userID); err != nil {
-		log.Printf("error: %v\n", err)
-		return c.JSON(http.StatusInternalServerError, Err{Message: "error deleting wallet"})
+	if err = h.store.DeleteWallet(walletID, version); err != nil {
+		return err
 	}
 	return c.NoContent(http.StatusNoContent)
 }
+
+// RestoreWalletHandler
+//
+//	@Summary		Restore wallet
+//	@Description	Bring back a soft-deleted wallet with the status it had
+//	@Tags			wallet
+//	@Produce		json
+//	@Param			id	path	int	true	"Wallet ID"
+//	@Param			Idempotency-Key	header	string	false	"Key to run a retried request only once"
+//	@Success		200	{object}	Wallet
+//	@Header			200	{string}	ETag	"New version of the wallet"
+//	@Failure		400	{object}	Problem
+//	@Failure		403	{object}	Problem
+//	@Failure		404	{object}	Problem
+//	@Failure		409	{object}	Problem
+//	@Failure		500	{object}	Problem
+//	@Router			/api/v1/wallets/{id}/restore [post]
+//	@Router			/api/v1/admin/wallets/{id}/restore [post]
+func (h *Handler) RestoreWalletHandler(c echo.Context) error {
+	// parse wallet id
+	walletID, err := h.ownedWalletID(c)
+	if err != nil {
+		return err
+	}
+
+	// restore wallet
+	wallet, err := h.store.RestoreWallet(walletID)
+	if err != nil {
+		return err
+	}
+
+	setETag(c, wallet)
+	return c.JSON(http.StatusOK, wallet)
+}
@@ -0,0 +1,204 @@
+package wallet
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"github.com/labstack/echo/v4"
+	"io"
+	"log"
+	"net/http"
+	"time"
+)
+
+const (
+	HeaderIdempotencyKey     = "Idempotency-Key"
+	HeaderIdempotentReplayed = "Idempotent-Replayed"
+
+	// DefaultIdempotencyTTL is how long a response is kept for replays.
+	DefaultIdempotencyTTL   = 24 * time.Hour
+	MaxIdempotencyKeyLength = 255
+)
+
+var (
+	// ErrIdempotencyKeyInUse is returned by ReserveIdempotencyKey when the key has not expired yet.
+	ErrIdempotencyKeyInUse    = errors.New("idempotency key in use")
+	ErrIdempotencyKeyNotFound = errors.New("idempotency key not found")
+	ErrIdempotencyKeyReused   = errors.New("idempotency key was used for a different request")
+	ErrRequestInProgress      = errors.New("a request with this idempotency key is in progress")
+)
+
+// IdempotencyRecord is the response stored under an Idempotency-Key,
+// StatusCode is 0 while the first request is still running.
+type IdempotencyRecord struct {
+	// Key is the Idempotency-Key scoped to the caller, see scopedIdempotencyKey.
+	Key         string
+	RequestHash string
+	StatusCode  int
+	ContentType string
+	ETag        string
+	Body        []byte
+	CreatedAt   time.Time
+	ExpiresAt   time.Time
+}
+
+// Completed reports whether the response of the first request was stored.
+func (r IdempotencyRecord) Completed() bool {
+	return r.StatusCode != 0
+}
+
+type IdempotencyStorer interface {
+	// ReserveIdempotencyKey stores the record as in progress, replacing an expired one,
+	// and returns ErrIdempotencyKeyInUse when the key is still live.
+	// Every record expired at the CreatedAt of the new one is purged.
+	ReserveIdempotencyKey(record IdempotencyRecord) error
+	GetIdempotencyKey(key string) (IdempotencyRecord, error)
+	// CompleteIdempotencyKey stores the status code, content type, ETag and body of the record.
+	CompleteIdempotencyKey(record IdempotencyRecord) error
+	DeleteIdempotencyKey(key string) error
+}
+
+// RequestHash identifies a request by its method, path and body.
+func RequestHash(method, path string, body []byte) string {
+	h := sha256.New()
+	fmt.Fprintf(h, "%s %s\n", method, path)
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// scopedIdempotencyKey is the key a response is stored under: the Idempotency-Key of one caller,
+// so a caller sending the key of another never gets the other's response replayed.
+// The subject of an API key caller names the key.
+func scopedIdempotencyKey(subject, key string) string {
+	h := sha256.Sum256([]byte(subject + "\x00" + key))
+	return hex.EncodeToString(h[:])
+}
+
+// Idempotency runs a request carrying an Idempotency-Key header once: a retry with the
+// same key and body gets the stored response back, a different body under the same key
+// gets 422. Server errors, conflicts and failed preconditions are not stored, so the client
+// can retry them, after fixing its If-Match for instance.
+// Keys are kept per caller, the middleware runs after authentication.
+// Requests without the header are passed through.
+func Idempotency(store IdempotencyStorer, ttl time.Duration) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			key := c.Request().Header.Get(HeaderIdempotencyKey)
+			if key == "" {
+				return next(c)
+			}
+			v := validator{}
+			v.check(len(key) <= MaxIdempotencyKeyLength, HeaderIdempotencyKey, fmt.Sprintf("must be at most %d characters", MaxIdempotencyKeyLength))
+			if err := v.err(); err != nil {
+				return err
+			}
+
+			// read the body for the hash and put it back for the handler
+			body, err := io.ReadAll(c.Request().Body)
+			if err != nil {
+				return fmt.Errorf("%w: %v", ErrInvalidRequest, err)
+			}
+			c.Request().Body = io.NopCloser(bytes.NewReader(body))
+
+			now := time.Now().UTC()
+			record := IdempotencyRecord{
+				Key:         scopedIdempotencyKey(Subject(c), key),
+				RequestHash: RequestHash(c.Request().Method, c.Request().URL.Path, body),
+				CreatedAt:   now,
+				ExpiresAt:   now.Add(ttl),
+			}
+
+			err = store.ReserveIdempotencyKey(record)
+			if errors.Is(err, ErrIdempotencyKeyInUse) {
+				return replay(c, store, record)
+			}
+			if err != nil {
+				return err
+			}
+
+			return runOnce(c, next, store, record)
+		}
+	}
+}
+
+func replay(c echo.Context, store IdempotencyStorer, record IdempotencyRecord) error {
+	stored, err := store.GetIdempotencyKey(record.Key)
+	if errors.Is(err, ErrIdempotencyKeyNotFound) {
+		// released by a failed first request in the meantime
+		return ErrRequestInProgress
+	}
+	if err != nil {
+		return err
+	}
+
+	if stored.RequestHash != record.RequestHash {
+		return ErrIdempotencyKeyReused
+	}
+	if !stored.Completed() {
+		return ErrRequestInProgress
+	}
+
+	if stored.ETag != "" {
+		c.Response().Header().Set(HeaderETag, stored.ETag)
+	}
+	c.Response().Header().Set(HeaderIdempotentReplayed, "true")
+	return c.Blob(stored.StatusCode, stored.ContentType, stored.Body)
+}
+
+// runOnce runs the handler with the key reserved and stores its response.
+func runOnce(c echo.Context, next echo.HandlerFunc, store IdempotencyStorer, record IdempotencyRecord) error {
+	completed := false
+	defer func() {
+		// a server error or a panic leaves the request free to be retried
+		if !completed {
+			if err := store.DeleteIdempotencyKey(record.Key); err != nil {
+				log.Printf("error: %v\n", err)
+			}
+		}
+	}()
+
+	writer := &recordingWriter{ResponseWriter: c.Response().Writer}
+	c.Response().Writer = writer
+
+	// answer the error here, so the response can be stored
+	if err := next(c); err != nil {
+		c.Error(err)
+	}
+
+	if !replayable(c.Response().Status) {
+		return nil
+	}
+	record.StatusCode = c.Response().Status
+	record.ContentType = c.Response().Header().Get(echo.HeaderContentType)
+	record.ETag = c.Response().Header().Get(HeaderETag)
+	record.Body = writer.body.Bytes()
+	if err := store.CompleteIdempotencyKey(record); err != nil {
+		log.Printf("error: %v\n", err)
+		return nil
+	}
+	completed = true
+	return nil
+}
+
+// replayable reports whether a response with status is stored for retries. The answer to
+// a stale If-Match or a concurrent change would be stale itself on a retry.
+func replayable(status int) bool {
+	switch status {
+	case http.StatusConflict, http.StatusPreconditionFailed, http.StatusPreconditionRequired:
+		return false
+	}
+	return status < http.StatusInternalServerError
+}
+
+// recordingWriter keeps a copy of the response body.
+type recordingWriter struct {
+	http.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *recordingWriter) Write(b []byte) (int, error) {
+	w.body.Write(b)
+	return w.ResponseWriter.Write(b)
+}
@@ -0,0 +1,229 @@
+package wallet
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golfz/fun-exercise-api/wallet/authtest"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+type mockIdempotencyStorer struct {
+	records map[string]IdempotencyRecord
+}
+
+func (m *mockIdempotencyStorer) ReserveIdempotencyKey(record IdempotencyRecord) error {
+	if stored, ok := m.records[record.Key]; ok && stored.ExpiresAt.After(record.CreatedAt) {
+		return ErrIdempotencyKeyInUse
+	}
+	m.records[record.Key] = record
+	return nil
+}
+
+func (m *mockIdempotencyStorer) GetIdempotencyKey(key string) (IdempotencyRecord, error) {
+	record, ok := m.records[key]
+	if !ok {
+		return IdempotencyRecord{}, ErrIdempotencyKeyNotFound
+	}
+	return record, nil
+}
+
+func (m *mockIdempotencyStorer) CompleteIdempotencyKey(record IdempotencyRecord) error {
+	m.records[record.Key] = record
+	return nil
+}
+
+func (m *mockIdempotencyStorer) DeleteIdempotencyKey(key string) error {
+	delete(m.records, key)
+	return nil
+}
+
+// idempotencySetup serves handler behind the Idempotency middleware, after the auth middleware
+// when given, calls counts how many times the handler ran.
+func idempotencySetup(handler echo.HandlerFunc, auth ...echo.MiddlewareFunc) (e *echo.Echo, store *mockIdempotencyStorer, calls *int) {
+	e = echo.New()
+	e.HTTPErrorHandler = ProblemHandler
+	store = &mockIdempotencyStorer{records: make(map[string]IdempotencyRecord)}
+	calls = new(int)
+	e.POST("/api/v1/wallets", func(c echo.Context) error {
+		*calls++
+		return handler(c)
+	}, append(auth, Idempotency(store, time.Hour))...)
+	return e, store, calls
+}
+
+func postWallet(e *echo.Echo, key, body string) *httptest.ResponseRecorder {
+	return postWalletAs(e, "", key, body)
+}
+
+// postWalletAs posts the wallet with authorization when not empty.
+func postWalletAs(e *echo.Echo, authorization, key, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/wallets", strings.NewReader(body))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	if authorization != "" {
+		req.Header.Set(echo.HeaderAuthorization, authorization)
+	}
+	if key != "" {
+		req.Header.Set(HeaderIdempotencyKey, key)
+	}
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestIdempotency(t *testing.T) {
+	created := func(c echo.Context) error {
+		return c.JSON(http.StatusCreated, Wallet{ID: 7, WalletName: "John's Wallet"})
+	}
+
+	t.Run("given a retry with the same key and body should replay the response", func(t *testing.T) {
+		e, _, calls := idempotencySetup(created)
+
+		first := postWallet(e, "key-1", `{"wallet_name": "John's Wallet"}`)
+		retry := postWallet(e, "key-1", `{"wallet_name": "John's Wallet"}`)
+
+		assert.Equal(t, 1, *calls)
+		assert.Equal(t, http.StatusCreated, retry.Code)
+		assert.Equal(t, first.Body.String(), retry.Body.String())
+		assert.Equal(t, first.Header().Get(echo.HeaderContentType), retry.Header().Get(echo.HeaderContentType))
+		assert.Equal(t, "true", retry.Header().Get(HeaderIdempotentReplayed))
+		assert.Empty(t, first.Header().Get(HeaderIdempotentReplayed))
+	})
+
+	t.Run("given a retry of a response with an ETag should replay the ETag", func(t *testing.T) {
+		e, _, calls := idempotencySetup(func(c echo.Context) error {
+			w := Wallet{ID: 7, WalletName: "John's Wallet", Version: 1}
+			setETag(c, w)
+			return c.JSON(http.StatusCreated, w)
+		})
+
+		first := postWallet(e, "key-1", `{"wallet_name": "John's Wallet"}`)
+		retry := postWallet(e, "key-1", `{"wallet_name": "John's Wallet"}`)
+
+		assert.Equal(t, 1, *calls)
+		assert.NotEmpty(t, first.Header().Get(HeaderETag))
+		assert.Equal(t, first.Header().Get(HeaderETag), retry.Header().Get(HeaderETag))
+		assert.Equal(t, "true", retry.Header().Get(HeaderIdempotentReplayed))
+	})
+
+	t.Run("given another caller with the same key and body should run the request for them", func(t *testing.T) {
+		e, store, calls := idempotencySetup(func(c echo.Context) error {
+			return c.JSON(http.StatusCreated, Wallet{ID: 7, UserName: Subject(c)})
+		}, JWTAuth(authtest.Keys()))
+		body := `{"wallet_name": "Savings"}`
+
+		john := postWalletAs(e, authtest.Bearer(t, "1"), "key-1", body)
+		jane := postWalletAs(e, authtest.Bearer(t, "2"), "key-1", body)
+
+		assert.Equal(t, 2, *calls)
+		assert.Equal(t, http.StatusCreated, jane.Code)
+		assert.Contains(t, john.Body.String(), `"user_name":"1"`)
+		assert.Contains(t, jane.Body.String(), `"user_name":"2"`)
+		assert.Empty(t, jane.Header().Get(HeaderIdempotentReplayed))
+		assert.Len(t, store.records, 2)
+	})
+
+	t.Run("given the same key with another body should return 422", func(t *testing.T) {
+		e, _, calls := idempotencySetup(created)
+
+		postWallet(e, "key-1", `{"wallet_name": "John's Wallet"}`)
+		reused := postWallet(e, "key-1", `{"wallet_name": "Jane's Wallet"}`)
+
+		assert.Equal(t, 1, *calls)
+		assert.Equal(t, http.StatusUnprocessableEntity, reused.Code)
+		assert.Contains(t, reused.Body.String(), CodeIdempotencyKeyReused)
+	})
+
+	t.Run("given the first request is still running should return 409", func(t *testing.T) {
+		e, store, calls := idempotencySetup(created)
+		body := `{"wallet_name": "John's Wallet"}`
+		now := time.Now().UTC()
+		key := scopedIdempotencyKey("", "key-1")
+		store.records[key] = IdempotencyRecord{Key: key, RequestHash: RequestHash(http.MethodPost, "/api/v1/wallets", []byte(body)), CreatedAt: now, ExpiresAt: now.Add(time.Hour)}
+
+		resp := postWallet(e, "key-1", body)
+
+		assert.Equal(t, 0, *calls)
+		assert.Equal(t, http.StatusConflict, resp.Code)
+		assert.Contains(t, resp.Body.String(), CodeRequestInProgress)
+	})
+
+	t.Run("given a client error should replay the error", func(t *testing.T) {
+		e, _, calls := idempotencySetup(func(c echo.Context) error {
+			return ErrInsufficientFunds
+		})
+
+		postWallet(e, "key-1", `{}`)
+		retry := postWallet(e, "key-1", `{}`)
+
+		assert.Equal(t, 1, *calls)
+		assert.Equal(t, http.StatusUnprocessableEntity, retry.Code)
+		assert.Equal(t, MIMEApplicationProblemJSON, retry.Header().Get(echo.HeaderContentType))
+		assert.Contains(t, retry.Body.String(), CodeInsufficientFunds)
+	})
+
+	t.Run("given a server error should let the request be retried", func(t *testing.T) {
+		e, store, calls := idempotencySetup(func(c echo.Context) error {
+			return errors.New("connection refused")
+		})
+
+		first := postWallet(e, "key-1", `{}`)
+		postWallet(e, "key-1", `{}`)
+
+		assert.Equal(t, http.StatusInternalServerError, first.Code)
+		assert.Equal(t, 2, *calls)
+		assert.Empty(t, store.records)
+	})
+
+	for _, err := range []error{ErrVersionMismatch, ErrPreconditionRequired, ErrConflict} {
+		t.Run("given "+err.Error()+" should let the request be retried", func(t *testing.T) {
+			e, store, calls := idempotencySetup(func(c echo.Context) error {
+				return err
+			})
+
+			postWallet(e, "key-1", `{}`)
+			postWallet(e, "key-1", `{}`)
+
+			assert.Equal(t, 2, *calls)
+			assert.Empty(t, store.records)
+		})
+	}
+
+	t.Run("given no key should run every request", func(t *testing.T) {
+		e, store, calls := idempotencySetup(created)
+
+		postWallet(e, "", `{}`)
+		postWallet(e, "", `{}`)
+
+		assert.Equal(t, 2, *calls)
+		assert.Empty(t, store.records)
+	})
+
+	t.Run("given a key too long should return 400", func(t *testing.T) {
+		e, _, calls := idempotencySetup(created)
+
+		resp := postWallet(e, strings.Repeat("k", MaxIdempotencyKeyLength+1), `{}`)
+
+		assert.Equal(t, 0, *calls)
+		assert.Equal(t, http.StatusBadRequest, resp.Code)
+	})
+
+	t.Run("given the handler reads the body should see it in full", func(t *testing.T) {
+		var got Wallet
+		e, _, _ := idempotencySetup(func(c echo.Context) error {
+			if err := c.Bind(&got); err != nil {
+				return err
+			}
+			return c.NoContent(http.StatusNoContent)
+		})
+
+		postWallet(e, "key-1", `{"wallet_name": "John's Wallet"}`)
+
+		assert.Equal(t, "John's Wallet", got.WalletName)
+	})
+}
@@ -0,0 +1,232 @@
+package wallet
+
+import (
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// Money is an exact decimal amount kept as an integer count of
+// 1/100000000 units, so balances never go through float64.
+// It is serialised to JSON as a number with its exact decimal text.
+type Money int64
+
+// MoneyScale is the number of decimal places Money can hold.
+const MoneyScale = 8
+
+const moneyUnit = 100000000
+
+// MaxMoney is the largest amount either way Money may hold, 9999999999.99999999,
+// the most a DECIMAL(18, 8) column keeps, so every store has the same limit.
+const MaxMoney Money = 999999999999999999
+
+var ErrInvalidMoney = errors.New("invalid money amount")
+
+// ParseMoney parses a decimal string such as "100", "-5.25" or "0.00000001".
+func ParseMoney(s string) (Money, error) {
+	text := strings.TrimSpace(s)
+	negative := strings.HasPrefix(text, "-")
+	text = strings.TrimPrefix(strings.TrimPrefix(text, "-"), "+")
+
+	whole, frac, _ := strings.Cut(text, ".")
+	if whole == "" && frac == "" || !isDigits(whole) || !isDigits(frac) {
+		return 0, fmt.Errorf("%w: %q", ErrInvalidMoney, s)
+	}
+	if len(frac) > MoneyScale {
+		return 0, fmt.Errorf("%w: %q has more than %d decimal places", ErrInvalidMoney, s, MoneyScale)
+	}
+
+	units, err := strconv.ParseUint("0"+whole+frac+strings.Repeat("0", MoneyScale-len(frac)), 10, 64)
+	if err != nil || units > uint64(MaxMoney) {
+		return 0, fmt.Errorf("%w: %q is out of range", ErrInvalidMoney, s)
+	}
+
+	if negative {
+		return -Money(units), nil
+	}
+	return Money(units), nil
+}
+
+// MustParseMoney is like ParseMoney but panics if s is not a valid amount.
+func MustParseMoney(s string) Money {
+	m, err := ParseMoney(s)
+	if err != nil {
+		panic(err)
+	}
+	return m
+}
+
+func isDigits(s string) bool {
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// String formats m with at least two and at most MoneyScale decimal places.
+func (m Money) String() string {
+	sign, units := "", m.units()
+	if m < 0 {
+		sign = "-"
+	}
+
+	frac := strings.TrimRight(fmt.Sprintf("%0*d", MoneyScale, units%moneyUnit), "0")
+	for len(frac) < 2 {
+		frac += "0"
+	}
+
+	return fmt.Sprintf("%s%d.%s", sign, units/moneyUnit, frac)
+}
+
+// units is the magnitude of m, negated in uint64 so math.MinInt64 does not overflow.
+func (m Money) units() uint64 {
+	if m < 0 {
+		return -uint64(m)
+	}
+	return uint64(m)
+}
+
+// Decimals returns the number of decimal places needed to write m exactly.
+func (m Money) Decimals() int {
+	units := m.units() % moneyUnit
+	decimals := MoneyScale
+	for decimals > 0 && units%10 == 0 {
+		units /= 10
+		decimals--
+	}
+	return decimals
+}
+
+// Add returns m + other, or ErrInvalidMoney when the sum is beyond MaxMoney either way.
+func (m Money) Add(other Money) (Money, error) {
+	sum := m + other
+	// an int64 that wrapped has the opposite sign of both operands
+	wrapped := m > 0 && other > 0 && sum < 0 || m < 0 && other < 0 && sum >= 0
+	if wrapped || sum > MaxMoney || sum < -MaxMoney {
+		return 0, fmt.Errorf("%w: %v + %v is out of range", ErrInvalidMoney, m, other)
+	}
+	return sum, nil
+}
+
+// Sub returns m - other, or ErrInvalidMoney when the difference is beyond MaxMoney either way.
+func (m Money) Sub(other Money) (Money, error) {
+	diff := m - other
+	wrapped := m >= 0 && other < 0 && diff < 0 || m < 0 && other > 0 && diff >= 0
+	if wrapped || diff > MaxMoney || diff < -MaxMoney {
+		return 0, fmt.Errorf("%w: %v - %v is out of range", ErrInvalidMoney, m, other)
+	}
+	return diff, nil
+}
+
+func (m Money) Neg() Money {
+	return -m
+}
+
+func (m Money) Abs() Money {
+	if m < 0 {
+		return -m
+	}
+	return m
+}
+
+// Convert multiplies m by rate and rounds half away from zero to the given
+// number of decimal places.
+func (m Money) Convert(rate *big.Rat, decimals int) (Money, error) {
+	minorUnit := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(decimals)), nil)
+
+	value := new(big.Rat).SetFrac(big.NewInt(int64(m)), big.NewInt(moneyUnit))
+	value.Mul(value, rate)
+	value.Mul(value, new(big.Rat).SetInt(minorUnit))
+
+	// round the count of minor units half away from zero
+	numerator := new(big.Int).Abs(value.Num())
+	quotient, remainder := new(big.Int).QuoRem(numerator, value.Denom(), new(big.Int))
+	if remainder.Lsh(remainder, 1).Cmp(value.Denom()) >= 0 {
+		quotient.Add(quotient, big.NewInt(1))
+	}
+	if value.Sign() < 0 {
+		quotient.Neg(quotient)
+	}
+
+	units := quotient.Mul(quotient, new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(MoneyScale-decimals)), nil))
+	if !units.IsInt64() || new(big.Int).Abs(units).Int64() > int64(MaxMoney) {
+		return 0, fmt.Errorf("%w: converted amount is out of range", ErrInvalidMoney)
+	}
+
+	return Money(units.Int64()), nil
+}
+
+func (m Money) IsZero() bool {
+	return m == 0
+}
+
+func (m Money) IsPositive() bool {
+	return m > 0
+}
+
+func (m Money) IsNegative() bool {
+	return m < 0
+}
+
+func (m Money) MarshalJSON() ([]byte, error) {
+	return []byte(m.String()), nil
+}
+
+// UnmarshalJSON accepts both JSON numbers and strings, e.g. 100.5 or "100.5".
+func (m *Money) UnmarshalJSON(data []byte) error {
+	if string(data) == "null" {
+		return nil
+	}
+
+	text := string(data)
+	if strings.HasPrefix(text, `"`) {
+		unquoted, err := strconv.Unquote(text)
+		if err != nil {
+			return fmt.Errorf("%w: %s", ErrInvalidMoney, data)
+		}
+		text = unquoted
+	}
+
+	parsed, err := ParseMoney(text)
+	if err != nil {
+		return err
+	}
+
+	*m = parsed
+	return nil
+}
+
+// Scan implements sql.Scanner for DECIMAL columns, an integer is a whole amount.
+// Columns counting 1/100000000 units are scanned through the store's dialect instead.
+func (m *Money) Scan(src interface{}) error {
+	var err error
+	switch v := src.(type) {
+	case nil:
+		*m = 0
+	case []byte:
+		*m, err = ParseMoney(string(v))
+	case string:
+		*m, err = ParseMoney(v)
+	case int64:
+		if v > math.MaxInt64/moneyUnit || v < math.MinInt64/moneyUnit {
+			return fmt.Errorf("%w: %d is out of range", ErrInvalidMoney, v)
+		}
+		*m = Money(v * moneyUnit)
+	case float64:
+		*m, err = ParseMoney(strconv.FormatFloat(v, 'f', MoneyScale, 64))
+	default:
+		err = fmt.Errorf("%w: cannot scan %T", ErrInvalidMoney, src)
+	}
+	return err
+}
+
+// Value implements driver.Valuer, amounts are sent to the database as decimal text.
+func (m Money) Value() (driver.Value, error) {
+	return m.String(), nil
+}
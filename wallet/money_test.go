@@ -0,0 +1,193 @@
+package wallet
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+	"testing"
+)
+
+func TestParseMoney(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    Money
+		wantErr bool
+	}{
+		{name: "Whole number", input: "100", want: 100 * moneyUnit},
+		{name: "Two decimals", input: "100.25", want: 10025000000},
+		{name: "Smallest unit", input: "0.00000001", want: 1},
+		{name: "Negative", input: "-5.5", want: -550000000},
+		{name: "Leading dot", input: ".5", want: 50000000},
+		{name: "Empty", input: "", wantErr: true},
+		{name: "Only dot", input: ".", wantErr: true},
+		{name: "Letters", input: "ten", wantErr: true},
+		{name: "Exponent", input: "1e2", wantErr: true},
+		{name: "Too many decimals", input: "0.000000001", wantErr: true},
+		{name: "Out of range", input: "100000000000000000000", wantErr: true},
+		{name: "Largest", input: "9999999999.99999999", want: MaxMoney},
+		{name: "Beyond the money columns", input: "10000000000", wantErr: true},
+		{name: "Beyond the money columns negative", input: "-10000000000", wantErr: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := ParseMoney(test.input)
+			if test.wantErr {
+				if !errors.Is(err, ErrInvalidMoney) {
+					t.Errorf("ParseMoney() error = %v, want %v", err, ErrInvalidMoney)
+				}
+				return
+			}
+			if err != nil || got != test.want {
+				t.Errorf("ParseMoney() = %v, %v, want %v", int64(got), err, int64(test.want))
+			}
+		})
+	}
+}
+
+func TestMoneyString(t *testing.T) {
+	tests := []struct {
+		name  string
+		money Money
+		want  string
+	}{
+		{name: "Zero", money: 0, want: "0.00"},
+		{name: "Whole number", money: MustParseMoney("100"), want: "100.00"},
+		{name: "One decimal", money: MustParseMoney("100.5"), want: "100.50"},
+		{name: "Eight decimals", money: MustParseMoney("0.12345678"), want: "0.12345678"},
+		{name: "Negative", money: MustParseMoney("-0.05"), want: "-0.05"},
+		{name: "Smallest", money: math.MinInt64, want: "-92233720368.54775808"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := test.money.String(); got != test.want {
+				t.Errorf("String() = %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestMoneyDecimals(t *testing.T) {
+	tests := []struct {
+		name  string
+		money Money
+		want  int
+	}{
+		{name: "Whole number", money: MustParseMoney("100"), want: 0},
+		{name: "Cents", money: MustParseMoney("-1.25"), want: 2},
+		{name: "Satoshi", money: MustParseMoney("0.00000001"), want: 8},
+		{name: "Smallest", money: math.MinInt64, want: 8},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := test.money.Decimals(); got != test.want {
+				t.Errorf("Decimals() = %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestMoneyArithmeticIsExact(t *testing.T) {
+	got, err := MustParseMoney("0.1").Add(MustParseMoney("0.2"))
+
+	if err != nil || got != MustParseMoney("0.3") {
+		t.Errorf("0.1 + 0.2 = %v, %v, want 0.30", got, err)
+	}
+	if got, err := got.Sub(MustParseMoney("0.3")); err != nil || !got.IsZero() {
+		t.Errorf("0.3 - 0.3 = %v, %v, want 0.00", got, err)
+	}
+}
+
+func TestMoneyArithmeticOutOfRange(t *testing.T) {
+	tests := []struct {
+		name string
+		op   func() (Money, error)
+	}{
+		{name: "Add beyond MaxMoney", op: func() (Money, error) { return MaxMoney.Add(1) }},
+		{name: "Add below -MaxMoney", op: func() (Money, error) { return MaxMoney.Neg().Add(-1) }},
+		{name: "Add wrapping int64", op: func() (Money, error) { return Money(math.MaxInt64).Add(1) }},
+		{name: "Sub beyond MaxMoney", op: func() (Money, error) { return MaxMoney.Sub(-1) }},
+		{name: "Sub below -MaxMoney", op: func() (Money, error) { return MaxMoney.Neg().Sub(MaxMoney) }},
+		{name: "Sub wrapping int64", op: func() (Money, error) { return Money(math.MinInt64).Sub(1) }},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got, err := test.op(); !errors.Is(err, ErrInvalidMoney) {
+				t.Errorf("got %v, %v, want ErrInvalidMoney", got, err)
+			}
+		})
+	}
+}
+
+func TestMoneyJSON(t *testing.T) {
+	t.Run("marshal as exact number", func(t *testing.T) {
+		got, err := json.Marshal(Wallet{Balance: MustParseMoney("1234.5")})
+		if err != nil {
+			t.Fatal(err)
+		}
+		var raw map[string]json.RawMessage
+		if err := json.Unmarshal(got, &raw); err != nil {
+			t.Fatal(err)
+		}
+		if string(raw["balance"]) != "1234.50" {
+			t.Errorf("balance = %s, want 1234.50", raw["balance"])
+		}
+	})
+
+	for _, body := range []string{`{"amount": 0.07}`, `{"amount": "0.07"}`} {
+		t.Run("unmarshal "+body, func(t *testing.T) {
+			var got BalanceChange
+			if err := json.Unmarshal([]byte(body), &got); err != nil {
+				t.Fatal(err)
+			}
+			if got.Amount != MustParseMoney("0.07") {
+				t.Errorf("amount = %v, want 0.07", got.Amount)
+			}
+		})
+	}
+
+	for _, data := range []string{`"12`, `12"`, `"1"2"`} {
+		t.Run("unmarshal "+data+" fails", func(t *testing.T) {
+			var got Money
+			if err := got.UnmarshalJSON([]byte(data)); !errors.Is(err, ErrInvalidMoney) {
+				t.Errorf("UnmarshalJSON() error = %v, want ErrInvalidMoney", err)
+			}
+		})
+	}
+}
+
+func TestMoneyScan(t *testing.T) {
+	tests := []struct {
+		name string
+		src  interface{}
+		want Money
+	}{
+		{name: "Bytes", src: []byte("1000.00"), want: MustParseMoney("1000")},
+		{name: "String", src: "0.5", want: MustParseMoney("0.5")},
+		{name: "Integer", src: int64(42), want: MustParseMoney("42")},
+		{name: "Float", src: 19.99, want: MustParseMoney("19.99")},
+		{name: "Null", src: nil, want: 0},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var got Money
+			if err := got.Scan(test.src); err != nil || got != test.want {
+				t.Errorf("Scan() = %v, %v, want %v", got, err, test.want)
+			}
+		})
+	}
+}
+
+func TestMoneyScanOutOfRange(t *testing.T) {
+	for _, src := range []int64{math.MaxInt64 / moneyUnit * 2, math.MinInt64 / moneyUnit * 2} {
+		var got Money
+		if err := got.Scan(src); !errors.Is(err, ErrInvalidMoney) {
+			t.Errorf("Scan(%d) error = %v, want ErrInvalidMoney", src, err)
+		}
+	}
+}
@@ -0,0 +1,94 @@
+package wallet
+
+import (
+	"errors"
+	"github.com/labstack/echo/v4"
+)
+
+// ErrForbidden means the caller is authenticated but the wallet or user belongs to someone else.
+var ErrForbidden = errors.New("not allowed to access this resource")
+
+// caller is the principal of the request, ErrUnauthorized when no middleware placed one.
+func caller(c echo.Context) (Principal, error) {
+	p, ok := CurrentPrincipal(c)
+	if !ok {
+		return Principal{}, ErrUnauthorized
+	}
+	return p, nil
+}
+
+// authorizeUser returns ErrForbidden unless the caller is the user userID or
+// RequirePermission lifted the check, every ownership check of the handlers ends up here.
+func (h *Handler) authorizeUser(c echo.Context, userID int) error {
+	p, err := caller(c)
+	if err != nil {
+		return err
+	}
+	if actsOnAnyOwner(c) {
+		return nil
+	}
+	if p.UserID == 0 || p.UserID != userID {
+		return ErrForbidden
+	}
+	return nil
+}
+
+// authorizeWallet returns ErrForbidden unless the caller owns the wallet,
+// soft-deleted or not, and ErrWalletNotFound when there is no such wallet.
+func (h *Handler) authorizeWallet(c echo.Context, walletID int) error {
+	if _, err := caller(c); err != nil {
+		return err
+	}
+	if actsOnAnyOwner(c) {
+		return nil
+	}
+	owner, err := h.store.GetWalletOwner(walletID)
+	if err != nil {
+		return err
+	}
+	return h.authorizeUser(c, owner)
+}
+
+// ownedWalletID is ParseWalletID for a wallet the caller owns.
+func (h *Handler) ownedWalletID(c echo.Context) (int, error) {
+	walletID, err := ParseWalletID(c)
+	if err != nil {
+		return 0, err
+	}
+	if err := h.authorizeWallet(c, walletID); err != nil {
+		return 0, err
+	}
+	return walletID, nil
+}
+
+// ownedUserID is ParseUserID for the caller's own user id.
+func (h *Handler) ownedUserID(c echo.Context) (int, error) {
+	userID, err := ParseUserID(c)
+	if err != nil {
+		return 0, err
+	}
+	if err := h.authorizeUser(c, userID); err != nil {
+		return 0, err
+	}
+	return userID, nil
+}
+
+// ownFilter limits a wallet listing to the caller's wallets, unless RequirePermission
+// lifted the ownership checks. Soft-deleted wallets need PermissionIncludeDeleted.
+func (h *Handler) ownFilter(c echo.Context, filter Filter) (Filter, error) {
+	p, err := caller(c)
+	if err != nil {
+		return Filter{}, err
+	}
+	if filter.IncludeDeleted && !p.Can(PermissionIncludeDeleted) {
+		return Filter{}, ErrForbidden
+	}
+	if actsOnAnyOwner(c) {
+		return filter, nil
+	}
+	if err := h.authorizeUser(c, p.UserID); err != nil {
+		return Filter{}, err
+	}
+	filter.UserID = p.UserID
+	return filter, nil
+}
@@ -0,0 +1,216 @@
+package wallet
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOwnership(t *testing.T) {
+	const otherUser = 2
+
+	tests := []struct {
+		name       string
+		method     string
+		body       string
+		param      string
+		anonymous  bool
+		principal  *Principal
+		owner      int
+		ownerErr   error
+		handler    func(h *Handler) echo.HandlerFunc
+		store      string
+		wantStatus int
+		wantCode   string
+	}{
+		{
+			name:       "given no principal should return 401",
+			method:     http.MethodGet,
+			param:      "1",
+			anonymous:  true,
+			handler:    func(h *Handler) echo.HandlerFunc { return h.GetWalletHandler },
+			store:      "GetWalletByID",
+			wantStatus: http.StatusUnauthorized,
+			wantCode:   CodeUnauthorized,
+		},
+		{
+			name:       "given a subject that is not a user id should return 403",
+			method:     http.MethodGet,
+			param:      "1",
+			principal:  &Principal{Subject: "service-account"},
+			handler:    func(h *Handler) echo.HandlerFunc { return h.GetWalletHandler },
+			store:      "GetWalletByID",
+			wantStatus: http.StatusForbidden,
+			wantCode:   CodeForbidden,
+		},
+		{
+			name:       "given another user's wallet should not read it",
+			method:     http.MethodGet,
+			param:      "1",
+			owner:      otherUser,
+			handler:    func(h *Handler) echo.HandlerFunc { return h.GetWalletHandler },
+			store:      "GetWalletByID",
+			wantStatus: http.StatusForbidden,
+			wantCode:   CodeForbidden,
+		},
+		{
+			name:       "given unknown wallet should return 404",
+			method:     http.MethodGet,
+			param:      "1",
+			ownerErr:   ErrWalletNotFound,
+			handler:    func(h *Handler) echo.HandlerFunc { return h.GetWalletHandler },
+			store:      "GetWalletByID",
+			wantStatus: http.StatusNotFound,
+			wantCode:   CodeWalletNotFound,
+		},
+		{
+			name:       "given another user's wallet should not deposit",
+			method:     http.MethodPost,
+			body:       `{"amount": 10}`,
+			param:      "1",
+			owner:      otherUser,
+			handler:    func(h *Handler) echo.HandlerFunc { return h.DepositHandler },
+			store:      "Deposit",
+			wantStatus: http.StatusForbidden,
+			wantCode:   CodeForbidden,
+		},
+		{
+			name:       "given another user's deleted wallet should not restore it",
+			method:     http.MethodPost,
+			param:      "1",
+			owner:      otherUser,
+			handler:    func(h *Handler) echo.HandlerFunc { return h.RestoreWalletHandler },
+			store:      "RestoreWallet",
+			wantStatus: http.StatusForbidden,
+			wantCode:   CodeForbidden,
+		},
+		{
+			name:       "given another user's wallet should not freeze it",
+			method:     http.MethodPost,
+			body:       `{"reason": "lost card"}`,
+			param:      "1",
+			owner:      otherUser,
+			handler:    func(h *Handler) echo.HandlerFunc { return h.FreezeWalletHandler },
+			store:      "ChangeWalletStatus",
+			wantStatus: http.StatusForbidden,
+			wantCode:   CodeForbidden,
+		},
+		{
+			name:       "given another user's wallet should not overwrite its balance",
+			method:     http.MethodPut,
+			body:       `{"id": 1, "balance": 10}`,
+			owner:      otherUser,
+			handler:    func(h *Handler) echo.HandlerFunc { return h.UpdateWalletHandler },
+			store:      "UpdateWallet",
+			wantStatus: http.StatusForbidden,
+			wantCode:   CodeForbidden,
+		},
+		{
+			name:       "given another user's wallet should not transfer from it",
+			method:     http.MethodPost,
+			body:       `{"from_wallet_id": 1, "to_wallet_id": 2, "amount": 10}`,
+			owner:      otherUser,
+			handler:    func(h *Handler) echo.HandlerFunc { return h.TransferHandler },
+			store:      "Transfer",
+			wantStatus: http.StatusForbidden,
+			wantCode:   CodeForbidden,
+		},
+		{
+			name:       "given another user's wallet should not convert from it",
+			method:     http.MethodPost,
+			body:       `{"from_wallet_id": 1, "to_wallet_id": 2, "amount": 10}`,
+			owner:      otherUser,
+			handler:    func(h *Handler) echo.HandlerFunc { return h.ConvertHandler },
+			store:      "Convert",
+			wantStatus: http.StatusForbidden,
+			wantCode:   CodeForbidden,
+		},
+		{
+			name:       "given another user id should not create a wallet for it",
+			method:     http.MethodPost,
+			body:       `{"user_id": 2, "wallet_name": "Savings", "wallet_type": "Savings"}`,
+			handler:    func(h *Handler) echo.HandlerFunc { return h.CreateWalletHandler },
+			store:      "CreateWallet",
+			wantStatus: http.StatusForbidden,
+			wantCode:   CodeForbidden,
+		},
+		{
+			name:       "given another user id should not list its wallets",
+			method:     http.MethodGet,
+			param:      "2",
+			handler:    func(h *Handler) echo.HandlerFunc { return h.GetUserWalletHandler },
+			store:      "GetWallets",
+			wantStatus: http.StatusForbidden,
+			wantCode:   CodeForbidden,
+		},
+		{
+			name:       "given another user id should not delete its wallets",
+			method:     http.MethodDelete,
+			param:      "2",
+			handler:    func(h *Handler) echo.HandlerFunc { return h.DeleteUserWalletHandler },
+			store:      "DeleteUserWallets",
+			wantStatus: http.StatusForbidden,
+			wantCode:   CodeForbidden,
+		},
+		{
+			name:       "given another user id should not rename the user",
+			method:     http.MethodPut,
+			body:       `{"name": "Mallory"}`,
+			param:      "2",
+			handler:    func(h *Handler) echo.HandlerFunc { return h.UpdateUserHandler },
+			store:      "UpdateUser",
+			wantStatus: http.StatusForbidden,
+			wantCode:   CodeForbidden,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			resp, c, h, mock := testSetup(tt.method, "/", strings.NewReader(tt.body))
+			c.Request().Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			c.Request().Header.Set(HeaderIfMatch, `"1"`)
+			if tt.param != "" {
+				c.SetParamNames("id")
+				c.SetParamValues(tt.param)
+			}
+			if tt.principal != nil {
+				SetPrincipal(c, *tt.principal)
+			}
+			if tt.anonymous {
+				c.Set(contextKeyPrincipal, nil)
+			}
+			if tt.owner != 0 {
+				mock.owner = tt.owner
+			}
+			mock.ownerErr = tt.ownerErr
+
+			// Act
+			err := serve(c, tt.handler(h))
+
+			// Assert
+			assert.NoError(t, err)
+			assert.False(t, mock.methodToCall[tt.store], "expected %s not to be called", tt.store)
+			assert.Equal(t, tt.wantStatus, resp.Code)
+			assert.Contains(t, resp.Body.String(), tt.wantCode)
+		})
+	}
+}
+
+func TestOwnFilter(t *testing.T) {
+	// Arrange
+	resp, c, h, mock := testSetup(http.MethodGet, "/api/v1/wallets", nil)
+	mock.ExpectToCall("GetWallets")
+
+	// Act
+	err := serve(c, h.GetWalletsHandler)
+
+	// Assert
+	mock.Verify(t)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.Equal(t, testCaller.UserID, mock.whatIsFilter.UserID)
+}
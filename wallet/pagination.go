@@ -0,0 +1,180 @@
+package wallet
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/labstack/echo/v4"
+	"strconv"
+	"time"
+)
+
+const (
+	DefaultPageLimit = 50
+	MaxPageLimit     = 500
+)
+
+const (
+	SortByID         = "id"
+	SortByBalance    = "balance"
+	SortByCreatedAt  = "created_at"
+	SortByWalletName = "wallet_name"
+)
+
+var AvailableSorts = []string{SortByID, SortByBalance, SortByCreatedAt, SortByWalletName}
+
+var (
+	ErrInvalidCursor = errors.New("invalid cursor")
+	ErrInvalidPage   = errors.New("invalid page")
+)
+
+// Page asks for at most Limit wallets ordered by Sort, then by id to break ties,
+// starting after the wallet the cursor points at.
+type Page struct {
+	Limit  int
+	Sort   string
+	Desc   bool
+	Cursor *Cursor
+}
+
+// Cursor is the position of the last wallet of a page, sent to clients as an opaque string.
+type Cursor struct {
+	Sort  string `json:"s"`
+	Desc  bool   `json:"d,omitempty"`
+	Value string `json:"v,omitempty"`
+	ID    int    `json:"id"`
+}
+
+type WalletPage struct {
+	Wallets    []Wallet `json:"wallets"`
+	NextCursor string   `json:"next_cursor,omitempty" example:"eyJzIjoiaWQiLCJpZCI6NTB9"`
+}
+
+func DefaultPage() Page {
+	return Page{Limit: DefaultPageLimit, Sort: SortByID}
+}
+
+func (c Cursor) Encode() string {
+	b, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func DecodeCursor(s string) (Cursor, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return Cursor{}, ErrInvalidCursor
+	}
+
+	var c Cursor
+	if err = json.Unmarshal(b, &c); err != nil || !IsSortValid(c.Sort) {
+		return Cursor{}, ErrInvalidCursor
+	}
+	if _, err = c.SortValue(); err != nil {
+		return Cursor{}, ErrInvalidCursor
+	}
+	return c, nil
+}
+
+// Validate guards the stores, which build ORDER BY from p.Sort.
+func (p Page) Validate() error {
+	if p.Limit < 1 || p.Limit > MaxPageLimit || !IsSortValid(p.Sort) {
+		return ErrInvalidPage
+	}
+	if p.Cursor != nil && (p.Cursor.Sort != p.Sort || p.Cursor.Desc != p.Desc) {
+		return ErrInvalidCursor
+	}
+	return nil
+}
+
+// SortValue returns the cursor value typed like the sort column: int, Money, time.Time or string.
+func (c Cursor) SortValue() (interface{}, error) {
+	switch c.Sort {
+	case SortByBalance:
+		return ParseMoney(c.Value)
+	case SortByCreatedAt:
+		return time.Parse(time.RFC3339Nano, c.Value)
+	case SortByWalletName:
+		return c.Value, nil
+	default:
+		return c.ID, nil
+	}
+}
+
+// CursorAt returns the cursor pointing at w in the page's order.
+func (p Page) CursorAt(w Wallet) Cursor {
+	c := Cursor{Sort: p.Sort, Desc: p.Desc, ID: w.ID}
+	switch p.Sort {
+	case SortByBalance:
+		c.Value = w.Balance.String()
+	case SortByCreatedAt:
+		c.Value = w.CreatedAt.UTC().Format(time.RFC3339Nano)
+	case SortByWalletName:
+		c.Value = w.WalletName
+	}
+	return c
+}
+
+// NewWalletPage cuts wallets, fetched with a limit of p.Limit+1, down to the page
+// and sets the next cursor when there were more.
+func (p Page) NewWalletPage(wallets []Wallet) WalletPage {
+	if len(wallets) <= p.Limit {
+		return WalletPage{Wallets: wallets}
+	}
+
+	wallets = wallets[:p.Limit]
+	return WalletPage{Wallets: wallets, NextCursor: p.CursorAt(wallets[len(wallets)-1]).Encode()}
+}
+
+func IsSortValid(sort string) bool {
+	for _, s := range AvailableSorts {
+		if s == sort {
+			return true
+		}
+	}
+	return false
+}
+
+// ParsePage reads the limit, sort, order and cursor query params.
+func ParsePage(c echo.Context) (Page, error) {
+	page := DefaultPage()
+	v := validator{}
+
+	if limit := c.QueryParam("limit"); limit != "" {
+		n, err := strconv.Atoi(limit)
+		if v.check(err == nil && n >= 1 && n <= MaxPageLimit, "limit", "must be between 1 and "+strconv.Itoa(MaxPageLimit)) {
+			page.Limit = n
+		}
+	}
+
+	if sort := c.QueryParam("sort"); sort != "" {
+		if v.check(IsSortValid(sort), "sort", "must be one of id, balance, created_at, wallet_name") {
+			page.Sort = sort
+		}
+	}
+
+	switch c.QueryParam("order") {
+	case "", "asc":
+	case "desc":
+		page.Desc = true
+	default:
+		v.add("order", "must be asc or desc")
+	}
+
+	if err := v.err(); err != nil {
+		return Page{}, err
+	}
+
+	if cursor := c.QueryParam("cursor"); cursor != "" {
+		decoded, err := DecodeCursor(cursor)
+		if err != nil {
+			return Page{}, err
+		}
+		if decoded.Sort != page.Sort || decoded.Desc != page.Desc {
+			return Page{}, fmt.Errorf("%w: does not match sort and order", ErrInvalidCursor)
+		}
+		page.Cursor = &decoded
+	}
+
+	return page, nil
+}
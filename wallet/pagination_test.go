@@ -0,0 +1,108 @@
+package wallet
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCursorRoundTrip(t *testing.T) {
+	w := Wallet{
+		ID:         42,
+		WalletName: "John Savings",
+		Balance:    MustParseMoney("1000.50"),
+		CreatedAt:  time.Date(2024, 3, 25, 14, 19, 0, 729237000, time.UTC),
+	}
+
+	for _, sort := range AvailableSorts {
+		t.Run(sort, func(t *testing.T) {
+			page := Page{Limit: 10, Sort: sort, Desc: true}
+
+			got, err := DecodeCursor(page.CursorAt(w).Encode())
+
+			require.NoError(t, err)
+			assert.Equal(t, page.CursorAt(w), got)
+			assert.NoError(t, Page{Limit: 10, Sort: sort, Desc: true, Cursor: &got}.Validate())
+		})
+	}
+}
+
+func TestDecodeCursor(t *testing.T) {
+	tests := []struct {
+		name   string
+		cursor string
+	}{
+		{name: "Not base64", cursor: "!!!"},
+		{name: "Not json", cursor: "bm90IGpzb24"},
+		{name: "Unknown sort", cursor: Cursor{Sort: "user_name", ID: 1}.Encode()},
+		{name: "Bad balance", cursor: Cursor{Sort: SortByBalance, Value: "lots", ID: 1}.Encode()},
+		{name: "Bad created_at", cursor: Cursor{Sort: SortByCreatedAt, Value: "yesterday", ID: 1}.Encode()},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := DecodeCursor(test.cursor)
+
+			assert.ErrorIs(t, err, ErrInvalidCursor)
+		})
+	}
+}
+
+func TestNewWalletPage(t *testing.T) {
+	page := Page{Limit: 2, Sort: SortByID}
+
+	t.Run("Last page has no next cursor", func(t *testing.T) {
+		got := page.NewWalletPage([]Wallet{{ID: 1}, {ID: 2}})
+
+		assert.Len(t, got.Wallets, 2)
+		assert.Empty(t, got.NextCursor)
+	})
+
+	t.Run("Extra wallet is cut and points the next cursor at the last kept one", func(t *testing.T) {
+		got := page.NewWalletPage([]Wallet{{ID: 1}, {ID: 2}, {ID: 3}})
+
+		assert.Len(t, got.Wallets, 2)
+		assert.Equal(t, Cursor{Sort: SortByID, ID: 2}.Encode(), got.NextCursor)
+	})
+}
+
+func TestParsePage(t *testing.T) {
+	nameCursor := Cursor{Sort: SortByWalletName, Value: "b", ID: 2}
+
+	tests := []struct {
+		name    string
+		query   string
+		want    Page
+		wantErr bool
+	}{
+		{name: "Defaults", query: "", want: DefaultPage()},
+		{name: "Every param", query: "?limit=10&sort=wallet_name&order=asc&cursor=" + nameCursor.Encode(), want: Page{Limit: 10, Sort: SortByWalletName, Cursor: &nameCursor}},
+		{name: "Descending", query: "?order=desc", want: Page{Limit: DefaultPageLimit, Sort: SortByID, Desc: true}},
+		{name: "Limit not a number", query: "?limit=ten", wantErr: true},
+		{name: "Limit too large", query: "?limit=501", wantErr: true},
+		{name: "Unknown sort", query: "?sort=user_id", wantErr: true},
+		{name: "Unknown order", query: "?order=up", wantErr: true},
+		{name: "Cursor from another sort", query: "?sort=balance&cursor=" + nameCursor.Encode(), wantErr: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/api/v1/wallets"+test.query, nil)
+			c := echo.New().NewContext(req, httptest.NewRecorder())
+
+			got, err := ParsePage(c)
+
+			if test.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, test.want, got)
+		})
+	}
+}
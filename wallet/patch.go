@@ -0,0 +1,161 @@
+package wallet
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/labstack/echo/v4"
+	"io"
+	"net/http"
+	"sort"
+)
+
+const MaxNameLength = 255
+
+// WalletPatch is a JSON Merge Patch (RFC 7396) of a wallet, nil fields are left as they are.
+// The user name belongs to the user, it is changed with UpdateUserHandler.
+type WalletPatch struct {
+	WalletName *string `json:"wallet_name,omitempty" example:"John's Savings"`
+	WalletType *string `json:"wallet_type,omitempty" example:"Savings"`
+}
+
+// ParseWalletPatch decodes a merge patch, rejecting members that cannot be patched
+// and null for members that cannot be removed.
+func ParseWalletPatch(body []byte) (WalletPatch, error) {
+	var members map[string]json.RawMessage
+	if err := json.Unmarshal(body, &members); err != nil {
+		return WalletPatch{}, fmt.Errorf("%w: patch must be a JSON object", ErrInvalidRequest)
+	}
+
+	patch := WalletPatch{}
+	v := validator{}
+	// map order is random, keep the field errors in a stable order
+	names := make([]string, 0, len(members))
+	for name := range members {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		raw := members[name]
+		var target **string
+		switch name {
+		case "wallet_name":
+			target = &patch.WalletName
+		case "wallet_type":
+			target = &patch.WalletType
+		default:
+			v.add(name, "cannot be patched")
+			continue
+		}
+
+		if string(raw) == "null" {
+			v.add(name, "cannot be removed")
+			continue
+		}
+		var value string
+		if err := json.Unmarshal(raw, &value); err != nil {
+			v.add(name, "must be a string")
+			continue
+		}
+		*target = &value
+	}
+
+	if err := v.err(); err != nil {
+		return WalletPatch{}, err
+	}
+	return patch, patch.Validate()
+}
+
+// Validate checks each patched field on its own.
+func (p WalletPatch) Validate() error {
+	v := validator{}
+	if p.WalletName != nil {
+		v.checkName("wallet_name", *p.WalletName)
+	}
+	if p.WalletType != nil {
+		v.checkWalletType("wallet_type", *p.WalletType)
+	}
+	return v.err()
+}
+
+// Apply patches w, checking the result against the rules that depend on the
+// rest of the wallet: the currency and sign of the balance must suit the new type,
+// and the new type must not allow a negative balance the old type did not.
+func (p WalletPatch) Apply(w *Wallet) error {
+	patched := *w
+	if p.WalletName != nil {
+		patched.WalletName = *p.WalletName
+	}
+	if p.WalletType != nil {
+		patched.WalletType = *p.WalletType
+	}
+
+	if err := CheckCurrency(patched.WalletType, patched.Currency); err != nil {
+		return err
+	}
+	// otherwise a customer could turn their savings into credit and withdraw below zero
+	if AllowsNegativeBalance(patched.WalletType) && !AllowsNegativeBalance(w.WalletType) {
+		return ErrWalletTypeChange
+	}
+	if patched.Balance.IsNegative() && !AllowsNegativeBalance(patched.WalletType) {
+		return ErrNegativeBalance
+	}
+
+	*w = patched
+	return nil
+}
+
+// PatchWalletHandler
+//
+//	@Summary		Patch wallet
+//	@Description	Change the wallet name or wallet type with a JSON Merge Patch, a wallet cannot become a Credit Card
+//	@Tags			wallet
+//	@Accept			json
+//	@Accept			application/merge-patch+json
+//	@Produce		json
+//	@Param			id		path	int			true	"Wallet ID"
+//	@Param			patch	body	WalletPatch	true	"Fields to change"
+//	@Param			If-Match	header	string	true	"ETag of the wallet from a previous read"
+//	@Param			Idempotency-Key	header	string	false	"Key to run a retried request only once"
+//	@Success		200	{object}	Wallet
+//	@Header			200	{string}	ETag	"New version of the wallet"
+//	@Failure		400	{object}	Problem
+//	@Failure		403	{object}	Problem
+//	@Failure		404	{object}	Problem
+//	@Failure		409	{object}	Problem
+//	@Failure		412	{object}	Problem
+//	@Failure		422	{object}	Problem
+//	@Failure		428	{object}	Problem
+//	@Failure		500	{object}	Problem
+//	@Router			/api/v1/wallets/{id} [patch]
+func (h *Handler) PatchWalletHandler(c echo.Context) error {
+	// parse wallet id
+	walletID, err := h.ownedWalletID(c)
+	if err != nil {
+		return err
+	}
+
+	version, err := IfMatchVersion(c)
+	if err != nil {
+		return err
+	}
+
+	// parse patch
+	body, err := io.ReadAll(c.Request().Body)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidRequest, err)
+	}
+	patch, err := ParseWalletPatch(body)
+	if err != nil {
+		return err
+	}
+
+	// patch wallet
+	wallet, err := h.store.PatchWallet(walletID, version, patch)
+	if err != nil {
+		return err
+	}
+
+	setETag(c, wallet)
+	return c.JSON(http.StatusOK, wallet)
+}
@@ -0,0 +1,188 @@
+package wallet
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseWalletPatch(t *testing.T) {
+	savings := WalletTypeSavings
+	name := "Everyday"
+
+	tests := []struct {
+		name    string
+		body    string
+		want    WalletPatch
+		wantErr string
+	}{
+		{name: "Empty patch", body: `{}`, want: WalletPatch{}},
+		{name: "Some fields", body: `{"wallet_name": "Everyday", "wallet_type": "Savings"}`, want: WalletPatch{WalletName: &name, WalletType: &savings}},
+		{name: "Not an object", body: `["wallet_name"]`, wantErr: "invalid request: patch must be a JSON object"},
+		{name: "Field that cannot be patched", body: `{"balance": 100}`, wantErr: "balance: cannot be patched"},
+		{name: "Null removes a required field", body: `{"wallet_name": null}`, wantErr: "wallet_name: cannot be removed"},
+		{name: "User name belongs to the user", body: `{"user_name": "John Doe"}`, wantErr: "user_name: cannot be patched"},
+		{name: "Not a string", body: `{"wallet_name": 1}`, wantErr: "wallet_name: must be a string"},
+		{name: "Empty name", body: `{"wallet_name": " "}`, wantErr: "wallet_name: must not be empty"},
+		{name: "Name too long", body: `{"wallet_name": "` + strings.Repeat("a", MaxNameLength+1) + `"}`, wantErr: "wallet_name: must be at most 255 characters"},
+		{name: "Unknown wallet type", body: `{"wallet_type": "Piggy Bank"}`, wantErr: "wallet_type: must be one of Savings, Credit Card, Crypto Wallet"},
+		{name: "Every problem is listed", body: `{"id": 2, "balance": 1}`, wantErr: "balance: cannot be patched; id: cannot be patched"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := ParseWalletPatch([]byte(test.body))
+
+			if test.wantErr != "" {
+				assert.EqualError(t, err, test.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, test.want, got)
+		})
+	}
+}
+
+func TestWalletPatchApply(t *testing.T) {
+	savings, creditCard := WalletTypeSavings, WalletTypeCreditCard
+
+	t.Run("Changes the patched fields", func(t *testing.T) {
+		w := Wallet{ID: 1, WalletName: "Card", WalletType: WalletTypeCreditCard, Currency: CurrencyTHB}
+
+		err := WalletPatch{WalletType: &savings}.Apply(&w)
+
+		assert.NoError(t, err)
+		assert.Equal(t, Wallet{ID: 1, WalletName: "Card", WalletType: WalletTypeSavings, Currency: CurrencyTHB}, w)
+	})
+
+	t.Run("Overdrawn wallet must stay a Credit Card", func(t *testing.T) {
+		w := Wallet{WalletType: WalletTypeCreditCard, Currency: CurrencyTHB, Balance: MustParseMoney("-1")}
+
+		err := WalletPatch{WalletType: &savings}.Apply(&w)
+
+		assert.ErrorIs(t, err, ErrNegativeBalance)
+		assert.Equal(t, WalletTypeCreditCard, w.WalletType)
+	})
+
+	t.Run("Savings cannot become a Credit Card", func(t *testing.T) {
+		w := Wallet{WalletType: WalletTypeSavings, Currency: CurrencyTHB, Balance: MustParseMoney("10")}
+
+		err := WalletPatch{WalletType: &creditCard}.Apply(&w)
+
+		assert.ErrorIs(t, err, ErrWalletTypeChange)
+		assert.Equal(t, WalletTypeSavings, w.WalletType)
+	})
+
+	t.Run("BTC wallet must stay a Crypto Wallet", func(t *testing.T) {
+		w := Wallet{WalletType: WalletTypeCryptoWallet, Currency: CurrencyBTC}
+
+		err := WalletPatch{WalletType: &creditCard}.Apply(&w)
+
+		assert.ErrorIs(t, err, ErrUnsupportedCurrency)
+	})
+}
+
+func TestPatchWallet(t *testing.T) {
+	t.Run("given invalid patch should return 400 and not call the store", func(t *testing.T) {
+		// Arrange
+		resp, c, h, mock := testSetup(http.MethodPatch, "/", strings.NewReader(`{"wallet_type": "Piggy Bank"}`))
+		c.Request().Header.Set(HeaderIfMatch, `"1"`)
+		c.SetPath("/api/v1/wallets/:id")
+		c.SetParamNames("id")
+		c.SetParamValues("1")
+
+		// Act
+		err := serve(c, h.PatchWalletHandler)
+
+		// Assert
+		assert.NoError(t, err)
+		assert.False(t, mock.methodToCall["PatchWallet"])
+		assert.Equal(t, http.StatusBadRequest, resp.Code)
+	})
+
+	t.Run("given no If-Match should return 428 and not call the store", func(t *testing.T) {
+		// Arrange
+		resp, c, h, mock := testSetup(http.MethodPatch, "/", strings.NewReader(`{"wallet_name": "Everyday"}`))
+		c.SetPath("/api/v1/wallets/:id")
+		c.SetParamNames("id")
+		c.SetParamValues("1")
+
+		// Act
+		err := serve(c, h.PatchWalletHandler)
+
+		// Assert
+		assert.NoError(t, err)
+		assert.False(t, mock.methodToCall["PatchWallet"])
+		assert.Equal(t, http.StatusPreconditionRequired, resp.Code)
+	})
+
+	tests := []struct {
+		name     string
+		storeErr error
+		wantCode int
+	}{
+		{"given unknown wallet should return 404", ErrWalletNotFound, http.StatusNotFound},
+		{"given wallet changed since it was read should return 412", ErrVersionMismatch, http.StatusPreconditionFailed},
+		{"given type not suiting the wallet should return 422", ErrNegativeBalance, http.StatusUnprocessableEntity},
+		{"given type allowing credit should return 422", ErrWalletTypeChange, http.StatusUnprocessableEntity},
+		{"given unable to patch should return 500", errors.New("connection refused"), http.StatusInternalServerError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name+" and error message", func(t *testing.T) {
+			// Arrange
+			resp, c, h, mock := testSetup(http.MethodPatch, "/", strings.NewReader(`{"wallet_type": "Savings"}`))
+			c.Request().Header.Set(HeaderIfMatch, `"1"`)
+			c.SetPath("/api/v1/wallets/:id")
+			c.SetParamNames("id")
+			c.SetParamValues("1")
+			mock.err = tt.storeErr
+			mock.ExpectToCall("PatchWallet")
+
+			// Act
+			err := serve(c, h.PatchWalletHandler)
+
+			// Assert
+			mock.Verify(t)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantCode, resp.Code)
+			var got Problem
+			if err := json.Unmarshal(resp.Body.Bytes(), &got); err != nil {
+				t.Errorf("expected response body to be valid json, got %s", resp.Body.String())
+			}
+			assert.NotEmpty(t, got.Code)
+		})
+	}
+
+	t.Run("given valid patch should return 200 and the patched wallet", func(t *testing.T) {
+		// Arrange
+		resp, c, h, mock := testSetup(http.MethodPatch, "/", strings.NewReader(`{"wallet_name": "Everyday"}`))
+		c.Request().Header.Set(HeaderIfMatch, `"3"`)
+		c.SetPath("/api/v1/wallets/:id")
+		c.SetParamNames("id")
+		c.SetParamValues("7")
+		mock.wallet = Wallet{ID: 7, WalletName: "Everyday", WalletType: WalletTypeSavings, Currency: CurrencyTHB, Version: 4}
+		mock.ExpectToCall("PatchWallet")
+
+		// Act
+		err := serve(c, h.PatchWalletHandler)
+
+		// Assert
+		mock.Verify(t)
+		assert.NoError(t, err)
+		assert.Equal(t, 7, mock.whatIsID)
+		assert.Equal(t, 3, mock.whatIsVersion)
+		assert.Equal(t, `"4"`, resp.Header().Get(HeaderETag))
+		assert.Equal(t, "Everyday", *mock.whatIsPatch.WalletName)
+		assert.Nil(t, mock.whatIsPatch.WalletType)
+		assert.Equal(t, http.StatusOK, resp.Code)
+		var got Wallet
+		if err := json.Unmarshal(resp.Body.Bytes(), &got); err != nil {
+			t.Errorf("expected response body to be valid json, got %s", resp.Body.String())
+		}
+		assert.Equal(t, mock.wallet, got)
+	})
+}
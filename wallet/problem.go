@@ -0,0 +1,184 @@
+package wallet
+
+import (
+	"errors"
+	"github.com/labstack/echo/v4"
+	"log"
+	"net/http"
+	"strings"
+)
+
+const MIMEApplicationProblemJSON = "application/problem+json"
+
+// Problem is an RFC 7807 problem detail, Code is stable for clients to match on.
+type Problem struct {
+	Type     string       `json:"type" example:"about:blank"`
+	Title    string       `json:"title" example:"Not Found"`
+	Status   int          `json:"status" example:"404"`
+	Code     string       `json:"code" example:"WALLET_NOT_FOUND"`
+	Detail   string       `json:"detail,omitempty" example:"wallet not found"`
+	Instance string       `json:"instance,omitempty" example:"/api/v1/wallets/42"`
+	Errors   []FieldError `json:"errors,omitempty"`
+}
+
+const (
+	CodeInvalidRequest          = "INVALID_REQUEST"
+	CodeUnauthorized            = "UNAUTHORIZED"
+	CodeForbidden               = "FORBIDDEN"
+	CodeValidationFailed        = "VALIDATION_FAILED"
+	CodeWalletNotFound          = "WALLET_NOT_FOUND"
+	CodeUserNotFound            = "USER_NOT_FOUND"
+	CodeUserHasWallets          = "USER_HAS_WALLETS"
+	CodeAPIKeyNotFound          = "API_KEY_NOT_FOUND"
+	CodeAPIKeyRevoked           = "API_KEY_REVOKED"
+	CodeInsufficientFunds       = "INSUFFICIENT_FUNDS"
+	CodeUnsupportedCurrency     = "UNSUPPORTED_CURRENCY"
+	CodeInvalidPrecision        = "INVALID_PRECISION"
+	CodeCurrencyMismatch        = "CURRENCY_MISMATCH"
+	CodeRateNotFound            = "RATE_NOT_FOUND"
+	CodeConversionTooSmall      = "CONVERSION_TOO_SMALL"
+	CodeNegativeBalance         = "NEGATIVE_BALANCE"
+	CodeDuplicateWallet         = "DUPLICATE_WALLET"
+	CodeWalletTypeChange        = "WALLET_TYPE_CHANGE"
+	CodeWalletFrozen            = "WALLET_FROZEN"
+	CodeWalletClosed            = "WALLET_CLOSED"
+	CodeInvalidStatusTransition = "INVALID_STATUS_TRANSITION"
+	CodeWalletNotEmpty          = "WALLET_NOT_EMPTY"
+	CodeConflict                = "CONFLICT"
+	CodeIdempotencyKeyReused    = "IDEMPOTENCY_KEY_REUSED"
+	CodeVersionMismatch         = "VERSION_MISMATCH"
+	CodePreconditionRequired    = "PRECONDITION_REQUIRED"
+	CodeRequestInProgress       = "REQUEST_IN_PROGRESS"
+	CodeInvalidAmount           = "INVALID_AMOUNT"
+	CodeInvalidCursor           = "INVALID_CURSOR"
+	CodeInternalError           = "INTERNAL_ERROR"
+)
+
+// ErrInvalidRequest marks a request that cannot be read at all,
+// like malformed JSON or a path id that is not a number.
+var ErrInvalidRequest = errors.New("invalid request")
+
+// domainProblems maps the errors returned by handlers and the Storer to their answer.
+var domainProblems = []struct {
+	err    error
+	status int
+	code   string
+}{
+	{ErrInvalidRequest, http.StatusBadRequest, CodeInvalidRequest},
+	{ErrUnauthorized, http.StatusUnauthorized, CodeUnauthorized},
+	{ErrForbidden, http.StatusForbidden, CodeForbidden},
+	{ErrWalletNotFound, http.StatusNotFound, CodeWalletNotFound},
+	{ErrUserNotFound, http.StatusNotFound, CodeUserNotFound},
+	{ErrUserHasWallets, http.StatusConflict, CodeUserHasWallets},
+	{ErrAPIKeyNotFound, http.StatusNotFound, CodeAPIKeyNotFound},
+	{ErrAPIKeyRevoked, http.StatusConflict, CodeAPIKeyRevoked},
+	{ErrInvalidPrecision, http.StatusBadRequest, CodeInvalidPrecision},
+	{ErrInvalidMoney, http.StatusBadRequest, CodeInvalidAmount},
+	{ErrInvalidCursor, http.StatusBadRequest, CodeInvalidCursor},
+	{ErrInvalidPage, http.StatusBadRequest, CodeInvalidRequest},
+	{ErrInsufficientFunds, http.StatusUnprocessableEntity, CodeInsufficientFunds},
+	{ErrUnsupportedCurrency, http.StatusUnprocessableEntity, CodeUnsupportedCurrency},
+	{ErrCurrencyMismatch, http.StatusUnprocessableEntity, CodeCurrencyMismatch},
+	{ErrRateNotFound, http.StatusUnprocessableEntity, CodeRateNotFound},
+	{ErrConversionTooSmall, http.StatusUnprocessableEntity, CodeConversionTooSmall},
+	{ErrNegativeBalance, http.StatusUnprocessableEntity, CodeNegativeBalance},
+	{ErrDuplicateWallet, http.StatusConflict, CodeDuplicateWallet},
+	{ErrWalletTypeChange, http.StatusUnprocessableEntity, CodeWalletTypeChange},
+	{ErrWalletFrozen, http.StatusUnprocessableEntity, CodeWalletFrozen},
+	{ErrWalletClosed, http.StatusUnprocessableEntity, CodeWalletClosed},
+	{ErrInvalidStatusTransition, http.StatusConflict, CodeInvalidStatusTransition},
+	{ErrWalletNotEmpty, http.StatusConflict, CodeWalletNotEmpty},
+	{ErrConflict, http.StatusConflict, CodeConflict},
+	{ErrVersionMismatch, http.StatusPreconditionFailed, CodeVersionMismatch},
+	{ErrPreconditionRequired, http.StatusPreconditionRequired, CodePreconditionRequired},
+	{ErrIdempotencyKeyReused, http.StatusUnprocessableEntity, CodeIdempotencyKeyReused},
+	{ErrRequestInProgress, http.StatusConflict, CodeRequestInProgress},
+}
+
+// statusError answers err with another status than its mapping, keeping its code.
+type statusError struct {
+	status int
+	err    error
+}
+
+func (e *statusError) Error() string { return e.err.Error() }
+func (e *statusError) Unwrap() error { return e.err }
+
+// WithStatus overrides the status err is answered with, when a handler
+// gives a domain error another meaning, like an unknown rate in a quote.
+func WithStatus(status int, err error) error {
+	return &statusError{status: status, err: err}
+}
+
+// NewProblem builds the problem answering err.
+func NewProblem(err error) Problem {
+	problem := problemFor(err)
+
+	var override *statusError
+	if errors.As(err, &override) {
+		problem.Status = override.status
+	}
+
+	problem.Type = "about:blank"
+	problem.Title = http.StatusText(problem.Status)
+	return problem
+}
+
+func problemFor(err error) Problem {
+	var invalid *ValidationError
+	if errors.As(err, &invalid) {
+		return Problem{Status: http.StatusBadRequest, Code: CodeValidationFailed, Detail: "validation failed", Errors: invalid.Fields}
+	}
+
+	for _, p := range domainProblems {
+		if errors.Is(err, p.err) {
+			return Problem{Status: p.status, Code: p.code, Detail: err.Error()}
+		}
+	}
+
+	// errors raised by Echo itself: unknown routes, unreadable bodies...
+	var httpErr *echo.HTTPError
+	if errors.As(err, &httpErr) {
+		detail := http.StatusText(httpErr.Code)
+		if message, ok := httpErr.Message.(string); ok {
+			detail = message
+		}
+		return Problem{Status: httpErr.Code, Code: codeForStatus(httpErr.Code), Detail: detail}
+	}
+
+	return Problem{Status: http.StatusInternalServerError, Code: CodeInternalError, Detail: "the server could not complete the request"}
+}
+
+// codeForStatus turns a status into a code, 404 becomes NOT_FOUND.
+func codeForStatus(status int) string {
+	if status == http.StatusBadRequest {
+		return CodeInvalidRequest
+	}
+	if status >= http.StatusInternalServerError {
+		return CodeInternalError
+	}
+	return strings.ToUpper(strings.NewReplacer(" ", "_", "-", "_", "'", "").Replace(http.StatusText(status)))
+}
+
+// ProblemHandler is the Echo HTTPErrorHandler answering every error as application/problem+json.
+func ProblemHandler(err error, c echo.Context) {
+	if c.Response().Committed {
+		return
+	}
+
+	problem := NewProblem(err)
+	problem.Instance = c.Request().URL.Path
+	if problem.Status >= http.StatusInternalServerError {
+		log.Printf("error: %v\n", err)
+	}
+
+	c.Response().Header().Set(echo.HeaderContentType, MIMEApplicationProblemJSON)
+	if c.Request().Method == http.MethodHead {
+		err = c.NoContent(problem.Status)
+	} else {
+		err = c.JSON(problem.Status, problem)
+	}
+	if err != nil {
+		log.Printf("error: %v\n", err)
+	}
+}
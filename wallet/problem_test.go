@@ -0,0 +1,71 @@
+package wallet
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewProblem(t *testing.T) {
+	tests := []struct {
+		name       string
+		err        error
+		wantStatus int
+		wantCode   string
+	}{
+		{name: "Domain error", err: ErrWalletNotFound, wantStatus: http.StatusNotFound, wantCode: CodeWalletNotFound},
+		{name: "Wrapped domain error", err: fmt.Errorf("%w: %q", ErrUnsupportedCurrency, "XYZ"), wantStatus: http.StatusUnprocessableEntity, wantCode: CodeUnsupportedCurrency},
+		{name: "Unreadable request", err: fmt.Errorf("%w: wallet id must be a number", ErrInvalidRequest), wantStatus: http.StatusBadRequest, wantCode: CodeInvalidRequest},
+		{name: "Invalid fields", err: &ValidationError{Fields: []FieldError{{"amount", "must be greater than 0"}}}, wantStatus: http.StatusBadRequest, wantCode: CodeValidationFailed},
+		{name: "Status override keeps the code", err: WithStatus(http.StatusNotFound, ErrRateNotFound), wantStatus: http.StatusNotFound, wantCode: CodeRateNotFound},
+		{name: "Echo error", err: echo.ErrNotFound, wantStatus: http.StatusNotFound, wantCode: "NOT_FOUND"},
+		{name: "Echo bad request", err: echo.NewHTTPError(http.StatusBadRequest, "malformed JSON"), wantStatus: http.StatusBadRequest, wantCode: CodeInvalidRequest},
+		{name: "Unknown error", err: errors.New("connection refused"), wantStatus: http.StatusInternalServerError, wantCode: CodeInternalError},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := NewProblem(test.err)
+
+			assert.Equal(t, test.wantStatus, got.Status)
+			assert.Equal(t, test.wantCode, got.Code)
+			assert.Equal(t, http.StatusText(test.wantStatus), got.Title)
+			assert.Equal(t, "about:blank", got.Type)
+		})
+	}
+
+	t.Run("Unknown error does not leak its message", func(t *testing.T) {
+		got := NewProblem(errors.New("pq: password authentication failed"))
+
+		assert.NotContains(t, got.Detail, "password")
+	})
+}
+
+func TestProblemHandler(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/wallets/7", nil)
+	rec := httptest.NewRecorder()
+	c := echo.New().NewContext(req, rec)
+
+	ProblemHandler(ErrWalletNotFound, c)
+
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+	assert.Equal(t, MIMEApplicationProblemJSON, rec.Header().Get(echo.HeaderContentType))
+	var got Problem
+	if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+		t.Errorf("expected response body to be valid json, got %s", rec.Body.String())
+	}
+	assert.Equal(t, Problem{
+		Type:     "about:blank",
+		Title:    "Not Found",
+		Status:   http.StatusNotFound,
+		Code:     CodeWalletNotFound,
+		Detail:   "wallet not found",
+		Instance: "/api/v1/wallets/7",
+	}, got)
+}
@@ -0,0 +1,106 @@
+package wallet
+
+import (
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	// RoleCustomer acts on its own user and wallets only.
+	RoleCustomer = "customer"
+	// RoleSupport reads every user and wallet and freezes or unfreezes wallets, it cannot close them.
+	RoleSupport = "support"
+	// RoleAdmin may do everything.
+	RoleAdmin = "admin"
+	// RoleAuditor reads every user and wallet and changes nothing.
+	RoleAuditor = "auditor"
+
+	contextKeyAnyOwner = "auth.any_owner"
+)
+
+// Permission is what a route needs from the roles of the caller.
+type Permission string
+
+const (
+	// PermissionOwn acts on the caller's own user and wallets.
+	PermissionOwn            Permission = "own"
+	PermissionReadUsers      Permission = "users:read"
+	PermissionManageUsers    Permission = "users:manage"
+	PermissionReadWallets    Permission = "wallets:read"
+	PermissionManageWallets  Permission = "wallets:manage"
+	PermissionWalletStatus   Permission = "wallets:status"
+	PermissionIncludeDeleted Permission = "wallets:include_deleted"
+	PermissionFXQuote        Permission = "fx:quote"
+	PermissionManageAPIKeys  Permission = "api_keys:manage"
+)
+
+// permissions are every Permission, the scopes an API key may have.
+var permissions = []Permission{
+	PermissionOwn, PermissionReadUsers, PermissionManageUsers, PermissionReadWallets, PermissionManageWallets,
+	PermissionWalletStatus, PermissionIncludeDeleted, PermissionFXQuote, PermissionManageAPIKeys,
+}
+
+// rolePermissions lists the permissions each role grants, unknown roles grant nothing.
+var rolePermissions = map[string][]Permission{
+	RoleCustomer: {PermissionOwn, PermissionFXQuote},
+	RoleSupport:  {PermissionReadUsers, PermissionReadWallets, PermissionWalletStatus, PermissionFXQuote},
+	RoleAuditor:  {PermissionReadUsers, PermissionReadWallets, PermissionFXQuote},
+	RoleAdmin: {
+		PermissionOwn, PermissionReadUsers, PermissionManageUsers, PermissionReadWallets,
+		PermissionManageWallets, PermissionWalletStatus, PermissionIncludeDeleted, PermissionFXQuote,
+		PermissionManageAPIKeys,
+	},
+}
+
+// IsPermissionValid reports whether permission is a known Permission.
+func IsPermissionValid(permission Permission) bool {
+	for _, p := range permissions {
+		if p == permission {
+			return true
+		}
+	}
+	return false
+}
+
+// Can reports whether one of the roles of the caller grants permission,
+// or for an API key one of its scopes.
+func (p Principal) Can(permission Permission) bool {
+	for _, scope := range p.Scopes {
+		if scope == permission {
+			return true
+		}
+	}
+	for _, role := range p.Roles {
+		for _, granted := range rolePermissions[role] {
+			if granted == permission {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// RequirePermission lets the request through when the caller has permission, and answers
+// 403 otherwise. Behind any permission but PermissionOwn the handlers act on every user's
+// wallets, so the ownership checks are lifted.
+func RequirePermission(permission Permission) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			p, err := caller(c)
+			if err != nil {
+				return err
+			}
+			if !p.Can(permission) {
+				return ErrForbidden
+			}
+
+			c.Set(contextKeyAnyOwner, permission != PermissionOwn)
+			return next(c)
+		}
+	}
+}
+
+// actsOnAnyOwner reports whether RequirePermission lifted the ownership checks.
+func actsOnAnyOwner(c echo.Context) bool {
+	anyOwner, _ := c.Get(contextKeyAnyOwner).(bool)
+	return anyOwner
+}
@@ -0,0 +1,156 @@
+package wallet
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/golfz/fun-exercise-api/wallet/authtest"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPrincipalCan(t *testing.T) {
+	tests := []struct {
+		roles      []string
+		permission Permission
+		want       bool
+	}{
+		{roles: nil, permission: PermissionOwn, want: true},
+		{roles: []string{RoleCustomer}, permission: PermissionReadWallets, want: false},
+		{roles: []string{RoleSupport}, permission: PermissionWalletStatus, want: true},
+		{roles: []string{RoleSupport}, permission: PermissionManageWallets, want: false},
+		{roles: []string{RoleSupport}, permission: PermissionOwn, want: false},
+		{roles: []string{RoleAuditor}, permission: PermissionReadUsers, want: true},
+		{roles: []string{RoleAuditor}, permission: PermissionWalletStatus, want: false},
+		{roles: []string{RoleAdmin}, permission: PermissionIncludeDeleted, want: true},
+		{roles: []string{RoleCustomer, RoleAuditor}, permission: PermissionReadWallets, want: true},
+		{roles: []string{"root"}, permission: PermissionOwn, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.permission), func(t *testing.T) {
+			assert.Equal(t, tt.want, NewPrincipal("1", tt.roles...).Can(tt.permission), "roles %v", tt.roles)
+		})
+	}
+}
+
+func TestRequirePermission(t *testing.T) {
+	tests := []struct {
+		name       string
+		roles      []string
+		permission Permission
+		handler    func(h *Handler) echo.HandlerFunc
+		query      string
+		wantStatus int
+		wantCall   string
+	}{
+		{
+			name:       "given a customer on an admin route should return 403",
+			roles:      []string{RoleCustomer},
+			permission: PermissionReadWallets,
+			handler:    func(h *Handler) echo.HandlerFunc { return h.GetWalletHandler },
+			wantStatus: http.StatusForbidden,
+		},
+		{
+			name:       "given support on an admin route should read another user's wallet",
+			roles:      []string{RoleSupport},
+			permission: PermissionReadWallets,
+			handler:    func(h *Handler) echo.HandlerFunc { return h.GetWalletHandler },
+			wantStatus: http.StatusOK,
+			wantCall:   "GetWalletByID",
+		},
+		{
+			name:       "given an admin on a customer route should still only touch own wallets",
+			roles:      []string{RoleAdmin},
+			permission: PermissionOwn,
+			handler:    func(h *Handler) echo.HandlerFunc { return h.GetWalletHandler },
+			wantStatus: http.StatusForbidden,
+		},
+		{
+			name:       "given an auditor should not freeze wallets",
+			roles:      []string{RoleAuditor},
+			permission: PermissionWalletStatus,
+			handler:    func(h *Handler) echo.HandlerFunc { return h.FreezeWalletHandler },
+			wantStatus: http.StatusForbidden,
+		},
+		{
+			name:       "given a customer asking for deleted wallets should return 403",
+			roles:      []string{RoleCustomer},
+			permission: PermissionOwn,
+			handler:    func(h *Handler) echo.HandlerFunc { return h.GetWalletsHandler },
+			query:      "?include_deleted=true",
+			wantStatus: http.StatusForbidden,
+		},
+		{
+			name:       "given support asking for deleted wallets should return 403",
+			roles:      []string{RoleSupport},
+			permission: PermissionReadWallets,
+			handler:    func(h *Handler) echo.HandlerFunc { return h.GetWalletsHandler },
+			query:      "?include_deleted=true",
+			wantStatus: http.StatusForbidden,
+		},
+		{
+			name:       "given an admin asking for deleted wallets should list every user's",
+			roles:      []string{RoleAdmin},
+			permission: PermissionReadWallets,
+			handler:    func(h *Handler) echo.HandlerFunc { return h.GetWalletsHandler },
+			query:      "?include_deleted=true",
+			wantStatus: http.StatusOK,
+			wantCall:   "GetWallets",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			resp, c, h, mock := testSetup(http.MethodGet, "/"+tt.query, nil)
+			c.SetParamNames("id")
+			c.SetParamValues("1")
+			SetPrincipal(c, NewPrincipal("1", tt.roles...))
+			mock.owner = 2
+
+			// Act
+			err := serve(c, RequirePermission(tt.permission)(tt.handler(h)))
+
+			// Assert
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantStatus, resp.Code)
+			if tt.wantCall != "" {
+				assert.True(t, mock.methodToCall[tt.wantCall], "expected %s to be called", tt.wantCall)
+			}
+			if tt.wantStatus == http.StatusForbidden {
+				assert.Contains(t, resp.Body.String(), CodeForbidden)
+			}
+		})
+	}
+
+	t.Run("given an admin listing wallets should not limit them to a user", func(t *testing.T) {
+		// Arrange
+		_, c, h, mock := testSetup(http.MethodGet, "/api/v1/admin/wallets?include_deleted=true", nil)
+		SetPrincipal(c, NewPrincipal("1", RoleAdmin))
+
+		// Act
+		err := serve(c, RequirePermission(PermissionReadWallets)(h.GetWalletsHandler))
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Equal(t, Filter{IncludeDeleted: true}, mock.whatIsFilter)
+	})
+}
+
+func TestJWTAuthRoles(t *testing.T) {
+	// Arrange
+	resp, c, h, mock := anonymousSetup(http.MethodGet, "/api/v1/admin/wallets", nil)
+	c.Request().Header.Set(echo.HeaderAuthorization, authtest.Bearer(t, "auditor-7", RoleAuditor))
+	handler := JWTAuth(authtest.Keys())(RequirePermission(PermissionReadWallets)(h.GetWalletsHandler))
+
+	// Act
+	err := serve(c, handler)
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.True(t, mock.methodToCall["GetWallets"])
+	p, _ := CurrentPrincipal(c)
+	assert.Equal(t, Principal{Subject: "auditor-7", Roles: []string{RoleAuditor}}, p)
+}
@@ -0,0 +1,183 @@
+package wallet
+
+import (
+	"github.com/labstack/echo/v4"
+	"net/http"
+)
+
+const (
+	WalletStatusActive = "active"
+	WalletStatusFrozen = "frozen"
+	WalletStatusClosed = "closed"
+)
+
+// statusTransitions lists the statuses each status may change to,
+// a closed wallet cannot be reopened.
+var statusTransitions = map[string][]string{
+	WalletStatusActive: {WalletStatusFrozen, WalletStatusClosed},
+	WalletStatusFrozen: {WalletStatusActive},
+}
+
+// CanChangeStatus reports whether a wallet in status from may move to status to.
+func CanChangeStatus(from, to string) bool {
+	return containsString(statusTransitions[from], to)
+}
+
+// CheckStatusChange returns ErrInvalidStatusTransition when CanChangeStatus forbids moving w
+// to status, and ErrWalletNotEmpty when w would be closed with money or debt left on it.
+func CheckStatusChange(w Wallet, status string) error {
+	if !CanChangeStatus(w.Status, status) {
+		return ErrInvalidStatusTransition
+	}
+	if status == WalletStatusClosed && !w.Balance.IsZero() {
+		return ErrWalletNotEmpty
+	}
+	return nil
+}
+
+// CheckDeletable returns ErrWalletFrozen when one of the wallets is frozen, a frozen wallet
+// cannot be soft-deleted until it is unfrozen. Active and closed wallets may be deleted,
+// and a restored wallet comes back with the status it was deleted with.
+func CheckDeletable(wallets ...Wallet) error {
+	for _, w := range wallets {
+		if w.Status == WalletStatusFrozen {
+			return ErrWalletFrozen
+		}
+	}
+	return nil
+}
+
+// CheckActive returns ErrWalletFrozen or ErrWalletClosed when the balance of one of
+// the wallets may not change, every balance change checks the wallets it touches.
+func CheckActive(wallets ...Wallet) error {
+	for _, w := range wallets {
+		switch w.Status {
+		case WalletStatusFrozen:
+			return ErrWalletFrozen
+		case WalletStatusClosed:
+			return ErrWalletClosed
+		}
+	}
+	return nil
+}
+
+type StatusChange struct {
+	Reason string `json:"reason" example:"Suspicious activity reported by compliance"`
+}
+
+// bindStatusChange reads the id of a wallet the caller owns and the reason of a status change.
+func (h *Handler) bindStatusChange(c echo.Context) (int, string, error) {
+	walletID, err := h.ownedWalletID(c)
+	if err != nil {
+		return 0, "", err
+	}
+
+	change := StatusChange{}
+	if err := c.Bind(&change); err != nil {
+		return 0, "", err
+	}
+	v := validator{}
+	v.checkName("reason", change.Reason)
+	if err := v.err(); err != nil {
+		return 0, "", err
+	}
+
+	return walletID, change.Reason, nil
+}
+
+func (h *Handler) changeStatus(c echo.Context, status string) error {
+	walletID, reason, err := h.bindStatusChange(c)
+	if err != nil {
+		return err
+	}
+
+	version, err := IfMatchVersion(c)
+	if err != nil {
+		return err
+	}
+
+	// change status
+	wallet, err := h.store.ChangeWalletStatus(walletID, version, status, reason)
+	if err != nil {
+		return err
+	}
+
+	setETag(c, wallet)
+	return c.JSON(http.StatusOK, wallet)
+}
+
+// FreezeWalletHandler
+//
+//	@Summary		Freeze wallet
+//	@Description	Stop every balance change of an active wallet until it is unfrozen
+//	@Tags			wallet status
+//	@Accept			json
+//	@Produce		json
+//	@Param			id		path	int				true	"Wallet ID"
+//	@Param			change	body	StatusChange	true	"Why the wallet is frozen"
+//	@Param			If-Match	header	string	true	"ETag of the wallet from a previous read"
+//	@Param			Idempotency-Key	header	string	false	"Key to run a retried request only once"
+//	@Success		200	{object}	Wallet
+//	@Header			200	{string}	ETag	"New version of the wallet"
+//	@Failure		400	{object}	Problem
+//	@Failure		403	{object}	Problem
+//	@Failure		404	{object}	Problem
+//	@Failure		409	{object}	Problem
+//	@Failure		412	{object}	Problem
+//	@Failure		428	{object}	Problem
+//	@Failure		500	{object}	Problem
+//	@Router			/api/v1/admin/wallets/{id}/freeze [post]
+func (h *Handler) FreezeWalletHandler(c echo.Context) error {
+	return h.changeStatus(c, WalletStatusFrozen)
+}
+
+// UnfreezeWalletHandler
+//
+//	@Summary		Unfreeze wallet
+//	@Description	Make a frozen wallet active again
+//	@Tags			wallet status
+//	@Accept			json
+//	@Produce		json
+//	@Param			id		path	int				true	"Wallet ID"
+//	@Param			change	body	StatusChange	true	"Why the wallet is unfrozen"
+//	@Param			If-Match	header	string	true	"ETag of the wallet from a previous read"
+//	@Param			Idempotency-Key	header	string	false	"Key to run a retried request only once"
+//	@Success		200	{object}	Wallet
+//	@Header			200	{string}	ETag	"New version of the wallet"
+//	@Failure		400	{object}	Problem
+//	@Failure		403	{object}	Problem
+//	@Failure		404	{object}	Problem
+//	@Failure		409	{object}	Problem
+//	@Failure		412	{object}	Problem
+//	@Failure		428	{object}	Problem
+//	@Failure		500	{object}	Problem
+//	@Router			/api/v1/admin/wallets/{id}/unfreeze [post]
+func (h *Handler) UnfreezeWalletHandler(c echo.Context) error {
+	return h.changeStatus(c, WalletStatusActive)
+}
+
+// CloseWalletHandler
+//
+//	@Summary		Close wallet
+//	@Description	Close an active wallet for good, a closed wallet cannot be reopened
+//	@Tags			wallet status
+//	@Accept			json
+//	@Produce		json
+//	@Param			id		path	int				true	"Wallet ID"
+//	@Param			change	body	StatusChange	true	"Why the wallet is closed"
+//	@Param			If-Match	header	string	true	"ETag of the wallet from a previous read"
+//	@Param			Idempotency-Key	header	string	false	"Key to run a retried request only once"
+//	@Success		200	{object}	Wallet
+//	@Header			200	{string}	ETag	"New version of the wallet"
+//	@Failure		400	{object}	Problem
+//	@Failure		403	{object}	Problem
+//	@Failure		404	{object}	Problem
+//	@Failure		409	{object}	Problem
+//	@Failure		412	{object}	Problem
+//	@Failure		428	{object}	Problem
+//	@Failure		500	{object}	Problem
+//	@Router			/api/v1/wallets/{id}/close [post]
+//	@Router			/api/v1/admin/wallets/{id}/close [post]
+func (h *Handler) CloseWalletHandler(c echo.Context) error {
+	return h.changeStatus(c, WalletStatusClosed)
+}
@@ -0,0 +1,163 @@
+package wallet
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCanChangeStatus(t *testing.T) {
+	tests := []struct {
+		from, to string
+		want     bool
+	}{
+		{from: WalletStatusActive, to: WalletStatusFrozen, want: true},
+		{from: WalletStatusFrozen, to: WalletStatusActive, want: true},
+		{from: WalletStatusActive, to: WalletStatusClosed, want: true},
+		{from: WalletStatusActive, to: WalletStatusActive, want: false},
+		{from: WalletStatusFrozen, to: WalletStatusClosed, want: false},
+		{from: WalletStatusClosed, to: WalletStatusActive, want: false},
+		{from: WalletStatusClosed, to: WalletStatusFrozen, want: false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.from+" to "+test.to, func(t *testing.T) {
+			assert.Equal(t, test.want, CanChangeStatus(test.from, test.to))
+		})
+	}
+}
+
+func TestCheckStatusChange(t *testing.T) {
+	empty := Wallet{Status: WalletStatusActive}
+	funded := Wallet{Status: WalletStatusActive, Balance: MustParseMoney("0.01")}
+	indebted := Wallet{Status: WalletStatusActive, Balance: MustParseMoney("-5")}
+
+	assert.NoError(t, CheckStatusChange(empty, WalletStatusClosed))
+	assert.NoError(t, CheckStatusChange(funded, WalletStatusFrozen))
+	assert.ErrorIs(t, CheckStatusChange(funded, WalletStatusClosed), ErrWalletNotEmpty)
+	assert.ErrorIs(t, CheckStatusChange(indebted, WalletStatusClosed), ErrWalletNotEmpty)
+	assert.ErrorIs(t, CheckStatusChange(Wallet{Status: WalletStatusFrozen}, WalletStatusClosed), ErrInvalidStatusTransition)
+}
+
+func TestCheckDeletable(t *testing.T) {
+	active := Wallet{Status: WalletStatusActive}
+
+	assert.NoError(t, CheckDeletable(active, Wallet{Status: WalletStatusClosed}))
+	assert.ErrorIs(t, CheckDeletable(active, Wallet{Status: WalletStatusFrozen}), ErrWalletFrozen)
+}
+
+func TestCheckActive(t *testing.T) {
+	active := Wallet{Status: WalletStatusActive}
+
+	assert.NoError(t, CheckActive(active, active))
+	assert.ErrorIs(t, CheckActive(active, Wallet{Status: WalletStatusFrozen}), ErrWalletFrozen)
+	assert.ErrorIs(t, CheckActive(Wallet{Status: WalletStatusClosed}, active), ErrWalletClosed)
+}
+
+func TestChangeStatus(t *testing.T) {
+	statusSetup := func(action, body string) (resp *httptest.ResponseRecorder, c echo.Context, h *Handler, mock *mockWalletStorer) {
+		resp, c, h, mock = testSetup(http.MethodPost, "/", strings.NewReader(body))
+		c.Request().Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		c.SetPath("/api/v1/wallets/:id/" + action)
+		c.SetParamNames("id")
+		c.SetParamValues("7")
+		c.Request().Header.Set(HeaderIfMatch, `"1"`)
+		return resp, c, h, mock
+	}
+
+	t.Run("given a reason should freeze the wallet", func(t *testing.T) {
+		// Arrange
+		resp, c, h, mock := statusSetup("freeze", `{"reason": "Reported stolen"}`)
+		mock.wallet = Wallet{ID: 7, Status: WalletStatusFrozen, StatusReason: "Reported stolen", Version: 2}
+		mock.ExpectToCall("ChangeWalletStatus")
+
+		// Act
+		err := serve(c, h.FreezeWalletHandler)
+
+		// Assert
+		mock.Verify(t)
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusOK, resp.Code)
+		assert.Equal(t, 7, mock.whatIsID)
+		assert.Equal(t, 1, mock.whatIsVersion)
+		assert.Equal(t, WalletStatusFrozen, mock.whatIsStatus)
+		assert.Equal(t, "Reported stolen", mock.whatIsReason)
+		assert.Equal(t, `"2"`, resp.Header().Get(HeaderETag))
+	})
+
+	t.Run("unfreeze and close ask for their status", func(t *testing.T) {
+		for handler, status := range map[string]string{"unfreeze": WalletStatusActive, "close": WalletStatusClosed} {
+			_, c, h, mock := statusSetup(handler, `{"reason": "Cleared by compliance"}`)
+			handle := h.UnfreezeWalletHandler
+			if handler == "close" {
+				handle = h.CloseWalletHandler
+			}
+
+			assert.NoError(t, serve(c, handle))
+			assert.Equal(t, status, mock.whatIsStatus)
+		}
+	})
+
+	t.Run("given no reason should return 400 and not call the store", func(t *testing.T) {
+		// Arrange
+		resp, c, h, mock := statusSetup("freeze", `{"reason": " "}`)
+
+		// Act
+		err := serve(c, h.FreezeWalletHandler)
+
+		// Assert
+		assert.NoError(t, err)
+		assert.False(t, mock.methodToCall["ChangeWalletStatus"])
+		assert.Equal(t, http.StatusBadRequest, resp.Code)
+		got := Problem{}
+		assert.NoError(t, json.Unmarshal(resp.Body.Bytes(), &got))
+		assert.Equal(t, []FieldError{{Field: "reason", Reason: "must not be empty"}}, got.Errors)
+	})
+
+	t.Run("given no If-Match should return 428 and not call the store", func(t *testing.T) {
+		// Arrange
+		resp, c, h, mock := statusSetup("freeze", `{"reason": "Reported stolen"}`)
+		c.Request().Header.Del(HeaderIfMatch)
+
+		// Act
+		err := serve(c, h.FreezeWalletHandler)
+
+		// Assert
+		assert.NoError(t, err)
+		assert.False(t, mock.methodToCall["ChangeWalletStatus"])
+		assert.Equal(t, http.StatusPreconditionRequired, resp.Code)
+	})
+
+	t.Run("given a wallet with a balance should not close it and return 409", func(t *testing.T) {
+		// Arrange
+		resp, c, h, mock := statusSetup("close", `{"reason": "Customer request"}`)
+		mock.err = ErrWalletNotEmpty
+
+		// Act
+		err := serve(c, h.CloseWalletHandler)
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusConflict, resp.Code)
+		assert.Contains(t, resp.Body.String(), CodeWalletNotEmpty)
+	})
+
+	t.Run("given a closed wallet should return 409", func(t *testing.T) {
+		// Arrange
+		resp, c, h, mock := statusSetup("unfreeze", `{"reason": "Reopen"}`)
+		mock.err = ErrInvalidStatusTransition
+
+		// Act
+		err := serve(c, h.UnfreezeWalletHandler)
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusConflict, resp.Code)
+		assert.Contains(t, resp.Body.String(), CodeInvalidStatusTransition)
+	})
+}
@@ -0,0 +1,108 @@
+package storertest
+
+import (
+	"testing"
+	"time"
+
+	"github.com/golfz/fun-exercise-api/wallet"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func createAPIKey(t *testing.T, store wallet.Storer, ownerID int, scopes ...wallet.Permission) wallet.APIKey {
+	t.Helper()
+	_, prefix, hash := wallet.NewAPIKey()
+	k := wallet.APIKey{Prefix: prefix, Hash: hash, Name: "batch", OwnerID: ownerID, Scopes: scopes}
+	require.NoError(t, store.CreateAPIKey(&k))
+	return k
+}
+
+func testAPIKeys(t *testing.T, store wallet.Storer) {
+	t.Run("creates and finds a key by its prefix", func(t *testing.T) {
+		k := createAPIKey(t, store, 1, wallet.PermissionOwn, wallet.PermissionFXQuote)
+
+		assert.NotZero(t, k.ID)
+		assert.False(t, k.CreatedAt.IsZero())
+		assert.Nil(t, k.LastUsedAt)
+		assert.Nil(t, k.RevokedAt)
+		got, err := store.GetAPIKeyByPrefix(k.Prefix)
+		require.NoError(t, err)
+		assert.Equal(t, k.Hash, got.Hash)
+		assert.Equal(t, 1, got.OwnerID)
+		assert.Equal(t, []wallet.Permission{wallet.PermissionOwn, wallet.PermissionFXQuote}, got.Scopes)
+		keys, err := store.GetAPIKeys()
+		require.NoError(t, err)
+		require.Len(t, keys, 1)
+		assert.Equal(t, k.ID, keys[0].ID)
+	})
+
+	t.Run("key of an unknown user is refused", func(t *testing.T) {
+		k := wallet.APIKey{Prefix: "0000000000000000", Hash: "hash", Name: "batch", OwnerID: 99, Scopes: []wallet.Permission{wallet.PermissionOwn}}
+
+		assert.ErrorIs(t, store.CreateAPIKey(&k), wallet.ErrUserNotFound)
+	})
+
+	t.Run("records when the key was used", func(t *testing.T) {
+		k := createAPIKey(t, store, 1, wallet.PermissionOwn)
+		at := time.Date(2024, 3, 26, 2, 0, 0, 0, time.UTC)
+
+		require.NoError(t, store.TouchAPIKey(k.ID, at))
+
+		got, err := store.GetAPIKeyByPrefix(k.Prefix)
+		require.NoError(t, err)
+		require.NotNil(t, got.LastUsedAt)
+		assert.True(t, at.Equal(*got.LastUsedAt))
+	})
+
+	t.Run("rotation replaces prefix and hash", func(t *testing.T) {
+		k := createAPIKey(t, store, 2, wallet.PermissionOwn)
+		_, prefix, hash := wallet.NewAPIKey()
+
+		rotated, err := store.RotateAPIKey(k.ID, prefix, hash)
+
+		require.NoError(t, err)
+		assert.Equal(t, k.ID, rotated.ID)
+		assert.Equal(t, prefix, rotated.Prefix)
+		assert.Equal(t, hash, rotated.Hash)
+		assert.Equal(t, k.Scopes, rotated.Scopes)
+		_, err = store.GetAPIKeyByPrefix(k.Prefix)
+		assert.ErrorIs(t, err, wallet.ErrAPIKeyNotFound)
+	})
+
+	t.Run("revoked key stays revoked and cannot be rotated", func(t *testing.T) {
+		k := createAPIKey(t, store, 2, wallet.PermissionOwn)
+
+		revoked, err := store.RevokeAPIKey(k.ID)
+		require.NoError(t, err)
+		require.NotNil(t, revoked.RevokedAt)
+		again, err := store.RevokeAPIKey(k.ID)
+		require.NoError(t, err)
+
+		require.NotNil(t, again.RevokedAt)
+		assert.True(t, revoked.RevokedAt.Equal(*again.RevokedAt))
+		_, prefix, hash := wallet.NewAPIKey()
+		_, err = store.RotateAPIKey(k.ID, prefix, hash)
+		assert.ErrorIs(t, err, wallet.ErrAPIKeyRevoked)
+	})
+
+	t.Run("keys go with their user", func(t *testing.T) {
+		u := wallet.User{Name: "Batch Owner"}
+		require.NoError(t, store.CreateUser(&u))
+		k := createAPIKey(t, store, u.ID, wallet.PermissionOwn)
+
+		require.NoError(t, store.DeleteUser(u.ID))
+
+		_, err := store.GetAPIKeyByPrefix(k.Prefix)
+		assert.ErrorIs(t, err, wallet.ErrAPIKeyNotFound)
+	})
+
+	t.Run("unknown key", func(t *testing.T) {
+		_, err := store.GetAPIKeyByPrefix("ffffffffffffffff")
+		assert.ErrorIs(t, err, wallet.ErrAPIKeyNotFound)
+		_, err = store.RotateAPIKey(99, "ffffffffffffffff", "hash")
+		assert.ErrorIs(t, err, wallet.ErrAPIKeyNotFound)
+		_, err = store.RevokeAPIKey(99)
+		assert.ErrorIs(t, err, wallet.ErrAPIKeyNotFound)
+		assert.ErrorIs(t, store.TouchAPIKey(99, time.Now()), wallet.ErrAPIKeyNotFound)
+	})
+}
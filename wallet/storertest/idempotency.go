@@ -0,0 +1,126 @@
+package storertest
+
+import (
+	"testing"
+	"time"
+
+	"github.com/golfz/fun-exercise-api/wallet"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// RunIdempotency exercises the IdempotencyStorer returned by newStore, which must be empty for every call.
+func RunIdempotency(t *testing.T, newStore func(t *testing.T) wallet.IdempotencyStorer) {
+	tests := []struct {
+		name string
+		test func(t *testing.T, store wallet.IdempotencyStorer)
+	}{
+		{name: "ReserveAndComplete", test: testReserveAndComplete},
+		{name: "ExpiredKey", test: testExpiredIdempotencyKey},
+		{name: "PurgeExpiredKeys", test: testPurgeExpiredIdempotencyKeys},
+		{name: "DeleteIdempotencyKey", test: testDeleteIdempotencyKey},
+		{name: "UnknownKey", test: testUnknownIdempotencyKey},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.test(t, newStore(t))
+		})
+	}
+}
+
+func newRecord(key string, createdAt time.Time) wallet.IdempotencyRecord {
+	return wallet.IdempotencyRecord{
+		Key:         key,
+		RequestHash: wallet.RequestHash("POST", "/api/v1/wallets", []byte(key)),
+		CreatedAt:   createdAt,
+		ExpiresAt:   createdAt.Add(time.Hour),
+	}
+}
+
+func testReserveAndComplete(t *testing.T, store wallet.IdempotencyStorer) {
+	record := newRecord("create-1", time.Now().UTC())
+
+	require.NoError(t, store.ReserveIdempotencyKey(record))
+
+	t.Run("is in progress until completed", func(t *testing.T) {
+		got, err := store.GetIdempotencyKey(record.Key)
+
+		require.NoError(t, err)
+		assert.Equal(t, record.RequestHash, got.RequestHash)
+		assert.False(t, got.Completed())
+	})
+
+	t.Run("cannot be reserved twice", func(t *testing.T) {
+		assert.ErrorIs(t, store.ReserveIdempotencyKey(newRecord(record.Key, time.Now().UTC())), wallet.ErrIdempotencyKeyInUse)
+	})
+
+	t.Run("keeps the completed response", func(t *testing.T) {
+		completed := record
+		completed.StatusCode = 201
+		completed.ContentType = "application/json"
+		completed.ETag = `"1"`
+		completed.Body = []byte(`{"id":1}`)
+
+		require.NoError(t, store.CompleteIdempotencyKey(completed))
+
+		got, err := store.GetIdempotencyKey(record.Key)
+		require.NoError(t, err)
+		assert.True(t, got.Completed())
+		assert.Equal(t, 201, got.StatusCode)
+		assert.Equal(t, "application/json", got.ContentType)
+		assert.Equal(t, `"1"`, got.ETag)
+		assert.Equal(t, `{"id":1}`, string(got.Body))
+		assert.True(t, got.ExpiresAt.After(got.CreatedAt))
+	})
+}
+
+func testExpiredIdempotencyKey(t *testing.T, store wallet.IdempotencyStorer) {
+	expired := newRecord("create-1", time.Now().UTC().Add(-2*time.Hour))
+	require.NoError(t, store.ReserveIdempotencyKey(expired))
+	expired.StatusCode = 201
+	require.NoError(t, store.CompleteIdempotencyKey(expired))
+
+	fresh := newRecord("create-1", time.Now().UTC())
+	fresh.RequestHash = wallet.RequestHash("POST", "/api/v1/wallets", []byte("another body"))
+
+	require.NoError(t, store.ReserveIdempotencyKey(fresh))
+	got, err := store.GetIdempotencyKey(fresh.Key)
+	require.NoError(t, err)
+	assert.Equal(t, fresh.RequestHash, got.RequestHash)
+	assert.False(t, got.Completed())
+}
+
+func testPurgeExpiredIdempotencyKeys(t *testing.T, store wallet.IdempotencyStorer) {
+	expired := newRecord("create-1", time.Now().UTC().Add(-2*time.Hour))
+	require.NoError(t, store.ReserveIdempotencyKey(expired))
+	live := newRecord("create-2", time.Now().UTC().Add(-time.Minute))
+	require.NoError(t, store.ReserveIdempotencyKey(live))
+
+	require.NoError(t, store.ReserveIdempotencyKey(newRecord("create-3", time.Now().UTC())))
+
+	_, err := store.GetIdempotencyKey(expired.Key)
+	assert.ErrorIs(t, err, wallet.ErrIdempotencyKeyNotFound)
+	_, err = store.GetIdempotencyKey(live.Key)
+	assert.NoError(t, err)
+}
+
+func testDeleteIdempotencyKey(t *testing.T, store wallet.IdempotencyStorer) {
+	record := newRecord("create-1", time.Now().UTC())
+	require.NoError(t, store.ReserveIdempotencyKey(record))
+
+	require.NoError(t, store.DeleteIdempotencyKey(record.Key))
+
+	_, err := store.GetIdempotencyKey(record.Key)
+	assert.ErrorIs(t, err, wallet.ErrIdempotencyKeyNotFound)
+	assert.NoError(t, store.ReserveIdempotencyKey(record))
+}
+
+func testUnknownIdempotencyKey(t *testing.T, store wallet.IdempotencyStorer) {
+	_, err := store.GetIdempotencyKey("unknown")
+	assert.ErrorIs(t, err, wallet.ErrIdempotencyKeyNotFound)
+
+	record := newRecord("unknown", time.Now().UTC())
+	record.StatusCode = 200
+	assert.ErrorIs(t, store.CompleteIdempotencyKey(record), wallet.ErrIdempotencyKeyNotFound)
+}
@@ -0,0 +1,828 @@
+// Package storertest is the conformance suite every wallet.Storer must pass,
+// so the Postgres, SQLite and in-memory stores behave the same way.
+package storertest
+
+import (
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/golfz/fun-exercise-api/wallet"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Run exercises the Storer returned by newStore, which must be empty for every call.
+// Every test starts with the users of seedUsers, so wallets may use user id 1 and 2.
+func Run(t *testing.T, newStore func(t *testing.T) wallet.Storer) {
+	tests := []struct {
+		name string
+		test func(t *testing.T, store wallet.Storer)
+	}{
+		{name: "Users", test: testUsers},
+		{name: "APIKeys", test: testAPIKeys},
+		{name: "GetWallets", test: testGetWallets},
+		{name: "Pagination", test: testPagination},
+		{name: "GetWalletByID", test: testGetWalletByID},
+		{name: "GetWalletOwner", test: testGetWalletOwner},
+		{name: "CreateWallet", test: testCreateWallet},
+		{name: "DuplicateWallet", test: testDuplicateWallet},
+		{name: "UpdateWallet", test: testUpdateWallet},
+		{name: "PatchWallet", test: testPatchWallet},
+		{name: "DeleteWallet", test: testDeleteWallet},
+		{name: "DeleteUserWallets", test: testDeleteUserWallets},
+		{name: "RestoreWallet", test: testRestoreWallet},
+		{name: "WalletStatus", test: testWalletStatus},
+		{name: "DepositAndWithdraw", test: testDepositAndWithdraw},
+		{name: "Transfer", test: testTransfer},
+		{name: "Convert", test: testConvert},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := newStore(t)
+			seedUsers(t, store)
+			tt.test(t, store)
+		})
+	}
+}
+
+// seedUsers creates John Doe with id 1 and Jane Roe with id 2.
+func seedUsers(t *testing.T, store wallet.Storer) {
+	t.Helper()
+	for i, name := range []string{"John Doe", "Jane Roe"} {
+		u := wallet.User{Name: name}
+		require.NoError(t, store.CreateUser(&u))
+		require.Equal(t, i+1, u.ID)
+	}
+}
+
+func createWallet(t *testing.T, store wallet.Storer, w wallet.Wallet) wallet.Wallet {
+	t.Helper()
+	if w.Currency == "" {
+		w.Currency = wallet.DefaultCurrency
+	}
+	if w.WalletName == "" {
+		w.WalletName = w.WalletType + " " + w.Currency
+	}
+	require.NoError(t, store.CreateWallet(&w))
+	return w
+}
+
+func currentVersion(t *testing.T, store wallet.Storer, id int) int {
+	t.Helper()
+	w, err := store.GetWalletByID(id)
+	require.NoError(t, err)
+	return w.Version
+}
+
+func walletIDs(wallets []wallet.Wallet) []int {
+	ids := make([]int, 0)
+	for _, w := range wallets {
+		ids = append(ids, w.ID)
+	}
+	return ids
+}
+
+func balanceOf(t *testing.T, store wallet.Storer, id int) wallet.Money {
+	t.Helper()
+	return walletByID(t, store, id).Balance
+}
+
+func walletByID(t *testing.T, store wallet.Storer, id int) wallet.Wallet {
+	t.Helper()
+	w, err := store.GetWalletByID(id)
+	require.NoError(t, err)
+	return w
+}
+
+// listWallets returns the first page of wallets matching the filter, in id order.
+func listWallets(t *testing.T, store wallet.Storer, filter wallet.Filter) []wallet.Wallet {
+	t.Helper()
+	page, err := store.GetWallets(filter, wallet.DefaultPage())
+	require.NoError(t, err)
+	return page.Wallets
+}
+
+func testGetWallets(t *testing.T, store wallet.Storer) {
+	savings := createWallet(t, store, wallet.Wallet{UserID: 1, WalletName: "John Savings", WalletType: wallet.WalletTypeSavings, Balance: wallet.MustParseMoney("100")})
+	credit := createWallet(t, store, wallet.Wallet{UserID: 1, WalletName: "john_card", WalletType: wallet.WalletTypeCreditCard, Currency: wallet.CurrencyUSD, Balance: wallet.MustParseMoney("-20.50")})
+	other := createWallet(t, store, wallet.Wallet{UserID: 2, WalletName: "Jane Savings", WalletType: wallet.WalletTypeSavings, Balance: wallet.MustParseMoney("100.01")})
+	crypto := createWallet(t, store, wallet.Wallet{UserID: 2, WalletName: "Jane Crypto", WalletType: wallet.WalletTypeCryptoWallet})
+
+	money := func(s string) *wallet.Money {
+		m := wallet.MustParseMoney(s)
+		return &m
+	}
+	before := savings.CreatedAt.Add(-time.Hour)
+	after := crypto.CreatedAt.Add(time.Hour)
+
+	tests := []struct {
+		name    string
+		filter  wallet.Filter
+		wantIDs []int
+	}{
+		{name: "no filter returns every wallet ordered by id", filter: wallet.Filter{}, wantIDs: []int{savings.ID, credit.ID, other.ID, crypto.ID}},
+		{name: "by wallet type", filter: wallet.Filter{WalletTypes: []string{wallet.WalletTypeSavings}}, wantIDs: []int{savings.ID, other.ID}},
+		{name: "by several wallet types", filter: wallet.Filter{WalletTypes: []string{wallet.WalletTypeCreditCard, wallet.WalletTypeCryptoWallet}}, wantIDs: []int{credit.ID, crypto.ID}},
+		{name: "by user id", filter: wallet.Filter{UserID: 1}, wantIDs: []int{savings.ID, credit.ID}},
+		{name: "by currency", filter: wallet.Filter{Currency: wallet.CurrencyUSD}, wantIDs: []int{credit.ID}},
+		{name: "by user id and wallet type", filter: wallet.Filter{UserID: 2, WalletTypes: []string{wallet.WalletTypeCreditCard}}, wantIDs: []int{}},
+		{name: "by min balance, inclusive", filter: wallet.Filter{MinBalance: money("100")}, wantIDs: []int{savings.ID, other.ID}},
+		{name: "by max balance, inclusive", filter: wallet.Filter{MaxBalance: money("0")}, wantIDs: []int{credit.ID, crypto.ID}},
+		{name: "by balance range", filter: wallet.Filter{MinBalance: money("-20.50"), MaxBalance: money("100")}, wantIDs: []int{savings.ID, credit.ID, crypto.ID}},
+		{name: "by created range around every wallet", filter: wallet.Filter{CreatedAfter: &before, CreatedBefore: &after}, wantIDs: []int{savings.ID, credit.ID, other.ID, crypto.ID}},
+		{name: "by created after every wallet", filter: wallet.Filter{CreatedAfter: &after}, wantIDs: []int{}},
+		{name: "by wallet name prefix ignoring case", filter: wallet.Filter{WalletName: "JOHN"}, wantIDs: []int{savings.ID, credit.ID}},
+		{name: "by wallet name prefix matches wildcards literally", filter: wallet.Filter{WalletName: "john_"}, wantIDs: []int{credit.ID}},
+		{name: "by wallet name is a prefix, not a substring", filter: wallet.Filter{WalletName: "Savings"}, wantIDs: []int{}},
+		{name: "by user name anywhere in the name", filter: wallet.Filter{UserName: "roe"}, wantIDs: []int{other.ID, crypto.ID}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			page, err := store.GetWallets(test.filter, wallet.DefaultPage())
+
+			assert.NoError(t, err)
+			assert.Equal(t, test.wantIDs, walletIDs(page.Wallets))
+			assert.Empty(t, page.NextCursor)
+		})
+	}
+}
+
+func testPagination(t *testing.T, store wallet.Storer) {
+	b := createWallet(t, store, wallet.Wallet{UserID: 1, WalletName: "b", WalletType: wallet.WalletTypeSavings, Balance: wallet.MustParseMoney("20")})
+	a := createWallet(t, store, wallet.Wallet{UserID: 1, WalletName: "a", WalletType: wallet.WalletTypeSavings, Balance: wallet.MustParseMoney("30")})
+	d := createWallet(t, store, wallet.Wallet{UserID: 1, WalletName: "d", WalletType: wallet.WalletTypeSavings, Balance: wallet.MustParseMoney("10.5")})
+	c := createWallet(t, store, wallet.Wallet{UserID: 1, WalletName: "c", WalletType: wallet.WalletTypeSavings, Balance: wallet.MustParseMoney("20")})
+	other := createWallet(t, store, wallet.Wallet{UserID: 2, WalletName: "e", WalletType: wallet.WalletTypeSavings})
+
+	tests := []struct {
+		name    string
+		sort    string
+		desc    bool
+		wantIDs []int
+	}{
+		{name: "by id", sort: wallet.SortByID, wantIDs: []int{b.ID, a.ID, d.ID, c.ID}},
+		{name: "by id descending", sort: wallet.SortByID, desc: true, wantIDs: []int{c.ID, d.ID, a.ID, b.ID}},
+		{name: "by balance with ties broken by id", sort: wallet.SortByBalance, wantIDs: []int{d.ID, b.ID, c.ID, a.ID}},
+		{name: "by balance descending", sort: wallet.SortByBalance, desc: true, wantIDs: []int{a.ID, c.ID, b.ID, d.ID}},
+		{name: "by wallet name", sort: wallet.SortByWalletName, wantIDs: []int{a.ID, b.ID, c.ID, d.ID}},
+		{name: "by created at", sort: wallet.SortByCreatedAt, wantIDs: []int{b.ID, a.ID, d.ID, c.ID}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			page := wallet.Page{Limit: 3, Sort: test.sort, Desc: test.desc}
+			var gotIDs []int
+			for pages := 0; pages < 2; pages++ {
+				got, err := store.GetWallets(wallet.Filter{UserID: 1}, page)
+				require.NoError(t, err)
+				gotIDs = append(gotIDs, walletIDs(got.Wallets)...)
+				if got.NextCursor == "" {
+					break
+				}
+
+				cursor, err := wallet.DecodeCursor(got.NextCursor)
+				require.NoError(t, err)
+				page.Cursor = &cursor
+			}
+
+			assert.Equal(t, test.wantIDs, gotIDs)
+			assert.NotContains(t, gotIDs, other.ID)
+		})
+	}
+
+	t.Run("rejects an unknown sort", func(t *testing.T) {
+		_, err := store.GetWallets(wallet.Filter{}, wallet.Page{Limit: 3, Sort: "user_name; DROP TABLE user_wallet"})
+
+		assert.ErrorIs(t, err, wallet.ErrInvalidPage)
+	})
+}
+
+func testGetWalletByID(t *testing.T, store wallet.Storer) {
+	created := createWallet(t, store, wallet.Wallet{UserID: 1, WalletType: wallet.WalletTypeSavings, Balance: wallet.MustParseMoney("12.34")})
+
+	got, err := store.GetWalletByID(created.ID)
+	require.NoError(t, err)
+	assert.Equal(t, created.ID, got.ID)
+	assert.Equal(t, created.WalletName, got.WalletName)
+	assert.Equal(t, created.Balance, got.Balance)
+
+	_, err = store.GetWalletByID(created.ID + 1)
+	assert.ErrorIs(t, err, wallet.ErrWalletNotFound)
+}
+
+func testGetWalletOwner(t *testing.T, store wallet.Storer) {
+	w := createWallet(t, store, wallet.Wallet{UserID: 2, WalletType: wallet.WalletTypeSavings})
+
+	t.Run("returns the user of the wallet", func(t *testing.T) {
+		owner, err := store.GetWalletOwner(w.ID)
+
+		require.NoError(t, err)
+		assert.Equal(t, 2, owner)
+	})
+
+	t.Run("soft-deleted wallet still has its owner", func(t *testing.T) {
+		require.NoError(t, store.DeleteWallet(w.ID, w.Version))
+
+		owner, err := store.GetWalletOwner(w.ID)
+
+		require.NoError(t, err)
+		assert.Equal(t, 2, owner)
+	})
+
+	t.Run("unknown wallet", func(t *testing.T) {
+		_, err := store.GetWalletOwner(999)
+
+		assert.ErrorIs(t, err, wallet.ErrWalletNotFound)
+	})
+}
+
+func testCreateWallet(t *testing.T, store wallet.Storer) {
+	w := wallet.Wallet{
+		UserID:     1,
+		WalletName: "John's BTC",
+		WalletType: wallet.WalletTypeCryptoWallet,
+		Currency:   wallet.CurrencyBTC,
+		Balance:    wallet.MustParseMoney("0.12345678"),
+	}
+	// fields the store sets itself are ignored
+	deletedAt := time.Now().UTC()
+	w.Status, w.Version, w.DeletedAt = wallet.WalletStatusFrozen, 7, &deletedAt
+
+	err := store.CreateWallet(&w)
+
+	require.NoError(t, err)
+	assert.NotZero(t, w.ID)
+	assert.False(t, w.CreatedAt.IsZero())
+	assert.Equal(t, wallet.WalletStatusActive, w.Status)
+	assert.Equal(t, 1, w.Version)
+	assert.Nil(t, w.DeletedAt)
+	wallets := listWallets(t, store, wallet.Filter{UserID: 1})
+	require.Len(t, wallets, 1)
+	assert.Equal(t, w.ID, wallets[0].ID)
+	assert.Equal(t, "John Doe", w.UserName)
+	assert.Equal(t, "John Doe", wallets[0].UserName)
+	assert.Equal(t, "John's BTC", wallets[0].WalletName)
+	assert.Equal(t, wallet.CurrencyBTC, wallets[0].Currency)
+	assert.Equal(t, wallet.MustParseMoney("0.12345678"), wallets[0].Balance)
+
+	transactions, err := store.GetTransactions(w.ID)
+	require.NoError(t, err)
+	require.Len(t, transactions, 1)
+	assert.Equal(t, wallet.ReasonOpeningBalance, transactions[0].Reason)
+	assert.Equal(t, wallet.TransactionTypeCredit, transactions[0].Type)
+	assert.Equal(t, w.Balance, transactions[0].Amount)
+}
+
+func testUsers(t *testing.T, store wallet.Storer) {
+	t.Run("creates and reads users", func(t *testing.T) {
+		u := wallet.User{Name: "Max Mustermann"}
+
+		require.NoError(t, store.CreateUser(&u))
+
+		assert.NotZero(t, u.ID)
+		assert.False(t, u.CreatedAt.IsZero())
+		got, err := store.GetUserByID(u.ID)
+		require.NoError(t, err)
+		assert.Equal(t, "Max Mustermann", got.Name)
+		users, err := store.GetUsers()
+		require.NoError(t, err)
+		require.Len(t, users, 3)
+		assert.Equal(t, u.ID, users[2].ID)
+	})
+
+	t.Run("wallet shows the current user name", func(t *testing.T) {
+		w := createWallet(t, store, wallet.Wallet{UserID: 2, WalletType: wallet.WalletTypeSavings})
+		u := wallet.User{ID: 2, Name: "Jane Doe"}
+
+		require.NoError(t, store.UpdateUser(&u))
+
+		assert.Equal(t, "Jane Doe", u.Name)
+		got, err := store.GetWalletByID(w.ID)
+		require.NoError(t, err)
+		assert.Equal(t, "Jane Doe", got.UserName)
+	})
+
+	t.Run("wallet of an unknown user is refused", func(t *testing.T) {
+		w := wallet.Wallet{UserID: 99, WalletName: "Savings", WalletType: wallet.WalletTypeSavings, Currency: wallet.DefaultCurrency}
+
+		assert.ErrorIs(t, store.CreateWallet(&w), wallet.ErrUserNotFound)
+	})
+
+	t.Run("user with wallets cannot be deleted", func(t *testing.T) {
+		w := createWallet(t, store, wallet.Wallet{UserID: 1, WalletType: wallet.WalletTypeSavings})
+		require.NoError(t, store.DeleteWallet(w.ID, w.Version))
+
+		assert.ErrorIs(t, store.DeleteUser(1), wallet.ErrUserHasWallets)
+	})
+
+	t.Run("user without wallets is deleted", func(t *testing.T) {
+		u := wallet.User{Name: "Erika Mustermann"}
+		require.NoError(t, store.CreateUser(&u))
+
+		require.NoError(t, store.DeleteUser(u.ID))
+
+		_, err := store.GetUserByID(u.ID)
+		assert.ErrorIs(t, err, wallet.ErrUserNotFound)
+	})
+
+	t.Run("unknown user", func(t *testing.T) {
+		_, err := store.GetUserByID(99)
+		assert.ErrorIs(t, err, wallet.ErrUserNotFound)
+		assert.ErrorIs(t, store.UpdateUser(&wallet.User{ID: 99, Name: "Nobody"}), wallet.ErrUserNotFound)
+		assert.ErrorIs(t, store.DeleteUser(99), wallet.ErrUserNotFound)
+	})
+}
+
+func testDuplicateWallet(t *testing.T, store wallet.Storer) {
+	savings := createWallet(t, store, wallet.Wallet{UserID: 1, WalletName: "Savings", WalletType: wallet.WalletTypeSavings})
+	card := createWallet(t, store, wallet.Wallet{UserID: 1, WalletName: "Card", WalletType: wallet.WalletTypeCreditCard})
+	name := func(s string) *string { return &s }
+
+	t.Run("create rejects a name the user already has", func(t *testing.T) {
+		w := wallet.Wallet{UserID: 1, WalletName: "Savings", WalletType: wallet.WalletTypeSavings, Currency: wallet.DefaultCurrency}
+
+		assert.ErrorIs(t, store.CreateWallet(&w), wallet.ErrDuplicateWallet)
+		assert.Len(t, listWallets(t, store, wallet.Filter{UserID: 1}), 2)
+	})
+
+	t.Run("another user may use the name", func(t *testing.T) {
+		createWallet(t, store, wallet.Wallet{UserID: 2, WalletName: "Savings", WalletType: wallet.WalletTypeSavings})
+	})
+
+	t.Run("patch rejects a name the user already has", func(t *testing.T) {
+		_, err := store.PatchWallet(card.ID, currentVersion(t, store, card.ID), wallet.WalletPatch{WalletName: name(savings.WalletName)})
+
+		assert.ErrorIs(t, err, wallet.ErrDuplicateWallet)
+		stored, err := store.GetWalletByID(card.ID)
+		require.NoError(t, err)
+		assert.Equal(t, "Card", stored.WalletName)
+	})
+
+	t.Run("patch may keep the wallet's own name", func(t *testing.T) {
+		_, err := store.PatchWallet(card.ID, currentVersion(t, store, card.ID), wallet.WalletPatch{WalletName: name("Card")})
+
+		assert.NoError(t, err)
+	})
+}
+
+func testUpdateWallet(t *testing.T, store wallet.Storer) {
+	created := createWallet(t, store, wallet.Wallet{UserID: 1, WalletType: wallet.WalletTypeSavings, Balance: wallet.MustParseMoney("100")})
+
+	t.Run("overwrites the balance and records the difference", func(t *testing.T) {
+		w := wallet.Wallet{ID: created.ID, Balance: wallet.MustParseMoney("60.50"), Version: created.Version}
+
+		err := store.UpdateWallet(&w)
+
+		require.NoError(t, err)
+		assert.Equal(t, wallet.MustParseMoney("60.50"), w.Balance)
+		assert.Equal(t, created.Version+1, w.Version)
+		assert.Equal(t, created.WalletName, w.WalletName)
+		transactions, err := store.GetTransactions(created.ID)
+		require.NoError(t, err)
+		require.Len(t, transactions, 2)
+		assert.Equal(t, wallet.TransactionTypeDebit, transactions[1].Type)
+		assert.Equal(t, wallet.MustParseMoney("39.50"), transactions[1].Amount)
+		assert.Equal(t, wallet.MustParseMoney("60.50"), transactions[1].Balance)
+	})
+
+	t.Run("rejects more decimals than the currency allows", func(t *testing.T) {
+		w := wallet.Wallet{ID: created.ID, Balance: wallet.MustParseMoney("1.001"), Version: currentVersion(t, store, created.ID)}
+
+		assert.ErrorIs(t, store.UpdateWallet(&w), wallet.ErrInvalidPrecision)
+	})
+
+	t.Run("rejects a negative balance the wallet type does not allow", func(t *testing.T) {
+		w := wallet.Wallet{ID: created.ID, Balance: wallet.MustParseMoney("-1"), Version: currentVersion(t, store, created.ID)}
+
+		assert.ErrorIs(t, store.UpdateWallet(&w), wallet.ErrNegativeBalance)
+	})
+
+	t.Run("rejects a version that moved on", func(t *testing.T) {
+		w := wallet.Wallet{ID: created.ID, Balance: wallet.MustParseMoney("1"), Version: created.Version}
+
+		assert.ErrorIs(t, store.UpdateWallet(&w), wallet.ErrVersionMismatch)
+		stored, err := store.GetWalletByID(created.ID)
+		require.NoError(t, err)
+		assert.Equal(t, wallet.MustParseMoney("60.50"), stored.Balance)
+	})
+
+	t.Run("unknown wallet", func(t *testing.T) {
+		w := wallet.Wallet{ID: created.ID + 1000, Balance: wallet.MustParseMoney("1"), Version: 1}
+
+		assert.ErrorIs(t, store.UpdateWallet(&w), wallet.ErrWalletNotFound)
+	})
+}
+
+func testPatchWallet(t *testing.T, store wallet.Storer) {
+	created := createWallet(t, store, wallet.Wallet{UserID: 1, WalletName: "Card", WalletType: wallet.WalletTypeCreditCard, Balance: wallet.MustParseMoney("10")})
+	name := func(s string) *string { return &s }
+
+	t.Run("changes only the patched fields", func(t *testing.T) {
+		got, err := store.PatchWallet(created.ID, created.Version, wallet.WalletPatch{WalletName: name("Everyday"), WalletType: name(wallet.WalletTypeSavings)})
+
+		require.NoError(t, err)
+		assert.Equal(t, "Everyday", got.WalletName)
+		assert.Equal(t, created.Version+1, got.Version)
+		assert.Equal(t, wallet.WalletTypeSavings, got.WalletType)
+		assert.Equal(t, created.UserName, got.UserName)
+		assert.Equal(t, created.Balance, got.Balance)
+		stored, err := store.GetWalletByID(created.ID)
+		require.NoError(t, err)
+		assert.Equal(t, got, stored)
+	})
+
+	t.Run("rejects a type that does not suit the balance", func(t *testing.T) {
+		overdrawn := createWallet(t, store, wallet.Wallet{UserID: 1, WalletType: wallet.WalletTypeCreditCard, Balance: wallet.MustParseMoney("-5")})
+
+		_, err := store.PatchWallet(overdrawn.ID, overdrawn.Version, wallet.WalletPatch{WalletType: name(wallet.WalletTypeSavings)})
+
+		assert.ErrorIs(t, err, wallet.ErrNegativeBalance)
+		stored, err := store.GetWalletByID(overdrawn.ID)
+		require.NoError(t, err)
+		assert.Equal(t, wallet.WalletTypeCreditCard, stored.WalletType)
+	})
+
+	t.Run("rejects a type allowing credit", func(t *testing.T) {
+		savings := createWallet(t, store, wallet.Wallet{UserID: 2, WalletType: wallet.WalletTypeSavings})
+
+		_, err := store.PatchWallet(savings.ID, savings.Version, wallet.WalletPatch{WalletType: name(wallet.WalletTypeCreditCard)})
+
+		assert.ErrorIs(t, err, wallet.ErrWalletTypeChange)
+	})
+
+	t.Run("rejects a type that does not suit the currency", func(t *testing.T) {
+		btc := createWallet(t, store, wallet.Wallet{UserID: 2, WalletType: wallet.WalletTypeCryptoWallet, Currency: wallet.CurrencyBTC})
+
+		_, err := store.PatchWallet(btc.ID, btc.Version, wallet.WalletPatch{WalletType: name(wallet.WalletTypeSavings)})
+
+		assert.ErrorIs(t, err, wallet.ErrUnsupportedCurrency)
+	})
+
+	t.Run("rejects a version that moved on", func(t *testing.T) {
+		_, err := store.PatchWallet(created.ID, created.Version, wallet.WalletPatch{WalletName: name("Stale")})
+
+		assert.ErrorIs(t, err, wallet.ErrVersionMismatch)
+		stored, err := store.GetWalletByID(created.ID)
+		require.NoError(t, err)
+		assert.Equal(t, "Everyday", stored.WalletName)
+	})
+
+	t.Run("unknown wallet", func(t *testing.T) {
+		_, err := store.PatchWallet(created.ID+1000, 1, wallet.WalletPatch{WalletName: name("Ghost")})
+
+		assert.ErrorIs(t, err, wallet.ErrWalletNotFound)
+	})
+}
+
+func testDeleteWallet(t *testing.T, store wallet.Storer) {
+	deleted := createWallet(t, store, wallet.Wallet{UserID: 1, WalletType: wallet.WalletTypeSavings, Balance: wallet.MustParseMoney("10")})
+	kept := createWallet(t, store, wallet.Wallet{UserID: 1, WalletType: wallet.WalletTypeCreditCard})
+
+	t.Run("rejects a version that moved on", func(t *testing.T) {
+		err := store.DeleteWallet(deleted.ID, deleted.Version+1)
+
+		assert.ErrorIs(t, err, wallet.ErrVersionMismatch)
+		assert.Equal(t, deleted.Balance, balanceOf(t, store, deleted.ID))
+	})
+
+	t.Run("soft-deletes only that wallet", func(t *testing.T) {
+		err := store.DeleteWallet(deleted.ID, deleted.Version)
+
+		require.NoError(t, err)
+		assert.Equal(t, []int{kept.ID}, walletIDs(listWallets(t, store, wallet.Filter{})))
+		all := listWallets(t, store, wallet.Filter{IncludeDeleted: true})
+		require.Len(t, all, 2)
+		assert.NotNil(t, all[0].DeletedAt)
+		assert.Nil(t, all[1].DeletedAt)
+	})
+
+	t.Run("deleted wallet is gone for reads and balance changes", func(t *testing.T) {
+		_, err := store.GetWalletByID(deleted.ID)
+		assert.ErrorIs(t, err, wallet.ErrWalletNotFound)
+
+		_, err = store.Deposit(deleted.ID, wallet.MustParseMoney("1"))
+		assert.ErrorIs(t, err, wallet.ErrWalletNotFound)
+
+		_, err = store.Transfer(kept.ID, deleted.ID, wallet.MustParseMoney("1"))
+		assert.ErrorIs(t, err, wallet.ErrWalletNotFound)
+
+		_, err = store.PatchWallet(deleted.ID, deleted.Version+1, wallet.WalletPatch{})
+		assert.ErrorIs(t, err, wallet.ErrWalletNotFound)
+
+		err = store.DeleteWallet(deleted.ID, deleted.Version+1)
+		assert.ErrorIs(t, err, wallet.ErrWalletNotFound)
+	})
+
+	t.Run("name is free again", func(t *testing.T) {
+		createWallet(t, store, wallet.Wallet{UserID: 1, WalletType: wallet.WalletTypeSavings})
+	})
+
+	t.Run("unknown wallet", func(t *testing.T) {
+		err := store.DeleteWallet(kept.ID+1000, 1)
+
+		assert.ErrorIs(t, err, wallet.ErrWalletNotFound)
+	})
+}
+
+func testDeleteUserWallets(t *testing.T, store wallet.Storer) {
+	createWallet(t, store, wallet.Wallet{UserID: 1, WalletType: wallet.WalletTypeSavings})
+	createWallet(t, store, wallet.Wallet{UserID: 1, WalletType: wallet.WalletTypeCreditCard})
+	kept := createWallet(t, store, wallet.Wallet{UserID: 2, WalletType: wallet.WalletTypeSavings})
+
+	err := store.DeleteUserWallets(1)
+
+	require.NoError(t, err)
+	assert.Equal(t, []int{kept.ID}, walletIDs(listWallets(t, store, wallet.Filter{})))
+	assert.Len(t, listWallets(t, store, wallet.Filter{UserID: 1, IncludeDeleted: true}), 2)
+}
+
+func testRestoreWallet(t *testing.T, store wallet.Storer) {
+	savings := createWallet(t, store, wallet.Wallet{UserID: 1, WalletType: wallet.WalletTypeSavings, Balance: wallet.MustParseMoney("10")})
+	require.NoError(t, store.DeleteWallet(savings.ID, savings.Version))
+
+	t.Run("brings the wallet back with its balance", func(t *testing.T) {
+		restored, err := store.RestoreWallet(savings.ID)
+
+		require.NoError(t, err)
+		assert.Nil(t, restored.DeletedAt)
+		assert.Equal(t, savings.Version+2, restored.Version)
+		assert.Equal(t, savings.Balance, balanceOf(t, store, savings.ID))
+	})
+
+	t.Run("live wallet is returned as it is", func(t *testing.T) {
+		restored, err := store.RestoreWallet(savings.ID)
+
+		require.NoError(t, err)
+		assert.Equal(t, savings.Version+2, restored.Version)
+	})
+
+	t.Run("name taken in the meantime", func(t *testing.T) {
+		require.NoError(t, store.DeleteWallet(savings.ID, savings.Version+2))
+		createWallet(t, store, wallet.Wallet{UserID: 1, WalletType: wallet.WalletTypeSavings})
+
+		_, err := store.RestoreWallet(savings.ID)
+
+		assert.ErrorIs(t, err, wallet.ErrDuplicateWallet)
+	})
+
+	t.Run("unknown wallet", func(t *testing.T) {
+		_, err := store.RestoreWallet(savings.ID + 1000)
+
+		assert.ErrorIs(t, err, wallet.ErrWalletNotFound)
+	})
+}
+
+func testWalletStatus(t *testing.T, store wallet.Storer) {
+	savings := createWallet(t, store, wallet.Wallet{UserID: 1, WalletType: wallet.WalletTypeSavings, Balance: wallet.MustParseMoney("100")})
+	other := createWallet(t, store, wallet.Wallet{UserID: 2, WalletType: wallet.WalletTypeSavings, Balance: wallet.MustParseMoney("100")})
+	current := savings
+
+	t.Run("new wallets are active", func(t *testing.T) {
+		assert.Equal(t, wallet.WalletStatusActive, savings.Status)
+	})
+
+	t.Run("rejects a version that moved on", func(t *testing.T) {
+		_, err := store.ChangeWalletStatus(savings.ID, savings.Version+1, wallet.WalletStatusFrozen, "Reported stolen")
+
+		assert.ErrorIs(t, err, wallet.ErrVersionMismatch)
+		assert.Equal(t, wallet.WalletStatusActive, walletByID(t, store, savings.ID).Status)
+	})
+
+	t.Run("frozen wallet refuses every balance change", func(t *testing.T) {
+		frozen, err := store.ChangeWalletStatus(savings.ID, savings.Version, wallet.WalletStatusFrozen, "Reported stolen")
+		require.NoError(t, err)
+		assert.Equal(t, wallet.WalletStatusFrozen, frozen.Status)
+		assert.Equal(t, "Reported stolen", frozen.StatusReason)
+		assert.Equal(t, savings.Version+1, frozen.Version)
+		current = frozen
+
+		_, err = store.Deposit(savings.ID, wallet.MustParseMoney("1"))
+		assert.ErrorIs(t, err, wallet.ErrWalletFrozen)
+		_, err = store.Withdraw(savings.ID, wallet.MustParseMoney("1"))
+		assert.ErrorIs(t, err, wallet.ErrWalletFrozen)
+		_, err = store.Transfer(other.ID, savings.ID, wallet.MustParseMoney("1"))
+		assert.ErrorIs(t, err, wallet.ErrWalletFrozen)
+		_, err = store.Convert(savings.ID, other.ID, wallet.MustParseMoney("1"), wallet.RateTable{})
+		assert.ErrorIs(t, err, wallet.ErrWalletFrozen)
+		err = store.UpdateWallet(&wallet.Wallet{ID: savings.ID, Balance: wallet.MustParseMoney("1"), Version: frozen.Version})
+		assert.ErrorIs(t, err, wallet.ErrWalletFrozen)
+
+		assert.Equal(t, savings.Balance, balanceOf(t, store, savings.ID))
+		assert.Equal(t, other.Balance, balanceOf(t, store, other.ID))
+	})
+
+	t.Run("frozen wallet cannot be closed", func(t *testing.T) {
+		_, err := store.ChangeWalletStatus(savings.ID, current.Version, wallet.WalletStatusClosed, "Customer request")
+
+		assert.ErrorIs(t, err, wallet.ErrInvalidStatusTransition)
+	})
+
+	t.Run("frozen wallet cannot be deleted", func(t *testing.T) {
+		err := store.DeleteWallet(savings.ID, current.Version)
+		assert.ErrorIs(t, err, wallet.ErrWalletFrozen)
+
+		err = store.DeleteUserWallets(savings.UserID)
+		assert.ErrorIs(t, err, wallet.ErrWalletFrozen)
+
+		assert.Len(t, listWallets(t, store, wallet.Filter{UserID: savings.UserID}), 1)
+	})
+
+	t.Run("unfrozen wallet takes balance changes again", func(t *testing.T) {
+		_, err := store.ChangeWalletStatus(savings.ID, current.Version, wallet.WalletStatusActive, "Cleared by compliance")
+		require.NoError(t, err)
+
+		current, err = store.Deposit(savings.ID, wallet.MustParseMoney("1"))
+		assert.NoError(t, err)
+	})
+
+	t.Run("wallet with a balance cannot be closed", func(t *testing.T) {
+		_, err := store.ChangeWalletStatus(savings.ID, current.Version, wallet.WalletStatusClosed, "Customer request")
+
+		assert.ErrorIs(t, err, wallet.ErrWalletNotEmpty)
+		assert.Equal(t, wallet.WalletStatusActive, walletByID(t, store, savings.ID).Status)
+	})
+
+	t.Run("closed wallet cannot be reopened", func(t *testing.T) {
+		emptied, err := store.Withdraw(savings.ID, current.Balance)
+		require.NoError(t, err)
+		closed, err := store.ChangeWalletStatus(savings.ID, emptied.Version, wallet.WalletStatusClosed, "Customer request")
+		require.NoError(t, err)
+		current = closed
+
+		_, err = store.ChangeWalletStatus(savings.ID, closed.Version, wallet.WalletStatusActive, "Changed their mind")
+		assert.ErrorIs(t, err, wallet.ErrInvalidStatusTransition)
+		_, err = store.Deposit(savings.ID, wallet.MustParseMoney("1"))
+		assert.ErrorIs(t, err, wallet.ErrWalletClosed)
+	})
+
+	t.Run("closed wallet is restored closed", func(t *testing.T) {
+		require.NoError(t, store.DeleteWallet(savings.ID, current.Version))
+
+		restored, err := store.RestoreWallet(savings.ID)
+
+		require.NoError(t, err)
+		assert.Equal(t, wallet.WalletStatusClosed, restored.Status)
+	})
+
+	t.Run("unknown wallet", func(t *testing.T) {
+		_, err := store.ChangeWalletStatus(other.ID+1000, 1, wallet.WalletStatusFrozen, "Reported stolen")
+
+		assert.ErrorIs(t, err, wallet.ErrWalletNotFound)
+	})
+}
+
+func testDepositAndWithdraw(t *testing.T, store wallet.Storer) {
+	savings := createWallet(t, store, wallet.Wallet{UserID: 1, WalletType: wallet.WalletTypeSavings, Balance: wallet.MustParseMoney("100")})
+	credit := createWallet(t, store, wallet.Wallet{UserID: 1, WalletType: wallet.WalletTypeCreditCard})
+
+	t.Run("deposit adds to the balance", func(t *testing.T) {
+		w, err := store.Deposit(savings.ID, wallet.MustParseMoney("0.10"))
+
+		require.NoError(t, err)
+		assert.Equal(t, wallet.MustParseMoney("100.10"), w.Balance)
+		assert.Equal(t, savings.Version+1, w.Version)
+	})
+
+	t.Run("withdraw takes from the balance", func(t *testing.T) {
+		w, err := store.Withdraw(savings.ID, wallet.MustParseMoney("0.20"))
+
+		require.NoError(t, err)
+		assert.Equal(t, wallet.MustParseMoney("99.90"), w.Balance)
+	})
+
+	t.Run("withdraw below zero from Savings is refused", func(t *testing.T) {
+		_, err := store.Withdraw(savings.ID, wallet.MustParseMoney("99.91"))
+
+		assert.ErrorIs(t, err, wallet.ErrInsufficientFunds)
+		assert.Equal(t, wallet.MustParseMoney("99.90"), balanceOf(t, store, savings.ID))
+	})
+
+	t.Run("withdraw below zero from Credit Card is allowed", func(t *testing.T) {
+		w, err := store.Withdraw(credit.ID, wallet.MustParseMoney("25"))
+
+		require.NoError(t, err)
+		assert.Equal(t, wallet.MustParseMoney("-25"), w.Balance)
+	})
+
+	t.Run("amount with too many decimals is refused", func(t *testing.T) {
+		_, err := store.Deposit(savings.ID, wallet.MustParseMoney("0.001"))
+
+		assert.ErrorIs(t, err, wallet.ErrInvalidPrecision)
+	})
+
+	t.Run("balance beyond MaxMoney is refused", func(t *testing.T) {
+		before := balanceOf(t, store, savings.ID)
+
+		_, err := store.Deposit(savings.ID, wallet.MustParseMoney("9999999999.99"))
+
+		assert.ErrorIs(t, err, wallet.ErrInvalidMoney)
+		assert.Equal(t, before, balanceOf(t, store, savings.ID))
+	})
+
+	t.Run("unknown wallet", func(t *testing.T) {
+		_, err := store.Deposit(credit.ID+1000, wallet.MustParseMoney("1"))
+
+		assert.ErrorIs(t, err, wallet.ErrWalletNotFound)
+	})
+
+	t.Run("every change is in the ledger", func(t *testing.T) {
+		transactions, err := store.GetTransactions(savings.ID)
+
+		require.NoError(t, err)
+		reasons := make([]string, 0)
+		for _, tx := range transactions {
+			reasons = append(reasons, tx.Reason)
+		}
+		assert.Equal(t, []string{wallet.ReasonOpeningBalance, wallet.ReasonDeposit, wallet.ReasonWithdrawal}, reasons)
+	})
+}
+
+func testTransfer(t *testing.T, store wallet.Storer) {
+	from := createWallet(t, store, wallet.Wallet{UserID: 1, WalletType: wallet.WalletTypeSavings, Balance: wallet.MustParseMoney("100")})
+	to := createWallet(t, store, wallet.Wallet{UserID: 2, WalletType: wallet.WalletTypeSavings, Balance: wallet.MustParseMoney("5")})
+	usd := createWallet(t, store, wallet.Wallet{UserID: 2, WalletType: wallet.WalletTypeSavings, Currency: wallet.CurrencyUSD})
+
+	t.Run("moves money with one correlation id", func(t *testing.T) {
+		result, err := store.Transfer(from.ID, to.ID, wallet.MustParseMoney("40.25"))
+
+		require.NoError(t, err)
+		assert.Equal(t, wallet.MustParseMoney("59.75"), result.From.Balance)
+		assert.Equal(t, wallet.MustParseMoney("45.25"), result.To.Balance)
+		out, err := store.GetTransactions(from.ID)
+		require.NoError(t, err)
+		in, err := store.GetTransactions(to.ID)
+		require.NoError(t, err)
+		assert.Equal(t, wallet.ReasonTransferOut, out[len(out)-1].Reason)
+		assert.Equal(t, wallet.ReasonTransferIn, in[len(in)-1].Reason)
+		assert.Equal(t, out[len(out)-1].CorrelationID, in[len(in)-1].CorrelationID)
+	})
+
+	t.Run("insufficient funds leaves both wallets untouched", func(t *testing.T) {
+		_, err := store.Transfer(from.ID, to.ID, wallet.MustParseMoney("59.76"))
+
+		assert.ErrorIs(t, err, wallet.ErrInsufficientFunds)
+		assert.Equal(t, wallet.MustParseMoney("59.75"), balanceOf(t, store, from.ID))
+		assert.Equal(t, wallet.MustParseMoney("45.25"), balanceOf(t, store, to.ID))
+	})
+
+	t.Run("balance beyond MaxMoney leaves both wallets untouched", func(t *testing.T) {
+		full := createWallet(t, store, wallet.Wallet{UserID: 2, WalletType: wallet.WalletTypeCryptoWallet, Balance: wallet.MustParseMoney("9999999999.99")})
+
+		_, err := store.Transfer(from.ID, full.ID, wallet.MustParseMoney("1"))
+
+		assert.ErrorIs(t, err, wallet.ErrInvalidMoney)
+		assert.Equal(t, wallet.MustParseMoney("59.75"), balanceOf(t, store, from.ID))
+		assert.Equal(t, full.Balance, balanceOf(t, store, full.ID))
+	})
+
+	t.Run("different currencies are refused", func(t *testing.T) {
+		_, err := store.Transfer(from.ID, usd.ID, wallet.MustParseMoney("1"))
+
+		assert.ErrorIs(t, err, wallet.ErrCurrencyMismatch)
+	})
+
+	t.Run("unknown wallet", func(t *testing.T) {
+		_, err := store.Transfer(from.ID, usd.ID+1000, wallet.MustParseMoney("1"))
+
+		assert.ErrorIs(t, err, wallet.ErrWalletNotFound)
+	})
+}
+
+func testConvert(t *testing.T, store wallet.Storer) {
+	thb := createWallet(t, store, wallet.Wallet{UserID: 1, WalletType: wallet.WalletTypeSavings, Balance: wallet.MustParseMoney("1000")})
+	usd := createWallet(t, store, wallet.Wallet{UserID: 1, WalletType: wallet.WalletTypeSavings, Currency: wallet.CurrencyUSD})
+	rates := wallet.RateTable{"USD/THB": big.NewRat(365, 10)}
+
+	t.Run("debits the source and credits the converted amount", func(t *testing.T) {
+		conversion, err := store.Convert(thb.ID, usd.ID, wallet.MustParseMoney("100"), rates)
+
+		require.NoError(t, err)
+		assert.NotZero(t, conversion.ID)
+		assert.Equal(t, wallet.MustParseMoney("100"), conversion.FromAmount)
+		assert.Equal(t, wallet.MustParseMoney("2.74"), conversion.ToAmount)
+		assert.Equal(t, "0.027397260274", conversion.Rate)
+		assert.Equal(t, wallet.MustParseMoney("900"), conversion.From.Balance)
+		assert.Equal(t, wallet.MustParseMoney("2.74"), conversion.To.Balance)
+		in, err := store.GetTransactions(usd.ID)
+		require.NoError(t, err)
+		require.Len(t, in, 1)
+		assert.Equal(t, wallet.ReasonConversionIn, in[0].Reason)
+		assert.Equal(t, conversion.CorrelationID, in[0].CorrelationID)
+	})
+
+	t.Run("unknown rate", func(t *testing.T) {
+		_, err := store.Convert(thb.ID, usd.ID, wallet.MustParseMoney("1"), wallet.RateTable{})
+
+		assert.ErrorIs(t, err, wallet.ErrRateNotFound)
+		assert.Equal(t, wallet.MustParseMoney("900"), balanceOf(t, store, thb.ID))
+	})
+
+	t.Run("insufficient funds", func(t *testing.T) {
+		_, err := store.Convert(usd.ID, thb.ID, wallet.MustParseMoney("2.75"), rates)
+
+		assert.ErrorIs(t, err, wallet.ErrInsufficientFunds)
+	})
+}
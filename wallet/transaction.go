@@ -0,0 +1,71 @@
+package wallet
+
+import (
+	"github.com/labstack/echo/v4"
+	"net/http"
+	"time"
+)
+
+type Transaction struct {
+	ID            int       `json:"id" example:"1"`
+	WalletID      int       `json:"wallet_id" example:"1"`
+	Type          string    `json:"type" example:"credit"`
+	Amount        Money     `json:"amount" swaggertype:"number" example:"100.00"`
+	Balance       Money     `json:"balance" swaggertype:"number" example:"200.00"`
+	Reason        string    `json:"reason" example:"transfer in"`
+	CorrelationID string    `json:"correlation_id" example:"6f1c2a8e-3b7d-4c1e-9f0a-2d5b8e7c4a10"`
+	CreatedAt     time.Time `json:"created_at" example:"2024-03-25T14:19:00.729237Z"`
+}
+
+const (
+	TransactionTypeCredit = "credit"
+	TransactionTypeDebit  = "debit"
+)
+
+const (
+	ReasonOpeningBalance = "opening balance"
+	ReasonBalanceUpdate  = "balance update"
+	ReasonTransferIn     = "transfer in"
+	ReasonTransferOut    = "transfer out"
+	ReasonDeposit        = "deposit"
+	ReasonWithdrawal     = "withdrawal"
+	ReasonConversionIn   = "conversion in"
+	ReasonConversionOut  = "conversion out"
+)
+
+// TransactionTypeOf returns the ledger entry type for a signed balance change.
+func TransactionTypeOf(amount Money) string {
+	if amount.IsNegative() {
+		return TransactionTypeDebit
+	}
+	return TransactionTypeCredit
+}
+
+// GetWalletTransactionsHandler
+//
+//	@Summary		Get transactions of the wallet
+//	@Description	Get every credit and debit recorded against the wallet, oldest first
+//	@Tags			wallet
+//	@Produce		json
+//	@Param			id	path		int	true	"Wallet ID"
+//	@Success		200	{array}		Transaction
+//	@Failure		400	{object}	Problem
+//	@Failure		403	{object}	Problem
+//	@Failure		500	{object}	Problem
+//	@Router			/api/v1/wallets/{id}/transactions [get]
+//	@Router			/api/v1/admin/wallets/{id}/transactions [get]
+func (h *Handler) GetWalletTransactionsHandler(c echo.Context) error {
+	// parse wallet id
+	walletID, err := h.ownedWalletID(c)
+	if err != nil {
+		return err
+	}
+
+	// get transactions
+	transactions, err := h.store.GetTransactions(walletID)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, transactions)
+}
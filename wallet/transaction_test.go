@@ -0,0 +1,82 @@
+package wallet
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"testing"
+)
+
+func TestGetWalletTransactions(t *testing.T) {
+	t.Run("given wallet id is not number should return 400 and error message", func(t *testing.T) {
+		// Arrange
+		resp, c, h, _ := testSetup(http.MethodGet, "/", nil)
+		c.SetPath("/api/v1/wallets/:id/transactions")
+		c.SetParamNames("id")
+		c.SetParamValues("abc")
+
+		// Act
+		err := serve(c, h.GetWalletTransactionsHandler)
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusBadRequest, resp.Code)
+		var got Problem
+		if err := json.Unmarshal(resp.Body.Bytes(), &got); err != nil {
+			t.Errorf("expected response body to be valid json, got %s", resp.Body.String())
+		}
+		assert.NotEmpty(t, got.Code)
+	})
+
+	t.Run("given unable to get transactions should return 500 and error message", func(t *testing.T) {
+		// Arrange
+		resp, c, h, mock := testSetup(http.MethodGet, "/", nil)
+		c.SetPath("/api/v1/wallets/:id/transactions")
+		c.SetParamNames("id")
+		c.SetParamValues("1")
+		mock.err = errors.New("unable to get transactions")
+		mock.ExpectToCall("GetTransactions")
+
+		// Act
+		err := serve(c, h.GetWalletTransactionsHandler)
+
+		// Assert
+		mock.Verify(t)
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusInternalServerError, resp.Code)
+		var got Problem
+		if err := json.Unmarshal(resp.Body.Bytes(), &got); err != nil {
+			t.Errorf("expected response body to be valid json, got %s", resp.Body.String())
+		}
+		assert.NotEmpty(t, got.Code)
+	})
+
+	t.Run("given no error should return 200 and []transactions", func(t *testing.T) {
+		// Arrange
+		resp, c, h, mock := testSetup(http.MethodGet, "/", nil)
+		c.SetPath("/api/v1/wallets/:id/transactions")
+		c.SetParamNames("id")
+		c.SetParamValues("7")
+		want := []Transaction{
+			{ID: 1, WalletID: 7, Type: TransactionTypeCredit, Amount: MustParseMoney("100"), Balance: MustParseMoney("100"), Reason: ReasonOpeningBalance},
+			{ID: 2, WalletID: 7, Type: TransactionTypeDebit, Amount: MustParseMoney("40"), Balance: MustParseMoney("60"), Reason: ReasonTransferOut},
+		}
+		mock.transactions = want
+		mock.ExpectToCall("GetTransactions")
+
+		// Act
+		err := serve(c, h.GetWalletTransactionsHandler)
+
+		// Assert
+		mock.Verify(t)
+		assert.NoError(t, err)
+		assert.Equal(t, 7, mock.whatIsID)
+		assert.Equal(t, http.StatusOK, resp.Code)
+		var got []Transaction
+		if err := json.Unmarshal(resp.Body.Bytes(), &got); err != nil {
+			t.Errorf("expected response body to be valid json, got %s", resp.Body.String())
+		}
+		assert.Equal(t, want, got)
+	})
+}
@@ -0,0 +1,68 @@
+package wallet
+
+import (
+	"github.com/labstack/echo/v4"
+	"net/http"
+)
+
+type Transfer struct {
+	FromWalletID int   `json:"from_wallet_id" example:"1"`
+	ToWalletID   int   `json:"to_wallet_id" example:"2"`
+	Amount       Money `json:"amount" swaggertype:"number" example:"100.00"`
+}
+
+func (t Transfer) Validate() error {
+	v := validator{}
+	fromOK := v.check(t.FromWalletID > 0, "from_wallet_id", "is required")
+	if v.check(t.ToWalletID > 0, "to_wallet_id", "is required") && fromOK {
+		v.check(t.FromWalletID != t.ToWalletID, "to_wallet_id", "must differ from from_wallet_id")
+	}
+	v.checkPositive("amount", t.Amount)
+	return v.err()
+}
+
+type TransferResult struct {
+	From Wallet `json:"from"`
+	To   Wallet `json:"to"`
+}
+
+// TransferHandler
+//
+//	@Summary		Transfer money between wallets
+//	@Description	Debit one wallet and credit another in a single transaction
+//	@Tags			transfer
+//	@Accept			json
+//	@Produce		json
+//	@Param			transfer	body	Transfer	true	"Transfer object"
+//	@Param			Idempotency-Key	header	string	false	"Key to run a retried request only once"
+//	@Success		200	{object}	TransferResult
+//	@Failure		400	{object}	Problem
+//	@Failure		403	{object}	Problem
+//	@Failure		404	{object}	Problem
+//	@Failure		422	{object}	Problem
+//	@Failure		500	{object}	Problem
+//	@Router			/api/v1/transfers [post]
+func (h *Handler) TransferHandler(c echo.Context) error {
+	// bind request body to transfer
+	transfer := Transfer{}
+	if err := c.Bind(&transfer); err != nil {
+		return err
+	}
+
+	// validate transfer
+	if err := transfer.Validate(); err != nil {
+		return err
+	}
+	// money may go to any wallet but only leave the caller's own
+	if err := h.authorizeWallet(c, transfer.FromWalletID); err != nil {
+		return err
+	}
+
+	// transfer money
+	result, err := h.store.Transfer(transfer.FromWalletID, transfer.ToWalletID, transfer.Amount)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, result)
+}
@@ -0,0 +1,105 @@
+package wallet
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestTransfer(t *testing.T) {
+	badRequests := []struct {
+		name string
+		body string
+	}{
+		{name: "given invalid json", body: `{"from_wallet_id": "abc"`},
+		{name: "given no from_wallet_id", body: `{"to_wallet_id": 2, "amount": 10}`},
+		{name: "given no to_wallet_id", body: `{"from_wallet_id": 1, "amount": 10}`},
+		{name: "given same wallet", body: `{"from_wallet_id": 1, "to_wallet_id": 1, "amount": 10}`},
+		{name: "given zero amount", body: `{"from_wallet_id": 1, "to_wallet_id": 2, "amount": 0}`},
+		{name: "given negative amount", body: `{"from_wallet_id": 1, "to_wallet_id": 2, "amount": -10}`},
+	}
+	for _, test := range badRequests {
+		t.Run(test.name+" should return 400 and error message", func(t *testing.T) {
+			// Arrange
+			resp, c, h, mock := testSetup(http.MethodPost, "/api/v1/transfers", strings.NewReader(test.body))
+			c.Request().Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+
+			// Act
+			err := serve(c, h.TransferHandler)
+
+			// Assert
+			assert.NoError(t, err)
+			assert.False(t, mock.methodToCall["Transfer"])
+			assert.Equal(t, http.StatusBadRequest, resp.Code)
+			var got Problem
+			if err := json.Unmarshal(resp.Body.Bytes(), &got); err != nil {
+				t.Errorf("expected response body to be valid json, got %s", resp.Body.String())
+			}
+			assert.NotEmpty(t, got.Code)
+		})
+	}
+
+	storeErrors := []struct {
+		name     string
+		err      error
+		wantCode int
+	}{
+		{name: "given wallet not found should return 404", err: ErrWalletNotFound, wantCode: http.StatusNotFound},
+		{name: "given insufficient funds should return 422", err: ErrInsufficientFunds, wantCode: http.StatusUnprocessableEntity},
+		{name: "given unable to transfer should return 500", err: errors.New("unable to transfer"), wantCode: http.StatusInternalServerError},
+	}
+	for _, test := range storeErrors {
+		t.Run(test.name+" and error message", func(t *testing.T) {
+			// Arrange
+			body := `{"from_wallet_id": 1, "to_wallet_id": 2, "amount": 10}`
+			resp, c, h, mock := testSetup(http.MethodPost, "/api/v1/transfers", strings.NewReader(body))
+			c.Request().Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			mock.err = test.err
+			mock.ExpectToCall("Transfer")
+
+			// Act
+			err := serve(c, h.TransferHandler)
+
+			// Assert
+			mock.Verify(t)
+			assert.NoError(t, err)
+			assert.Equal(t, test.wantCode, resp.Code)
+			var got Problem
+			if err := json.Unmarshal(resp.Body.Bytes(), &got); err != nil {
+				t.Errorf("expected response body to be valid json, got %s", resp.Body.String())
+			}
+			assert.NotEmpty(t, got.Code)
+		})
+	}
+
+	t.Run("given valid transfer should return 200 and both wallets", func(t *testing.T) {
+		// Arrange
+		body := `{"from_wallet_id": 1, "to_wallet_id": 2, "amount": 10}`
+		resp, c, h, mock := testSetup(http.MethodPost, "/api/v1/transfers", strings.NewReader(body))
+		c.Request().Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		want := TransferResult{
+			From: Wallet{ID: 1, Balance: MustParseMoney("90")},
+			To:   Wallet{ID: 2, Balance: MustParseMoney("110")},
+		}
+		mock.transferResult = want
+		mock.ExpectToCall("Transfer")
+
+		// Act
+		err := serve(c, h.TransferHandler)
+
+		// Assert
+		mock.Verify(t)
+		assert.NoError(t, err)
+		assert.Equal(t, Transfer{FromWalletID: 1, ToWalletID: 2, Amount: MustParseMoney("10")}, mock.whatIsTransfer)
+		assert.Equal(t, http.StatusOK, resp.Code)
+		var got TransferResult
+		if err := json.Unmarshal(resp.Body.Bytes(), &got); err != nil {
+			t.Errorf("expected response body to be valid json, got %s", resp.Body.String())
+		}
+		assert.Equal(t, want, got)
+	})
+}
@@ -0,0 +1,185 @@
+package wallet
+
+import (
+	"github.com/labstack/echo/v4"
+	"net/http"
+	"time"
+)
+
+// User owns wallets, the name shown as user_name on each of them.
+type User struct {
+	ID        int       `json:"id" example:"1"`
+	Name      string    `json:"name" example:"John Doe"`
+	CreatedAt time.Time `json:"created_at" example:"2024-03-25T14:19:00.729237Z"`
+}
+
+type UserForCreate struct {
+	Name string `json:"name" example:"John Doe"`
+}
+
+type UserStorer interface {
+	GetUsers() ([]User, error)
+	GetUserByID(id int) (User, error)
+	CreateUser(user *User) error
+	UpdateUser(user *User) error
+	// DeleteUser returns ErrUserHasWallets while any wallet, even a soft-deleted one, belongs to the user.
+	DeleteUser(id int) error
+}
+
+// ValidateUser checks a user sent to CreateUserHandler or UpdateUserHandler.
+func ValidateUser(u User) error {
+	v := validator{}
+	v.checkName("name", u.Name)
+	return v.err()
+}
+
+// bindUser reads the user of a create or update request.
+func bindUser(c echo.Context) (User, error) {
+	user := UserForCreate{}
+	if err := c.Bind(&user); err != nil {
+		return User{}, err
+	}
+	u := User{Name: user.Name}
+	return u, ValidateUser(u)
+}
+
+// GetUsersHandler
+//
+//	@Summary		Get all users
+//	@Description	Get all users in id order
+//	@Tags			user
+//	@Produce		json
+//	@Success		200	{array}		User
+//	@Failure		500	{object}	Problem
+//	@Router			/api/v1/admin/users [get]
+func (h *Handler) GetUsersHandler(c echo.Context) error {
+	users, err := h.store.GetUsers()
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, users)
+}
+
+// GetUserHandler
+//
+//	@Summary		Get user
+//	@Description	Get a single user by its id
+//	@Tags			user
+//	@Produce		json
+//	@Param			id	path		int	true	"User ID"
+//	@Success		200	{object}	User
+//	@Failure		400	{object}	Problem
+//	@Failure		403	{object}	Problem
+//	@Failure		404	{object}	Problem
+//	@Failure		500	{object}	Problem
+//	@Router			/api/v1/users/{id} [get]
+//	@Router			/api/v1/admin/users/{id} [get]
+func (h *Handler) GetUserHandler(c echo.Context) error {
+	// parse user id
+	userID, err := h.ownedUserID(c)
+	if err != nil {
+		return err
+	}
+
+	// get user
+	user, err := h.store.GetUserByID(userID)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, user)
+}
+
+// CreateUserHandler
+//
+//	@Summary		Create user
+//	@Description	Create a user, wallets are then created for its id
+//	@Tags			user
+//	@Accept			json
+//	@Produce		json
+//	@Param			user	body	UserForCreate	true	"User object"
+//	@Param			Idempotency-Key	header	string	false	"Key to run a retried request only once"
+//	@Success		201	{object}	User
+//	@Failure		400	{object}	Problem
+//	@Failure		500	{object}	Problem
+//	@Router			/api/v1/admin/users [post]
+func (h *Handler) CreateUserHandler(c echo.Context) error {
+	user, err := bindUser(c)
+	if err != nil {
+		return err
+	}
+
+	// create user
+	if err = h.store.CreateUser(&user); err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusCreated, user)
+}
+
+// UpdateUserHandler
+//
+//	@Summary		Update user
+//	@Description	Rename the user, every wallet of the user shows the new name
+//	@Tags			user
+//	@Accept			json
+//	@Produce		json
+//	@Param			id		path	int				true	"User ID"
+//	@Param			user	body	UserForCreate	true	"User object"
+//	@Param			Idempotency-Key	header	string	false	"Key to run a retried request only once"
+//	@Success		200	{object}	User
+//	@Failure		400	{object}	Problem
+//	@Failure		403	{object}	Problem
+//	@Failure		404	{object}	Problem
+//	@Failure		500	{object}	Problem
+//	@Router			/api/v1/users/{id} [put]
+//	@Router			/api/v1/admin/users/{id} [put]
+func (h *Handler) UpdateUserHandler(c echo.Context) error {
+	// parse user id
+	userID, err := h.ownedUserID(c)
+	if err != nil {
+		return err
+	}
+
+	user, err := bindUser(c)
+	if err != nil {
+		return err
+	}
+	user.ID = userID
+
+	// update user
+	if err = h.store.UpdateUser(&user); err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, user)
+}
+
+// DeleteUserHandler
+//
+//	@Summary		Delete user
+//	@Description	Delete a user that owns no wallet
+//	@Tags			user
+//	@Produce		json
+//	@Param			id	path	int	true	"User ID"
+//	@Success		204
+//	@Failure		400	{object}	Problem
+//	@Failure		403	{object}	Problem
+//	@Failure		404	{object}	Problem
+//	@Failure		409	{object}	Problem
+//	@Failure		500	{object}	Problem
+//	@Router			/api/v1/admin/users/{id} [delete]
+func (h *Handler) DeleteUserHandler(c echo.Context) error {
+	// parse user id
+	userID, err := h.ownedUserID(c)
+	if err != nil {
+		return err
+	}
+
+	// delete user
+	if err = h.store.DeleteUser(userID); err != nil {
+		return err
+	}
+	return c.NoContent(http.StatusNoContent)
+}
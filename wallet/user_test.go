@@ -0,0 +1,147 @@
+package wallet
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func userSetup(method, body string) (*httptest.ResponseRecorder, echo.Context, *Handler, *mockWalletStorer) {
+	resp, c, h, mock := testSetup(method, "/", strings.NewReader(body))
+	c.Request().Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	c.SetPath("/api/v1/users/:id")
+	c.SetParamNames("id")
+	c.SetParamValues("1")
+	return resp, c, h, mock
+}
+
+func TestGetUsers(t *testing.T) {
+	// Arrange
+	resp, c, h, mock := testSetup(http.MethodGet, "/api/v1/users", nil)
+	mock.users = []User{{ID: 1, Name: "John Doe"}, {ID: 2, Name: "Jane Doe"}}
+	mock.ExpectToCall("GetUsers")
+
+	// Act
+	err := serve(c, h.GetUsersHandler)
+
+	// Assert
+	mock.Verify(t)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.Code)
+	var got []User
+	assert.NoError(t, json.Unmarshal(resp.Body.Bytes(), &got))
+	assert.Equal(t, mock.users, got)
+}
+
+func TestGetUser(t *testing.T) {
+	t.Run("given existing user should return it", func(t *testing.T) {
+		// Arrange
+		resp, c, h, mock := userSetup(http.MethodGet, "")
+		mock.user = User{ID: 1, Name: "John Doe"}
+
+		// Act
+		err := serve(c, h.GetUserHandler)
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusOK, resp.Code)
+		assert.Equal(t, 1, mock.whatIsID)
+	})
+
+	t.Run("given unknown user should return 404 and error code", func(t *testing.T) {
+		// Arrange
+		resp, c, h, mock := userSetup(http.MethodGet, "")
+		mock.err = ErrUserNotFound
+
+		// Act
+		err := serve(c, h.GetUserHandler)
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusNotFound, resp.Code)
+		assert.Contains(t, resp.Body.String(), CodeUserNotFound)
+	})
+}
+
+func TestCreateUser(t *testing.T) {
+	t.Run("given a name should create the user", func(t *testing.T) {
+		// Arrange
+		resp, c, h, mock := testSetup(http.MethodPost, "/api/v1/users", strings.NewReader(`{"id": 9, "name": "John Doe"}`))
+		c.Request().Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		mock.ExpectToCall("CreateUser")
+
+		// Act
+		err := serve(c, h.CreateUserHandler)
+
+		// Assert
+		mock.Verify(t)
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusCreated, resp.Code)
+		assert.Equal(t, User{Name: "John Doe"}, mock.whatIsUser)
+	})
+
+	t.Run("given a blank name should return 400 and not call the store", func(t *testing.T) {
+		// Arrange
+		resp, c, h, mock := testSetup(http.MethodPost, "/api/v1/users", strings.NewReader(`{"name": " "}`))
+		c.Request().Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+
+		// Act
+		err := serve(c, h.CreateUserHandler)
+
+		// Assert
+		assert.NoError(t, err)
+		assert.False(t, mock.methodToCall["CreateUser"])
+		assert.Equal(t, http.StatusBadRequest, resp.Code)
+		assert.Contains(t, resp.Body.String(), CodeValidationFailed)
+	})
+}
+
+func TestUpdateUser(t *testing.T) {
+	// Arrange
+	resp, c, h, mock := userSetup(http.MethodPut, `{"name": "Johnny Doe"}`)
+	mock.ExpectToCall("UpdateUser")
+
+	// Act
+	err := serve(c, h.UpdateUserHandler)
+
+	// Assert
+	mock.Verify(t)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.Equal(t, User{ID: 1, Name: "Johnny Doe"}, mock.whatIsUser)
+}
+
+func TestDeleteUser(t *testing.T) {
+	t.Run("given user without wallets should return 204", func(t *testing.T) {
+		// Arrange
+		resp, c, h, mock := userSetup(http.MethodDelete, "")
+		mock.ExpectToCall("DeleteUser")
+
+		// Act
+		err := serve(c, h.DeleteUserHandler)
+
+		// Assert
+		mock.Verify(t)
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusNoContent, resp.Code)
+	})
+
+	t.Run("given user with wallets should return 409 and error code", func(t *testing.T) {
+		// Arrange
+		resp, c, h, mock := userSetup(http.MethodDelete, "")
+		mock.err = ErrUserHasWallets
+
+		// Act
+		err := serve(c, h.DeleteUserHandler)
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusConflict, resp.Code)
+		assert.Contains(t, resp.Body.String(), CodeUserHasWallets)
+	})
+}
@@ -1,7 +1,8 @@
 package wallet
 
 import (
-	"errors"
+	"crypto/rand"
+	"fmt"
 	"github.com/labstack/echo/v4"
 	"strconv"
 )
@@ -18,13 +19,53 @@ func IsWalletTypeValid(walletType string) bool {
 func ParseUserID(c echo.Context) (int, error) {
 	id := c.Param("id")
 	if id == "" {
-		return 0, errors.New("id is required")
+		return 0, fmt.Errorf("%w: id is required", ErrInvalidRequest)
 	}
 
 	userID, err := strconv.Atoi(id)
 	if err != nil {
-		return 0, errors.New("invalid user id")
+		return 0, fmt.Errorf("%w: user id must be a number", ErrInvalidRequest)
 	}
 
 	return userID, nil
 }
+
+func ParseWalletID(c echo.Context) (int, error) {
+	id := c.Param("id")
+	if id == "" {
+		return 0, fmt.Errorf("%w: id is required", ErrInvalidRequest)
+	}
+
+	walletID, err := strconv.Atoi(id)
+	if err != nil {
+		return 0, fmt.Errorf("%w: wallet id must be a number", ErrInvalidRequest)
+	}
+
+	return walletID, nil
+}
+
+func ParseAPIKeyID(c echo.Context) (int, error) {
+	id := c.Param("id")
+	if id == "" {
+		return 0, fmt.Errorf("%w: id is required", ErrInvalidRequest)
+	}
+
+	keyID, err := strconv.Atoi(id)
+	if err != nil {
+		return 0, fmt.Errorf("%w: api key id must be a number", ErrInvalidRequest)
+	}
+
+	return keyID, nil
+}
+
+// NewCorrelationID returns a random UUID (version 4) that ties together
+// the ledger entries written by a single balance-changing operation.
+func NewCorrelationID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	b[6] = (b[6] & 0x0f) | 0x40
+	b[8] = (b[8] & 0x3f) | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+}
@@ -1,6 +1,9 @@
 package wallet
 
-import "testing"
+import (
+	"regexp"
+	"testing"
+)
 
 func TestIsWalletTypeValid(t *testing.T) {
 	tests := []struct {
@@ -33,3 +36,43 @@ func TestIsWalletTypeValid(t *testing.T) {
 		})
 	}
 }
+
+func TestTransactionTypeOf(t *testing.T) {
+	tests := []struct {
+		name   string
+		amount Money
+		want   string
+	}{
+		{
+			name:   "Positive amount",
+			amount: MustParseMoney("10"),
+			want:   TransactionTypeCredit,
+		},
+		{
+			name:   "Negative amount",
+			amount: MustParseMoney("-10"),
+			want:   TransactionTypeDebit,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := TransactionTypeOf(test.amount); got != test.want {
+				t.Errorf("TransactionTypeOf() = %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestNewCorrelationID(t *testing.T) {
+	uuidPattern := regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)
+
+	first, second := NewCorrelationID(), NewCorrelationID()
+
+	if !uuidPattern.MatchString(first) {
+		t.Errorf("NewCorrelationID() = %v, want a version 4 uuid", first)
+	}
+	if first == second {
+		t.Errorf("NewCorrelationID() returned %v twice", first)
+	}
+}
@@ -0,0 +1,92 @@
+package wallet
+
+import (
+	"fmt"
+	"strings"
+)
+
+type FieldError struct {
+	Field  string `json:"field" example:"wallet_name"`
+	Reason string `json:"reason" example:"must not be empty"`
+}
+
+// ValidationError lists every invalid field of a request, not just the first one.
+type ValidationError struct {
+	Fields []FieldError
+}
+
+func (e *ValidationError) Error() string {
+	problems := make([]string, 0, len(e.Fields))
+	for _, f := range e.Fields {
+		problems = append(problems, f.Field+": "+f.Reason)
+	}
+	return strings.Join(problems, "; ")
+}
+
+// validator collects field errors, so a request is checked completely before it is rejected.
+type validator struct {
+	fields []FieldError
+}
+
+func (v *validator) add(field, reason string) {
+	v.fields = append(v.fields, FieldError{Field: field, Reason: reason})
+}
+
+// check adds the field error when ok is false and returns ok, so dependent checks can be skipped.
+func (v *validator) check(ok bool, field, reason string) bool {
+	if !ok {
+		v.add(field, reason)
+	}
+	return ok
+}
+
+func (v *validator) checkName(field, value string) bool {
+	if !v.check(strings.TrimSpace(value) != "", field, "must not be empty") {
+		return false
+	}
+	return v.check(len(value) <= MaxNameLength, field, fmt.Sprintf("must be at most %d characters", MaxNameLength))
+}
+
+func (v *validator) checkWalletType(field, walletType string) bool {
+	return v.check(IsWalletTypeValid(walletType), field, "must be one of "+strings.Join(AvailableWalletTypes, ", "))
+}
+
+func (v *validator) checkPositive(field string, amount Money) bool {
+	return v.check(amount.IsPositive(), field, "must be greater than 0")
+}
+
+// err returns a *ValidationError when any check failed.
+func (v *validator) err() error {
+	if len(v.fields) == 0 {
+		return nil
+	}
+	return &ValidationError{Fields: v.fields}
+}
+
+// ValidateWalletUpdate checks a wallet sent to UpdateWalletHandler, the balance
+// rules that depend on the stored wallet are left to the store.
+func ValidateWalletUpdate(w WalletForUpdate) error {
+	v := validator{}
+	v.check(w.ID > 0, "id", "is required")
+	v.check(w.Balance != nil, "balance", "is required")
+	return v.err()
+}
+
+// ValidateNewWallet checks a wallet sent to CreateWalletHandler, after the currency defaulted.
+func ValidateNewWallet(w Wallet) error {
+	v := validator{}
+	v.check(w.UserID > 0, "user_id", "is required")
+	v.checkName("wallet_name", w.WalletName)
+	typeOK := v.checkWalletType("wallet_type", w.WalletType)
+
+	if v.check(IsCurrencyValid(w.Currency), "currency", "is not supported") {
+		if typeOK && w.WalletType != WalletTypeCryptoWallet {
+			v.check(!cryptoCurrencies[w.Currency], "currency", "is only available for "+WalletTypeCryptoWallet)
+		}
+		decimals := CurrencyDecimals[w.Currency]
+		v.check(w.Balance.Decimals() <= decimals, "balance", fmt.Sprintf("must have at most %d decimal places for %s", decimals, w.Currency))
+	}
+	v.check(!w.Balance.IsNegative(), "balance", "must not be negative")
+
+	return v.err()
+}
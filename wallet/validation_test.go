@@ -0,0 +1,116 @@
+package wallet
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateNewWallet(t *testing.T) {
+	valid := Wallet{UserID: 1, WalletName: "John Savings", WalletType: WalletTypeSavings, Currency: CurrencyTHB, Balance: MustParseMoney("10.25")}
+	with := func(change func(w *Wallet)) Wallet {
+		w := valid
+		change(&w)
+		return w
+	}
+
+	tests := []struct {
+		name   string
+		wallet Wallet
+		want   []FieldError
+	}{
+		{name: "Valid wallet", wallet: valid, want: nil},
+		{name: "Credit card may open at zero", wallet: with(func(w *Wallet) { w.WalletType = WalletTypeCreditCard; w.Balance = 0 }), want: nil},
+		{name: "BTC in a Crypto Wallet", wallet: with(func(w *Wallet) {
+			w.WalletType = WalletTypeCryptoWallet
+			w.Currency = CurrencyBTC
+			w.Balance = MustParseMoney("0.00000001")
+		}), want: nil},
+		{name: "Missing user id", wallet: with(func(w *Wallet) { w.UserID = 0 }), want: []FieldError{{"user_id", "is required"}}},
+		{name: "Wallet name too long", wallet: with(func(w *Wallet) { w.WalletName = strings.Repeat("a", 256) }), want: []FieldError{{"wallet_name", "must be at most 255 characters"}}},
+		{name: "Unknown wallet type", wallet: with(func(w *Wallet) { w.WalletType = "Piggy Bank" }), want: []FieldError{{"wallet_type", "must be one of Savings, Credit Card, Crypto Wallet"}}},
+		{name: "Unsupported currency", wallet: with(func(w *Wallet) { w.Currency = "XYZ" }), want: []FieldError{{"currency", "is not supported"}}},
+		{name: "BTC outside a Crypto Wallet", wallet: with(func(w *Wallet) { w.Currency = CurrencyBTC }), want: []FieldError{{"currency", "is only available for Crypto Wallet"}}},
+		{name: "Too many decimals", wallet: with(func(w *Wallet) { w.Currency = CurrencyJPY }), want: []FieldError{{"balance", "must have at most 0 decimal places for JPY"}}},
+		{name: "Negative balance", wallet: with(func(w *Wallet) { w.Balance = MustParseMoney("-1") }), want: []FieldError{{"balance", "must not be negative"}}},
+		{
+			name:   "Every problem at once",
+			wallet: Wallet{Currency: "XYZ", Balance: MustParseMoney("-1")},
+			want: []FieldError{
+				{"user_id", "is required"},
+				{"wallet_name", "must not be empty"},
+				{"wallet_type", "must be one of Savings, Credit Card, Crypto Wallet"},
+				{"currency", "is not supported"},
+				{"balance", "must not be negative"},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := ValidateNewWallet(test.wallet)
+
+			if test.want == nil {
+				assert.NoError(t, err)
+				return
+			}
+			var invalid *ValidationError
+			if assert.ErrorAs(t, err, &invalid) {
+				assert.Equal(t, test.want, invalid.Fields)
+			}
+		})
+	}
+}
+
+func TestValidateWalletUpdate(t *testing.T) {
+	balance := MustParseMoney("0")
+
+	assert.NoError(t, ValidateWalletUpdate(WalletForUpdate{ID: 1, Balance: &balance}))
+
+	var invalid *ValidationError
+	if assert.ErrorAs(t, ValidateWalletUpdate(WalletForUpdate{}), &invalid) {
+		assert.Equal(t, []FieldError{{"id", "is required"}, {"balance", "is required"}}, invalid.Fields)
+	}
+}
+
+func TestTransferValidate(t *testing.T) {
+	tests := []struct {
+		name     string
+		transfer Transfer
+		want     []FieldError
+	}{
+		{name: "Valid transfer", transfer: Transfer{FromWalletID: 1, ToWalletID: 2, Amount: MustParseMoney("1")}, want: nil},
+		{name: "Same wallet", transfer: Transfer{FromWalletID: 1, ToWalletID: 1, Amount: MustParseMoney("1")}, want: []FieldError{{"to_wallet_id", "must differ from from_wallet_id"}}},
+		{
+			name:     "Nothing set",
+			transfer: Transfer{},
+			want: []FieldError{
+				{"from_wallet_id", "is required"},
+				{"to_wallet_id", "is required"},
+				{"amount", "must be greater than 0"},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := test.transfer.Validate()
+
+			if test.want == nil {
+				assert.NoError(t, err)
+				return
+			}
+			var invalid *ValidationError
+			if assert.ErrorAs(t, err, &invalid) {
+				assert.Equal(t, test.want, invalid.Fields)
+			}
+		})
+	}
+}
+
+func TestValidationErrorMessage(t *testing.T) {
+	err := &ValidationError{Fields: []FieldError{{"user_id", "is required"}, {"amount", "must be greater than 0"}}}
+
+	assert.Equal(t, "user_id: is required; amount: must be greater than 0", err.Error())
+}
@@ -0,0 +1,39 @@
+package wallet
+
+import (
+	"github.com/labstack/echo/v4"
+	"strconv"
+	"strings"
+)
+
+const (
+	HeaderETag    = "ETag"
+	HeaderIfMatch = "If-Match"
+)
+
+// ETag is the entity tag of a wallet at the given version.
+func ETag(version int) string {
+	return `"` + strconv.Itoa(version) + `"`
+}
+
+// IfMatchVersion reads the wallet version a PUT, PATCH or DELETE expects
+// from the If-Match header, which must hold the ETag of a previous read.
+func IfMatchVersion(c echo.Context) (int, error) {
+	ifMatch := strings.TrimSpace(c.Request().Header.Get(HeaderIfMatch))
+	if ifMatch == "" {
+		return 0, ErrPreconditionRequired
+	}
+
+	v := validator{}
+	version, err := strconv.Atoi(strings.TrimSuffix(strings.TrimPrefix(ifMatch, `"`), `"`))
+	v.check(err == nil && version > 0 && ifMatch == ETag(version), HeaderIfMatch, "must be the ETag of the wallet")
+	if err := v.err(); err != nil {
+		return 0, err
+	}
+	return version, nil
+}
+
+// setETag tags the response with the version of w.
+func setETag(c echo.Context, w Wallet) {
+	c.Response().Header().Set(HeaderETag, ETag(w.Version))
+}
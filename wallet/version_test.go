@@ -0,0 +1,53 @@
+package wallet
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIfMatchVersion(t *testing.T) {
+	tests := []struct {
+		name    string
+		ifMatch string
+		want    int
+		wantErr error
+		invalid bool
+	}{
+		{name: "ETag of a read", ifMatch: `"3"`, want: 3},
+		{name: "Surrounding spaces", ifMatch: ` "12" `, want: 12},
+		{name: "Missing", ifMatch: "", wantErr: ErrPreconditionRequired},
+		{name: "Unquoted", ifMatch: "3", invalid: true},
+		{name: "Weak", ifMatch: `W/"3"`, invalid: true},
+		{name: "Any version", ifMatch: "*", invalid: true},
+		{name: "Not a version", ifMatch: `"abc"`, invalid: true},
+		{name: "Zero", ifMatch: `"0"`, invalid: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPut, "/api/v1/wallets", nil)
+			if test.ifMatch != "" {
+				req.Header.Set(HeaderIfMatch, test.ifMatch)
+			}
+			c := echo.New().NewContext(req, httptest.NewRecorder())
+
+			got, err := IfMatchVersion(c)
+
+			if test.wantErr != nil {
+				assert.ErrorIs(t, err, test.wantErr)
+				return
+			}
+			if test.invalid {
+				var invalid *ValidationError
+				assert.ErrorAs(t, err, &invalid)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, test.want, got)
+		})
+	}
+}
@@ -3,26 +3,35 @@ package wallet
 import "time"
 
 type Wallet struct {
-	ID         int       `json:"id" example:"1"`
-	UserID     int       `json:"user_id" example:"1"`
-	UserName   string    `json:"user_name" example:"John Doe"`
-	WalletName string    `json:"wallet_name" example:"John's Wallet"`
-	WalletType string    `json:"wallet_type" example:"Credit Card"`
-	Balance    float64   `json:"balance" example:"100.00"`
-	CreatedAt  time.Time `json:"created_at" example:"2024-03-25T14:19:00.729237Z"`
+	ID         int    `json:"id" example:"1"`
+	UserID     int    `json:"user_id" example:"1"`
+	UserName   string `json:"user_name" example:"John Doe"`
+	WalletName string `json:"wallet_name" example:"John's Wallet"`
+	WalletType string `json:"wallet_type" example:"Credit Card"`
+	Currency   string `json:"currency" example:"THB"`
+	Balance    Money  `json:"balance" swaggertype:"number" example:"100.00"`
+	// Status is active, frozen or closed, only an active wallet balance may change.
+	Status       string `json:"status" example:"active"`
+	StatusReason string `json:"status_reason,omitempty" example:"Suspicious activity reported by compliance"`
+	// Version goes up with every change, it is sent as the ETag of the wallet.
+	Version   int       `json:"version" example:"1"`
+	CreatedAt time.Time `json:"created_at" example:"2024-03-25T14:19:00.729237Z"`
+	// DeletedAt is set while the wallet is soft-deleted.
+	DeletedAt *time.Time `json:"deleted_at,omitempty" example:"2024-03-26T09:00:00Z"`
 }
 
 type WalletForCreate struct {
-	UserID     int     `json:"user_id" example:"1"`
-	UserName   string  `json:"user_name" example:"John Doe"`
-	WalletName string  `json:"wallet_name" example:"John's Wallet"`
-	WalletType string  `json:"wallet_type" example:"Credit Card"`
-	Balance    float64 `json:"balance" example:"100.00"`
+	UserID     int    `json:"user_id" example:"1"`
+	WalletName string `json:"wallet_name" example:"John's Wallet"`
+	WalletType string `json:"wallet_type" example:"Credit Card"`
+	Currency   string `json:"currency" example:"THB"`
+	Balance    Money  `json:"balance" swaggertype:"number" example:"100.00"`
 }
 
+// WalletForUpdate is the body of a balance overwrite, Balance is nil when it was left out.
 type WalletForUpdate struct {
-	ID      int     `json:"id" example:"1"`
-	Balance float64 `json:"balance" example:"100.00"`
+	ID      int    `json:"id" example:"1"`
+	Balance *Money `json:"balance" swaggertype:"number" example:"100.00"`
 }
 
 const (
@@ -7,45 +7,217 @@ import (
 	"github.com/stretchr/testify/assert"
 	"io"
 	"log"
+	"math/big"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
 )
 
 type mockWalletStorer struct {
-	wallets      []Wallet
-	err          error
-	methodToCall map[string]bool
-	whatIsFilter Wallet
+	user           User
+	users          []User
+	wallet         Wallet
+	wallets        []Wallet
+	nextCursor     string
+	transactions   []Transaction
+	transferResult TransferResult
+	conversion     Conversion
+	apiKey         APIKey
+	apiKeys        []APIKey
+	owner          int
+	ownerErr       error
+	err            error
+	methodToCall   map[string]bool
+	whatIsFilter   Filter
+	whatIsPage     Page
+	whatIsWallet   Wallet
+	whatIsUser     User
+	whatIsAPIKey   APIKey
+	whatIsPrefix   string
+	whatIsHash     string
+	whatIsPatch    WalletPatch
+	whatIsTransfer Transfer
+	whatIsID       int
+	whatIsVersion  int
+	whatIsAmount   Money
+	whatIsStatus   string
+	whatIsReason   string
 }
 
 func NewMockWalletStorer() *mockWalletStorer {
 	return &mockWalletStorer{
+		owner:        testCaller.UserID,
 		methodToCall: make(map[string]bool),
 	}
 }
 
-func (m *mockWalletStorer) GetWallets(filter Wallet) ([]Wallet, error) {
+func (m *mockWalletStorer) GetUsers() ([]User, error) {
+	m.methodToCall["GetUsers"] = true
+	return m.users, m.err
+}
+
+func (m *mockWalletStorer) GetUserByID(id int) (User, error) {
+	m.methodToCall["GetUserByID"] = true
+	m.whatIsID = id
+	return m.user, m.err
+}
+
+func (m *mockWalletStorer) CreateUser(u *User) error {
+	m.methodToCall["CreateUser"] = true
+	m.whatIsUser = *u
+	return m.err
+}
+
+func (m *mockWalletStorer) UpdateUser(u *User) error {
+	m.methodToCall["UpdateUser"] = true
+	m.whatIsUser = *u
+	return m.err
+}
+
+func (m *mockWalletStorer) DeleteUser(id int) error {
+	m.methodToCall["DeleteUser"] = true
+	m.whatIsID = id
+	return m.err
+}
+
+func (m *mockWalletStorer) GetAPIKeys() ([]APIKey, error) {
+	m.methodToCall["GetAPIKeys"] = true
+	return m.apiKeys, m.err
+}
+
+func (m *mockWalletStorer) GetAPIKeyByPrefix(prefix string) (APIKey, error) {
+	m.methodToCall["GetAPIKeyByPrefix"] = true
+	m.whatIsPrefix = prefix
+	return m.apiKey, m.err
+}
+
+func (m *mockWalletStorer) CreateAPIKey(k *APIKey) error {
+	m.methodToCall["CreateAPIKey"] = true
+	m.whatIsAPIKey = *k
+	return m.err
+}
+
+func (m *mockWalletStorer) RotateAPIKey(id int, prefix, hash string) (APIKey, error) {
+	m.methodToCall["RotateAPIKey"] = true
+	m.whatIsID = id
+	m.whatIsPrefix = prefix
+	m.whatIsHash = hash
+	return m.apiKey, m.err
+}
+
+func (m *mockWalletStorer) RevokeAPIKey(id int) (APIKey, error) {
+	m.methodToCall["RevokeAPIKey"] = true
+	m.whatIsID = id
+	return m.apiKey, m.err
+}
+
+func (m *mockWalletStorer) TouchAPIKey(id int, at time.Time) error {
+	m.methodToCall["TouchAPIKey"] = true
+	m.whatIsID = id
+	return m.err
+}
+
+func (m *mockWalletStorer) GetWallets(filter Filter, page Page) (WalletPage, error) {
 	m.methodToCall["GetWallets"] = true
 	m.whatIsFilter = filter
-	return m.wallets, m.err
+	m.whatIsPage = page
+	return WalletPage{Wallets: m.wallets, NextCursor: m.nextCursor}, m.err
+}
+
+func (m *mockWalletStorer) GetWalletByID(id int) (Wallet, error) {
+	m.methodToCall["GetWalletByID"] = true
+	m.whatIsID = id
+	return m.wallet, m.err
+}
+
+// GetWalletOwner answers with owner and ownerErr rather than err,
+// so the ownership check passes in tests of a failing store call.
+func (m *mockWalletStorer) GetWalletOwner(id int) (int, error) {
+	return m.owner, m.ownerErr
 }
 
 func (m *mockWalletStorer) CreateWallet(w *Wallet) error {
 	m.methodToCall["CreateWallet"] = true
+	m.whatIsWallet = *w
 	return m.err
 }
 
 func (m *mockWalletStorer) UpdateWallet(w *Wallet) error {
 	m.methodToCall["UpdateWallet"] = true
+	m.whatIsWallet = *w
 	return m.err
 }
 
-func (m *mockWalletStorer) DeleteWallet(userID int) error {
+func (m *mockWalletStorer) PatchWallet(id, version int, patch WalletPatch) (Wallet, error) {
+	m.methodToCall["PatchWallet"] = true
+	m.whatIsID = id
+	m.whatIsVersion = version
+	m.whatIsPatch = patch
+	return m.wallet, m.err
+}
+
+func (m *mockWalletStorer) DeleteWallet(id, version int) error {
 	m.methodToCall["DeleteWallet"] = true
+	m.whatIsID = id
+	m.whatIsVersion = version
 	return m.err
 }
 
+func (m *mockWalletStorer) DeleteUserWallets(userID int) error {
+	m.methodToCall["DeleteUserWallets"] = true
+	m.whatIsID = userID
+	return m.err
+}
+
+func (m *mockWalletStorer) RestoreWallet(id int) (Wallet, error) {
+	m.methodToCall["RestoreWallet"] = true
+	m.whatIsID = id
+	return m.wallet, m.err
+}
+
+func (m *mockWalletStorer) ChangeWalletStatus(id, version int, status, reason string) (Wallet, error) {
+	m.methodToCall["ChangeWalletStatus"] = true
+	m.whatIsID = id
+	m.whatIsVersion = version
+	m.whatIsStatus = status
+	m.whatIsReason = reason
+	return m.wallet, m.err
+}
+
+func (m *mockWalletStorer) Transfer(fromID, toID int, amount Money) (TransferResult, error) {
+	m.methodToCall["Transfer"] = true
+	m.whatIsTransfer = Transfer{FromWalletID: fromID, ToWalletID: toID, Amount: amount}
+	return m.transferResult, m.err
+}
+
+func (m *mockWalletStorer) Convert(fromID, toID int, amount Money, rates FXRateProvider) (Conversion, error) {
+	m.methodToCall["Convert"] = true
+	m.whatIsTransfer = Transfer{FromWalletID: fromID, ToWalletID: toID, Amount: amount}
+	return m.conversion, m.err
+}
+
+func (m *mockWalletStorer) GetTransactions(walletID int) ([]Transaction, error) {
+	m.methodToCall["GetTransactions"] = true
+	m.whatIsID = walletID
+	return m.transactions, m.err
+}
+
+func (m *mockWalletStorer) Deposit(walletID int, amount Money) (Wallet, error) {
+	m.methodToCall["Deposit"] = true
+	m.whatIsID = walletID
+	m.whatIsAmount = amount
+	return m.wallet, m.err
+}
+
+func (m *mockWalletStorer) Withdraw(walletID int, amount Money) (Wallet, error) {
+	m.methodToCall["Withdraw"] = true
+	m.whatIsID = walletID
+	m.whatIsAmount = amount
+	return m.wallet, m.err
+}
+
 func (m *mockWalletStorer) ExpectToCall(methodName string) {
 	if m.methodToCall == nil {
 		m.methodToCall = make(map[string]bool)
@@ -61,16 +233,32 @@ func (m *mockWalletStorer) Verify(t *testing.T) {
 	}
 }
 
+// testCaller is the principal of every request made by testSetup, the owner of the mock's wallets.
+var testCaller = NewPrincipal("1")
+
+var testRates = RateTable{
+	rateKey(CurrencyUSD, CurrencyTHB): big.NewRat(365, 10),
+}
+
 func testSetup(method, url string, body io.Reader) (*httptest.ResponseRecorder, echo.Context, *Handler, *mockWalletStorer) {
 	req := httptest.NewRequest(method, url, body)
 	rec := httptest.NewRecorder()
 	c := echo.New().NewContext(req, rec)
+	SetPrincipal(c, testCaller)
 	mock := NewMockWalletStorer()
-	h := New(mock)
+	h := New(mock, testRates)
 
 	return rec, c, h, mock
 }
 
+// serve runs handler the way Echo does, answering a returned error with ProblemHandler.
+func serve(c echo.Context, handler echo.HandlerFunc) error {
+	if err := handler(c); err != nil {
+		ProblemHandler(err, c)
+	}
+	return nil
+}
+
 func TestGetWallets(t *testing.T) {
 	t.Run("given unable to get wallets should return 500 and error message", func(t *testing.T) {
 		// Arrange
@@ -79,17 +267,17 @@ func TestGetWallets(t *testing.T) {
 		mock.ExpectToCall("GetWallets")
 
 		// Act
-		err := h.GetWalletsHandler(c)
+		err := serve(c, h.GetWalletsHandler)
 
 		// Assert
 		mock.Verify(t)
 		assert.NoError(t, err)
 		assert.Equal(t, http.StatusInternalServerError, resp.Code)
-		var got Err
+		var got Problem
 		if err := json.Unmarshal(resp.Body.Bytes(), &got); err != nil {
 			t.Errorf("expected response body to be valid json, got %s", resp.Body.String())
 		}
-		assert.NotEmpty(t, got.Message)
+		assert.NotEmpty(t, got.Code)
 	})
 
 	t.Run("given user able to getting wallet should return list of wallets", func(t *testing.T) {
@@ -100,87 +288,594 @@ func TestGetWallets(t *testing.T) {
 			{
 				ID:       1,
 				UserName: "user1",
-				Balance:  1000,
+				Balance:  MustParseMoney("1000"),
 			},
 			{
 				ID:       2,
 				UserName: "user2",
-				Balance:  2000,
+				Balance:  MustParseMoney("2000"),
 			},
 		}
 		mock.wallets = want
 		mock.ExpectToCall("GetWallets")
 
 		// Act
-		err := h.GetWalletsHandler(c)
+		err := serve(c, h.GetWalletsHandler)
 
 		// Assert
 		mock.Verify(t)
 		assert.NoError(t, err)
 		assert.Equal(t, http.StatusOK, resp.Code)
-		var got []Wallet
+		var got WalletPage
 		if err := json.Unmarshal(resp.Body.Bytes(), &got); err != nil {
 			t.Errorf("expected response body to be valid json, got %s", resp.Body.String())
 		}
-		assert.Equal(t, want, got)
+		assert.Equal(t, want, got.Wallets)
 	})
 
 	t.Run("given user filter by available wallet_types should return 200 and list of wallets", func(t *testing.T) {
 		// Arrange
 		resp, c, h, mock := testSetup(http.MethodGet, "/api/v1/wallets?wallet_type=Savings", nil)
-		expectedFilter := Wallet{
-			WalletType: WalletTypeSavings,
+		expectedFilter := Filter{
+			UserID:      testCaller.UserID,
+			WalletTypes: []string{WalletTypeSavings},
 		}
 		want := []Wallet{
 			{
 				ID:       1,
 				UserName: "user1",
-				Balance:  1000,
+				Balance:  MustParseMoney("1000"),
 			},
 			{
 				ID:       2,
 				UserName: "user2",
-				Balance:  2000,
+				Balance:  MustParseMoney("2000"),
 			},
 		}
 		mock.wallets = want
 		mock.ExpectToCall("GetWallets")
 
 		// Act
-		err := h.GetWalletsHandler(c)
+		err := serve(c, h.GetWalletsHandler)
 
 		// Assert
 		mock.Verify(t)
 		assert.NoError(t, err)
 		assert.Equal(t, expectedFilter, mock.whatIsFilter)
 		assert.Equal(t, http.StatusOK, resp.Code)
-		var got []Wallet
+		var got WalletPage
 		if err := json.Unmarshal(resp.Body.Bytes(), &got); err != nil {
 			t.Errorf("expected response body to be valid json, got %s", resp.Body.String())
 		}
-		assert.Equal(t, want, got)
+		assert.Equal(t, want, got.Wallets)
 	})
 
-	t.Run("given user filter by unavailable wallet_types should return 200 and empty list of wallet", func(t *testing.T) {
+	t.Run("given user filter by unavailable wallet_types should return 400 and error message", func(t *testing.T) {
 		// Arrange
 		resp, c, h, mock := testSetup(http.MethodGet, "/api/v1/wallets?wallet_type=Unknown", nil)
-		want := []Wallet{}
-		mock.wallets = want
 
 		// Act
-		err := h.GetWalletsHandler(c)
+		err := serve(c, h.GetWalletsHandler)
+
+		// Assert
+		assert.NoError(t, err)
+		assert.False(t, mock.methodToCall["GetWallets"])
+		assert.Equal(t, http.StatusBadRequest, resp.Code)
+		var got Problem
+		if err := json.Unmarshal(resp.Body.Bytes(), &got); err != nil {
+			t.Errorf("expected response body to be valid json, got %s", resp.Body.String())
+		}
+		assert.NotEmpty(t, got.Code)
+	})
+
+	t.Run("given every filter should pass them all to the store", func(t *testing.T) {
+		// Arrange
+		query := "?wallet_type=Savings,Credit%20Card&wallet_type=Crypto%20Wallet&min_balance=10&max_balance=100.50" +
+			"&created_after=2024-01-01&created_before=2024-03-25T14:19:00Z&wallet_name=john&user_name=Doe"
+		resp, c, h, mock := testSetup(http.MethodGet, "/api/v1/wallets"+query, nil)
+		mock.ExpectToCall("GetWallets")
+
+		// Act
+		err := serve(c, h.GetWalletsHandler)
 
 		// Assert
 		mock.Verify(t)
 		assert.NoError(t, err)
 		assert.Equal(t, http.StatusOK, resp.Code)
-		var got []Wallet
+		minBalance, maxBalance := MustParseMoney("10"), MustParseMoney("100.50")
+		createdAfter := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+		createdBefore := time.Date(2024, 3, 25, 14, 19, 0, 0, time.UTC)
+		assert.Equal(t, Filter{
+			UserID:        testCaller.UserID,
+			WalletTypes:   []string{WalletTypeSavings, WalletTypeCreditCard, WalletTypeCryptoWallet},
+			MinBalance:    &minBalance,
+			MaxBalance:    &maxBalance,
+			CreatedAfter:  &createdAfter,
+			CreatedBefore: &createdBefore,
+			WalletName:    "john",
+			UserName:      "Doe",
+		}, mock.whatIsFilter)
+	})
+
+	t.Run("given user filter by currency should pass upper-cased currency to the filter", func(t *testing.T) {
+		// Arrange
+		resp, c, h, mock := testSetup(http.MethodGet, "/api/v1/wallets?currency=usd", nil)
+		expectedFilter := Filter{
+			UserID:   testCaller.UserID,
+			Currency: CurrencyUSD,
+		}
+		mock.wallets = []Wallet{}
+		mock.ExpectToCall("GetWallets")
+
+		// Act
+		err := serve(c, h.GetWalletsHandler)
+
+		// Assert
+		mock.Verify(t)
+		assert.NoError(t, err)
+		assert.Equal(t, expectedFilter, mock.whatIsFilter)
+		assert.Equal(t, http.StatusOK, resp.Code)
+	})
+
+	t.Run("given user filter by unsupported currency should return 400", func(t *testing.T) {
+		// Arrange
+		resp, c, h, mock := testSetup(http.MethodGet, "/api/v1/wallets?currency=XYZ", nil)
+
+		// Act
+		err := serve(c, h.GetWalletsHandler)
+
+		// Assert
+		assert.NoError(t, err)
+		assert.False(t, mock.methodToCall["GetWallets"])
+		assert.Equal(t, http.StatusBadRequest, resp.Code)
+	})
+
+	t.Run("given limit, sort and order should pass the page to the store and return next_cursor", func(t *testing.T) {
+		// Arrange
+		resp, c, h, mock := testSetup(http.MethodGet, "/api/v1/wallets?limit=2&sort=balance&order=desc", nil)
+		mock.wallets = []Wallet{{ID: 1}, {ID: 2}}
+		mock.nextCursor = "next"
+		mock.ExpectToCall("GetWallets")
+
+		// Act
+		err := serve(c, h.GetWalletsHandler)
+
+		// Assert
+		mock.Verify(t)
+		assert.NoError(t, err)
+		assert.Equal(t, Page{Limit: 2, Sort: SortByBalance, Desc: true}, mock.whatIsPage)
+		assert.Equal(t, http.StatusOK, resp.Code)
+		var got WalletPage
 		if err := json.Unmarshal(resp.Body.Bytes(), &got); err != nil {
 			t.Errorf("expected response body to be valid json, got %s", resp.Body.String())
 		}
-		assert.Equal(t, want, got)
+		assert.Equal(t, "next", got.NextCursor)
 	})
 
+	t.Run("given invalid page params should return 400 and error message", func(t *testing.T) {
+		// Arrange
+		resp, c, h, mock := testSetup(http.MethodGet, "/api/v1/wallets?limit=0", nil)
+
+		// Act
+		err := serve(c, h.GetWalletsHandler)
+
+		// Assert
+		assert.NoError(t, err)
+		assert.False(t, mock.methodToCall["GetWallets"])
+		assert.Equal(t, http.StatusBadRequest, resp.Code)
+	})
+}
+
+func TestGetWallet(t *testing.T) {
+	t.Run("given wallet id is not number should return 400 and error message", func(t *testing.T) {
+		// Arrange
+		resp, c, h, _ := testSetup(http.MethodGet, "/", nil)
+		c.SetPath("/api/v1/wallets/:id")
+		c.SetParamNames("id")
+		c.SetParamValues("abc")
+
+		// Act
+		err := serve(c, h.GetWalletHandler)
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusBadRequest, resp.Code)
+	})
+
+	tests := []struct {
+		name     string
+		storeErr error
+		wantCode int
+	}{
+		{"given unknown wallet should return 404", ErrWalletNotFound, http.StatusNotFound},
+		{"given unable to get wallet should return 500", errors.New("connection refused"), http.StatusInternalServerError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name+" and error message", func(t *testing.T) {
+			// Arrange
+			resp, c, h, mock := testSetup(http.MethodGet, "/", nil)
+			c.SetPath("/api/v1/wallets/:id")
+			c.SetParamNames("id")
+			c.SetParamValues("7")
+			mock.err = tt.storeErr
+			mock.ExpectToCall("GetWalletByID")
+
+			// Act
+			err := serve(c, h.GetWalletHandler)
+
+			// Assert
+			mock.Verify(t)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantCode, resp.Code)
+			var got Problem
+			if err := json.Unmarshal(resp.Body.Bytes(), &got); err != nil {
+				t.Errorf("expected response body to be valid json, got %s", resp.Body.String())
+			}
+			assert.NotEmpty(t, got.Code)
+		})
+	}
+
+	t.Run("given no error should return 200 and wallet", func(t *testing.T) {
+		// Arrange
+		resp, c, h, mock := testSetup(http.MethodGet, "/", nil)
+		c.SetPath("/api/v1/wallets/:id")
+		c.SetParamNames("id")
+		c.SetParamValues("7")
+		mock.ExpectToCall("GetWalletByID")
+		expected := Wallet{ID: 7, UserID: 1, UserName: "John Doe", WalletType: WalletTypeSavings, Currency: CurrencyTHB, Balance: MustParseMoney("1000"), Version: 2}
+		mock.wallet = expected
+
+		// Act
+		err := serve(c, h.GetWalletHandler)
+
+		// Assert
+		mock.Verify(t)
+		assert.Equal(t, 7, mock.whatIsID)
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusOK, resp.Code)
+		assert.Equal(t, `"2"`, resp.Header().Get(HeaderETag))
+		var got Wallet
+		if err := json.Unmarshal(resp.Body.Bytes(), &got); err != nil {
+			t.Errorf("expected response body to be valid json, got %s", resp.Body.String())
+		}
+		assert.Equal(t, expected, got)
+	})
+}
+
+func TestCreateWallet(t *testing.T) {
+	badRequests := []struct {
+		name string
+		body string
+	}{
+		{name: "given unsupported currency", body: `{"wallet_type": "Savings", "currency": "XYZ", "balance": 10}`},
+		{name: "given crypto currency for Savings", body: `{"wallet_type": "Savings", "currency": "BTC", "balance": 1}`},
+		{name: "given too many decimals for JPY", body: `{"wallet_type": "Savings", "currency": "JPY", "balance": 10.5}`},
+	}
+	for _, test := range badRequests {
+		t.Run(test.name+" should return 400 and error message", func(t *testing.T) {
+			// Arrange
+			resp, c, h, mock := testSetup(http.MethodPost, "/api/v1/wallets", strings.NewReader(test.body))
+			c.Request().Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+
+			// Act
+			err := serve(c, h.CreateWalletHandler)
+
+			// Assert
+			assert.NoError(t, err)
+			assert.False(t, mock.methodToCall["CreateWallet"])
+			assert.Equal(t, http.StatusBadRequest, resp.Code)
+			var got Problem
+			if err := json.Unmarshal(resp.Body.Bytes(), &got); err != nil {
+				t.Errorf("expected response body to be valid json, got %s", resp.Body.String())
+			}
+			assert.NotEmpty(t, got.Code)
+		})
+	}
+
+	t.Run("given several invalid fields should return 400 listing every one", func(t *testing.T) {
+		// Arrange
+		body := `{"wallet_name": "", "wallet_type": "Piggy Bank", "balance": -1}`
+		resp, c, h, mock := testSetup(http.MethodPost, "/api/v1/wallets", strings.NewReader(body))
+		c.Request().Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+
+		// Act
+		err := serve(c, h.CreateWalletHandler)
+
+		// Assert
+		assert.NoError(t, err)
+		assert.False(t, mock.methodToCall["CreateWallet"])
+		assert.Equal(t, http.StatusBadRequest, resp.Code)
+		var got Problem
+		if err := json.Unmarshal(resp.Body.Bytes(), &got); err != nil {
+			t.Errorf("expected response body to be valid json, got %s", resp.Body.String())
+		}
+		fields := make([]string, 0)
+		for _, f := range got.Errors {
+			fields = append(fields, f.Field)
+		}
+		assert.Equal(t, []string{"user_id", "wallet_name", "wallet_type", "balance"}, fields)
+	})
+
+	t.Run("given no currency should create wallet in default currency", func(t *testing.T) {
+		// Arrange
+		body := `{"user_id": 1, "wallet_name": "John's Wallet", "wallet_type": "Savings", "balance": 10.25}`
+		resp, c, h, mock := testSetup(http.MethodPost, "/api/v1/wallets", strings.NewReader(body))
+		c.Request().Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		mock.ExpectToCall("CreateWallet")
+
+		// Act
+		err := serve(c, h.CreateWalletHandler)
+
+		// Assert
+		mock.Verify(t)
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusCreated, resp.Code)
+		assert.Equal(t, DefaultCurrency, mock.whatIsWallet.Currency)
+		assert.Equal(t, MustParseMoney("10.25"), mock.whatIsWallet.Balance)
+	})
+
+	t.Run("given fields the server sets should not pass them to the store", func(t *testing.T) {
+		// Arrange
+		body := `{"id": 9, "user_id": 1, "user_name": "Mallory", "wallet_name": "John's Wallet", "wallet_type": "Savings", "balance": 10,
+			"status": "frozen", "version": 7, "created_at": "2020-01-01T00:00:00Z", "deleted_at": "2024-03-26T09:00:00Z"}`
+		resp, c, h, mock := testSetup(http.MethodPost, "/api/v1/wallets", strings.NewReader(body))
+		c.Request().Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		mock.ExpectToCall("CreateWallet")
+
+		// Act
+		err := serve(c, h.CreateWalletHandler)
+
+		// Assert
+		mock.Verify(t)
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusCreated, resp.Code)
+		assert.Equal(t, Wallet{UserID: 1, WalletName: "John's Wallet", WalletType: WalletTypeSavings, Currency: DefaultCurrency, Balance: MustParseMoney("10")}, mock.whatIsWallet)
+	})
+
+	t.Run("given crypto wallet in BTC should accept satoshi precision", func(t *testing.T) {
+		// Arrange
+		body := `{"user_id": 1, "wallet_name": "John's BTC", "wallet_type": "Crypto Wallet", "currency": "btc", "balance": "0.00000001"}`
+		resp, c, h, mock := testSetup(http.MethodPost, "/api/v1/wallets", strings.NewReader(body))
+		c.Request().Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		mock.ExpectToCall("CreateWallet")
+
+		// Act
+		err := serve(c, h.CreateWalletHandler)
+
+		// Assert
+		mock.Verify(t)
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusCreated, resp.Code)
+		assert.Equal(t, CurrencyBTC, mock.whatIsWallet.Currency)
+	})
+
+	t.Run("given an unknown user should return 422 and error code", func(t *testing.T) {
+		// Arrange
+		body := `{"user_id": 99, "wallet_name": "John's Wallet", "wallet_type": "Savings", "balance": 10}`
+		resp, c, h, mock := testSetup(http.MethodPost, "/api/v1/wallets", strings.NewReader(body))
+		c.Request().Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		// a token may name a user that was never created
+		SetPrincipal(c, NewPrincipal("99"))
+		mock.err = ErrUserNotFound
+		mock.ExpectToCall("CreateWallet")
+
+		// Act
+		err := serve(c, h.CreateWalletHandler)
+
+		// Assert
+		mock.Verify(t)
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusUnprocessableEntity, resp.Code)
+		var got Problem
+		if err := json.Unmarshal(resp.Body.Bytes(), &got); err != nil {
+			t.Errorf("expected response body to be valid json, got %s", resp.Body.String())
+		}
+		assert.Equal(t, CodeUserNotFound, got.Code)
+	})
+
+	t.Run("given the user already has the wallet name should return 409 and error code", func(t *testing.T) {
+		// Arrange
+		body := `{"user_id": 1, "wallet_name": "John's Wallet", "wallet_type": "Savings", "balance": 10}`
+		resp, c, h, mock := testSetup(http.MethodPost, "/api/v1/wallets", strings.NewReader(body))
+		c.Request().Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		mock.err = ErrDuplicateWallet
+		mock.ExpectToCall("CreateWallet")
+
+		// Act
+		err := serve(c, h.CreateWalletHandler)
+
+		// Assert
+		mock.Verify(t)
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusConflict, resp.Code)
+		var got Problem
+		if err := json.Unmarshal(resp.Body.Bytes(), &got); err != nil {
+			t.Errorf("expected response body to be valid json, got %s", resp.Body.String())
+		}
+		assert.Equal(t, CodeDuplicateWallet, got.Code)
+	})
+}
+
+func TestUpdateWallet(t *testing.T) {
+	t.Run("given no balance should return 400 and not call the store", func(t *testing.T) {
+		// Arrange
+		resp, c, h, mock := testSetup(http.MethodPut, "/api/v1/wallets", strings.NewReader(`{"id": 7}`))
+		c.Request().Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		c.Request().Header.Set(HeaderIfMatch, `"3"`)
+
+		// Act
+		err := serve(c, h.UpdateWalletHandler)
+
+		// Assert
+		assert.NoError(t, err)
+		assert.False(t, mock.methodToCall["UpdateWallet"])
+		assert.Equal(t, http.StatusBadRequest, resp.Code)
+		got := Problem{}
+		assert.NoError(t, json.Unmarshal(resp.Body.Bytes(), &got))
+		assert.Equal(t, []FieldError{{Field: "balance", Reason: "is required"}}, got.Errors)
+	})
+
+	t.Run("given no If-Match should return 428 and not call the store", func(t *testing.T) {
+		// Arrange
+		resp, c, h, mock := testSetup(http.MethodPut, "/api/v1/wallets", strings.NewReader(`{"id": 7, "balance": 10}`))
+		c.Request().Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+
+		// Act
+		err := serve(c, h.UpdateWalletHandler)
+
+		// Assert
+		assert.NoError(t, err)
+		assert.False(t, mock.methodToCall["UpdateWallet"])
+		assert.Equal(t, http.StatusPreconditionRequired, resp.Code)
+	})
+
+	t.Run("given wallet changed since it was read should return 412", func(t *testing.T) {
+		// Arrange
+		resp, c, h, mock := testSetup(http.MethodPut, "/api/v1/wallets", strings.NewReader(`{"id": 7, "balance": 10}`))
+		c.Request().Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		c.Request().Header.Set(HeaderIfMatch, `"3"`)
+		mock.err = ErrVersionMismatch
+		mock.ExpectToCall("UpdateWallet")
+
+		// Act
+		err := serve(c, h.UpdateWalletHandler)
+
+		// Assert
+		mock.Verify(t)
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusPreconditionFailed, resp.Code)
+	})
+
+	t.Run("given If-Match should update at that version and return the new ETag", func(t *testing.T) {
+		// Arrange
+		resp, c, h, mock := testSetup(http.MethodPut, "/api/v1/wallets", strings.NewReader(`{"id": 7, "balance": 10, "version": 99}`))
+		c.Request().Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		c.Request().Header.Set(HeaderIfMatch, `"3"`)
+		mock.ExpectToCall("UpdateWallet")
+
+		// Act
+		err := serve(c, h.UpdateWalletHandler)
+
+		// Assert
+		mock.Verify(t)
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusOK, resp.Code)
+		assert.Equal(t, 7, mock.whatIsWallet.ID)
+		assert.Equal(t, 3, mock.whatIsWallet.Version)
+		assert.Equal(t, MustParseMoney("10"), mock.whatIsWallet.Balance)
+		assert.Equal(t, `"3"`, resp.Header().Get(HeaderETag))
+	})
+}
+
+func TestDeleteWallet(t *testing.T) {
+	deleteSetup := func() (*httptest.ResponseRecorder, echo.Context, *Handler, *mockWalletStorer) {
+		resp, c, h, mock := testSetup(http.MethodDelete, "/", nil)
+		c.SetPath("/api/v1/wallets/:id")
+		c.SetParamNames("id")
+		c.SetParamValues("7")
+		return resp, c, h, mock
+	}
+
+	t.Run("given no If-Match should return 428 and not call the store", func(t *testing.T) {
+		// Arrange
+		resp, c, h, mock := deleteSetup()
+
+		// Act
+		err := serve(c, h.DeleteWalletHandler)
+
+		// Assert
+		assert.NoError(t, err)
+		assert.False(t, mock.methodToCall["DeleteWallet"])
+		assert.Equal(t, http.StatusPreconditionRequired, resp.Code)
+	})
+
+	t.Run("given wallet changed since it was read should return 412", func(t *testing.T) {
+		// Arrange
+		resp, c, h, mock := deleteSetup()
+		c.Request().Header.Set(HeaderIfMatch, `"3"`)
+		mock.err = ErrVersionMismatch
+		mock.ExpectToCall("DeleteWallet")
+
+		// Act
+		err := serve(c, h.DeleteWalletHandler)
+
+		// Assert
+		mock.Verify(t)
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusPreconditionFailed, resp.Code)
+	})
+
+	t.Run("given frozen wallet should return 422", func(t *testing.T) {
+		// Arrange
+		resp, c, h, mock := deleteSetup()
+		c.Request().Header.Set(HeaderIfMatch, `"3"`)
+		mock.err = ErrWalletFrozen
+
+		// Act
+		err := serve(c, h.DeleteWalletHandler)
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusUnprocessableEntity, resp.Code)
+		assert.Contains(t, resp.Body.String(), CodeWalletFrozen)
+	})
+
+	t.Run("given If-Match should delete the wallet at that version and return 204", func(t *testing.T) {
+		// Arrange
+		resp, c, h, mock := deleteSetup()
+		c.Request().Header.Set(HeaderIfMatch, `"3"`)
+		mock.ExpectToCall("DeleteWallet")
+
+		// Act
+		err := serve(c, h.DeleteWalletHandler)
+
+		// Assert
+		mock.Verify(t)
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusNoContent, resp.Code)
+		assert.Equal(t, 7, mock.whatIsID)
+		assert.Equal(t, 3, mock.whatIsVersion)
+	})
+}
+
+func TestRestoreWallet(t *testing.T) {
+	restoreSetup := func() (*httptest.ResponseRecorder, echo.Context, *Handler, *mockWalletStorer) {
+		resp, c, h, mock := testSetup(http.MethodPost, "/", nil)
+		c.SetPath("/api/v1/wallets/:id/restore")
+		c.SetParamNames("id")
+		c.SetParamValues("7")
+		return resp, c, h, mock
+	}
+
+	t.Run("given deleted wallet should return it with the new ETag", func(t *testing.T) {
+		// Arrange
+		resp, c, h, mock := restoreSetup()
+		mock.wallet = Wallet{ID: 7, WalletName: "John's Wallet", Version: 4}
+		mock.ExpectToCall("RestoreWallet")
+
+		// Act
+		err := serve(c, h.RestoreWalletHandler)
+
+		// Assert
+		mock.Verify(t)
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusOK, resp.Code)
+		assert.Equal(t, 7, mock.whatIsID)
+		assert.Equal(t, `"4"`, resp.Header().Get(HeaderETag))
+	})
+
+	t.Run("given the name was taken in the meantime should return 409", func(t *testing.T) {
+		// Arrange
+		resp, c, h, mock := restoreSetup()
+		mock.err = ErrDuplicateWallet
+
+		// Act
+		err := serve(c, h.RestoreWalletHandler)
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusConflict, resp.Code)
+	})
 }
 
 func TestGetUserWallet(t *testing.T) {
@@ -193,16 +888,16 @@ func TestGetUserWallet(t *testing.T) {
 		// see: https://echo.labstack.com/docs/testing#getuser
 
 		// Act
-		err := h.GetUserWalletHandler(c)
+		err := serve(c, h.GetUserWalletHandler)
 
 		// Assert
 		assert.NoError(t, err)
 		assert.Equal(t, http.StatusBadRequest, resp.Code)
-		var got Err
+		var got Problem
 		if err := json.Unmarshal(resp.Body.Bytes(), &got); err != nil {
 			t.Errorf("expected response body to be valid json, got %s", resp.Body.String())
 		}
-		assert.NotEmpty(t, got.Message)
+		assert.NotEmpty(t, got.Code)
 	})
 
 	t.Run("given user_id is not number should return 400 and error message", func(t *testing.T) {
@@ -213,16 +908,16 @@ func TestGetUserWallet(t *testing.T) {
 		c.SetParamValues("abc")
 
 		// Act
-		err := h.GetUserWalletHandler(c)
+		err := serve(c, h.GetUserWalletHandler)
 
 		// Assert
 		assert.NoError(t, err)
 		assert.Equal(t, http.StatusBadRequest, resp.Code)
-		var got Err
+		var got Problem
 		if err := json.Unmarshal(resp.Body.Bytes(), &got); err != nil {
 			t.Errorf("expected response body to be valid json, got %s", resp.Body.String())
 		}
-		assert.NotEmpty(t, got.Message)
+		assert.NotEmpty(t, got.Code)
 	})
 
 	t.Run("given error should return 500 and error message", func(t *testing.T) {
@@ -230,26 +925,26 @@ func TestGetUserWallet(t *testing.T) {
 		resp, c, h, mock := testSetup(http.MethodGet, "/", nil)
 		c.SetPath("/api/v1/users/:id/wallets")
 		c.SetParamNames("id")
-		c.SetParamValues("999")
+		c.SetParamValues("1")
 		mock.err = errors.New("unable to get wallets")
 		mock.ExpectToCall("GetWallets")
-		expectedFilter := Wallet{
-			UserID: 999,
+		expectedFilter := Filter{
+			UserID: 1,
 		}
 
 		// Act
-		err := h.GetUserWalletHandler(c)
+		err := serve(c, h.GetUserWalletHandler)
 
 		// Assert
 		mock.Verify(t)
 		assert.Equal(t, expectedFilter, mock.whatIsFilter)
 		assert.NoError(t, err)
 		assert.Equal(t, http.StatusInternalServerError, resp.Code)
-		var got Err
+		var got Problem
 		if err := json.Unmarshal(resp.Body.Bytes(), &got); err != nil {
 			t.Errorf("expected response body to be valid json, got %s", resp.Body.String())
 		}
-		assert.NotEmpty(t, got.Message)
+		assert.NotEmpty(t, got.Code)
 	})
 
 	t.Run("given no error should return 200 and []wallets", func(t *testing.T) {
@@ -259,35 +954,35 @@ func TestGetUserWallet(t *testing.T) {
 		c.SetParamNames("id")
 		c.SetParamValues("1")
 		mock.ExpectToCall("GetWallets")
-		expectedFilter := Wallet{
+		expectedFilter := Filter{
 			UserID: 1,
 		}
 		expectedWallets := []Wallet{
 			{
 				ID:       1,
 				UserName: "user1",
-				Balance:  1000,
+				Balance:  MustParseMoney("1000"),
 			},
 			{
 				ID:       2,
 				UserName: "user2",
-				Balance:  2000,
+				Balance:  MustParseMoney("2000"),
 			},
 		}
 		mock.wallets = expectedWallets
 
 		// Act
-		err := h.GetUserWalletHandler(c)
+		err := serve(c, h.GetUserWalletHandler)
 
 		// Assert
 		mock.Verify(t)
 		assert.Equal(t, expectedFilter, mock.whatIsFilter)
 		assert.NoError(t, err)
 		assert.Equal(t, http.StatusOK, resp.Code)
-		var got []Wallet
+		var got WalletPage
 		if err := json.Unmarshal(resp.Body.Bytes(), &got); err != nil {
 			t.Errorf("expected response body to be valid json, got %s", resp.Body.String())
 		}
-		assert.Equal(t, expectedWallets, got)
+		assert.Equal(t, expectedWallets, got.Wallets)
 	})
 }